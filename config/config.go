@@ -15,17 +15,27 @@ var (
 	WikimediaMaxImportance float64  = 500.0
 	Planet                 string   = ""
 	WhosOnFirst            string   = ""
+	WhosOnFirstPostcodes   string   = ""
 	WikimediaImportance    string   = ""
+	RankingConfig          string   = ""
+	TagFilters             string   = ""
+	PoiCategories          string   = ""
 
 	// INTERMEDIATES
 	BoundingBoxes string = ""
 	Countries     string = ""
 
-	Output        string = "geocoder.gpkg"
-	Database      string = "geocoder.gpkg"
-	EnableForward bool   = true
-	EnableReverse bool   = true
-	DisableCache  bool   = false
+	Output               string = "geocoder.gpkg"
+	Database             string = "geocoder.gpkg"
+	EnableForward        bool   = true
+	EnableReverse        bool   = true
+	EnableStreets        bool   = false
+	EnableAddresses      bool   = false
+	EnablePostcodes      bool   = false
+	EnablePOIs           bool   = false
+	EnableNatural        bool   = false
+	EnableAdminRelations bool   = false
+	DisableCache         bool   = false
 )
 
 type jsonConfig struct {
@@ -33,11 +43,21 @@ type jsonConfig struct {
 	WikimediaMaxImportance *float64 `json:"wikimedia_max_importance,omitempty"`
 	Planet                 string   `json:"planet,omitempty"`
 	WhosOnFirst            string   `json:"whos_on_first,omitempty"`
+	WhosOnFirstPostcodes   string   `json:"whos_on_first_postcodes,omitempty"`
 	WikimediaImportance    string   `json:"wikimedia_importance,omitempty"`
+	RankingConfig          string   `json:"ranking_config,omitempty"`
+	TagFilters             string   `json:"tag_filters,omitempty"`
+	PoiCategories          string   `json:"poi_categories,omitempty"`
 	Output                 string   `json:"output,omitempty"`
 	Database               string   `json:"database,omitempty"`
 	EnableForward          *bool    `json:"enable_forward,omitempty"`
 	EnableReverse          *bool    `json:"enable_reverse,omitempty"`
+	EnableStreets          *bool    `json:"enable_streets,omitempty"`
+	EnableAddresses        *bool    `json:"enable_addresses,omitempty"`
+	EnablePostcodes        *bool    `json:"enable_postcodes,omitempty"`
+	EnablePOIs             *bool    `json:"enable_pois,omitempty"`
+	EnableNatural          *bool    `json:"enable_natural,omitempty"`
+	EnableAdminRelations   *bool    `json:"enable_admin_relations,omitempty"`
 	DisableCache           *bool    `json:"disable_cache,omitempty"`
 }
 
@@ -63,9 +83,21 @@ func Load() {
 			if cfg.WhosOnFirst != "" {
 				WhosOnFirst = cfg.WhosOnFirst
 			}
+			if cfg.WhosOnFirstPostcodes != "" {
+				WhosOnFirstPostcodes = cfg.WhosOnFirstPostcodes
+			}
 			if cfg.WikimediaImportance != "" {
 				WikimediaImportance = cfg.WikimediaImportance
 			}
+			if cfg.RankingConfig != "" {
+				RankingConfig = cfg.RankingConfig
+			}
+			if cfg.TagFilters != "" {
+				TagFilters = cfg.TagFilters
+			}
+			if cfg.PoiCategories != "" {
+				PoiCategories = cfg.PoiCategories
+			}
 			if cfg.Output != "" {
 				Output = cfg.Output
 			}
@@ -78,6 +110,24 @@ func Load() {
 			if cfg.EnableReverse != nil {
 				EnableReverse = *cfg.EnableReverse
 			}
+			if cfg.EnableStreets != nil {
+				EnableStreets = *cfg.EnableStreets
+			}
+			if cfg.EnableAddresses != nil {
+				EnableAddresses = *cfg.EnableAddresses
+			}
+			if cfg.EnablePostcodes != nil {
+				EnablePostcodes = *cfg.EnablePostcodes
+			}
+			if cfg.EnablePOIs != nil {
+				EnablePOIs = *cfg.EnablePOIs
+			}
+			if cfg.EnableNatural != nil {
+				EnableNatural = *cfg.EnableNatural
+			}
+			if cfg.EnableAdminRelations != nil {
+				EnableAdminRelations = *cfg.EnableAdminRelations
+			}
 			if cfg.DisableCache != nil {
 				DisableCache = *cfg.DisableCache
 			}
@@ -98,9 +148,21 @@ func Load() {
 	if val := os.Getenv("WHOS_ON_FIRST"); val != "" {
 		WhosOnFirst = val
 	}
+	if val := os.Getenv("WHOS_ON_FIRST_POSTCODES"); val != "" {
+		WhosOnFirstPostcodes = val
+	}
 	if val := os.Getenv("WIKIMEDIA_IMPORTANCE"); val != "" {
 		WikimediaImportance = val
 	}
+	if val := os.Getenv("RANKING_CONFIG"); val != "" {
+		RankingConfig = val
+	}
+	if val := os.Getenv("TAG_FILTERS"); val != "" {
+		TagFilters = val
+	}
+	if val := os.Getenv("POI_CATEGORIES"); val != "" {
+		PoiCategories = val
+	}
 	if val := os.Getenv("OUTPUT"); val != "" {
 		Output = val
 	}
@@ -117,6 +179,36 @@ func Load() {
 			EnableReverse = b
 		}
 	}
+	if val := os.Getenv("ENABLE_STREETS"); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			EnableStreets = b
+		}
+	}
+	if val := os.Getenv("ENABLE_ADDRESSES"); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			EnableAddresses = b
+		}
+	}
+	if val := os.Getenv("ENABLE_POSTCODES"); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			EnablePostcodes = b
+		}
+	}
+	if val := os.Getenv("ENABLE_POIS"); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			EnablePOIs = b
+		}
+	}
+	if val := os.Getenv("ENABLE_NATURAL"); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			EnableNatural = b
+		}
+	}
+	if val := os.Getenv("ENABLE_ADMIN_RELATIONS"); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			EnableAdminRelations = b
+		}
+	}
 	if val := os.Getenv("DISABLE_CACHE"); val != "" {
 		if b, err := strconv.ParseBool(val); err == nil {
 			DisableCache = b
@@ -0,0 +1,408 @@
+package generate
+
+import (
+	"context"
+	"hstin/gocoder/config"
+	"hstin/gocoder/geo"
+	"hstin/gocoder/mapping"
+	"hstin/gocoder/structures"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+)
+
+// An address is attached to the nearest street with its addr:street name
+// within this distance in metres.
+const addressStreetRadius = 1000
+
+// An address without a street is attached to the nearest place with its
+// addr:place or addr:city name within this distance in metres.
+const addressPlaceRadius = 10000
+
+// addressObject is a node or building way with an addr:housenumber, or an
+// addr:interpolation way.
+type addressObject struct {
+	OSMType     string
+	ID          int64
+	HouseNumber string
+	Street      string
+	Place       string
+	City        string
+	Postcode    string
+	Refs        []int64
+	Lat         float64
+	Lng         float64
+
+	// Interpolation is the addr:interpolation value of interpolation ways
+	Interpolation string
+}
+
+// interpolationLine is the part of an addr:interpolation way between two
+// numbered nodes.
+type interpolationLine struct {
+	ID       int64
+	Start    int
+	End      int
+	Step     int
+	Street   string
+	Place    string
+	City     string
+	Postcode string
+	Line     orb.LineString
+}
+
+func newAddressObject(osmType string, id int64, tags osm.Tags) *addressObject {
+	return &addressObject{
+		OSMType:     osmType,
+		ID:          id,
+		HouseNumber: tags.Find("addr:housenumber"),
+		Street:      tags.Find("addr:street"),
+		Place:       tags.Find("addr:place"),
+		City:        tags.Find("addr:city"),
+		Postcode:    tags.Find("addr:postcode"),
+	}
+}
+
+// interpolationStep returns the distance between two house numbers of an
+// addr:interpolation value, or 0 for unsupported values like "alphabetic".
+func interpolationStep(interpolation string) int {
+	switch interpolation {
+	case "odd", "even":
+		return 2
+	case "all":
+		return 1
+	}
+	if step, err := strconv.Atoi(interpolation); err == nil && step > 0 {
+		return step
+	}
+	return 0
+}
+
+// newInterpolationLines splits an interpolation way at its numbered nodes.
+// Street, place, city and postcode default to those of the end points.
+func newInterpolationLines(way *addressObject, coordinates map[int64]orb.Point, endpoints map[int64]*addressObject) []*interpolationLine {
+	step := interpolationStep(way.Interpolation)
+	if step == 0 {
+		return nil
+	}
+
+	lines := make([]*interpolationLine, 0)
+	last := -1
+	for i, ref := range way.Refs {
+		endpoint, ok := endpoints[ref]
+		if !ok {
+			continue
+		}
+		if last < 0 {
+			last = i
+			continue
+		}
+
+		first := endpoints[way.Refs[last]]
+		start, errStart := strconv.Atoi(structures.NormalizeHouseNumber(first.HouseNumber))
+		end, errEnd := strconv.Atoi(structures.NormalizeHouseNumber(endpoint.HouseNumber))
+
+		line := make(orb.LineString, 0, i-last+1)
+		for _, lineRef := range way.Refs[last : i+1] {
+			if point, ok := coordinates[lineRef]; ok {
+				line = append(line, point)
+			}
+		}
+		last = i
+
+		if errStart != nil || errEnd != nil || start == end || len(line) < 2 {
+			continue
+		}
+		if start > end {
+			start, end = end, start
+			line.Reverse()
+		}
+		if (end-start)%step != 0 {
+			continue
+		}
+
+		interpolation := &interpolationLine{
+			ID:       way.ID,
+			Start:    start,
+			End:      end,
+			Step:     step,
+			Street:   way.Street,
+			Place:    way.Place,
+			City:     way.City,
+			Postcode: way.Postcode,
+			Line:     line,
+		}
+		for _, other := range []*addressObject{first, endpoint} {
+			if interpolation.Street == "" {
+				interpolation.Street = other.Street
+			}
+			if interpolation.Place == "" {
+				interpolation.Place = other.Place
+			}
+			if interpolation.City == "" {
+				interpolation.City = other.City
+			}
+			if interpolation.Postcode == "" {
+				interpolation.Postcode = other.Postcode
+			}
+		}
+		if interpolation.Street != "" || interpolation.Place != "" || interpolation.City != "" {
+			lines = append(lines, interpolation)
+		}
+	}
+	return lines
+}
+
+// CollectAddresses reads the nodes and ways with an addr:housenumber and the
+// addr:interpolation ways. Buildings are placed at the centroid of their
+// outline. The planet file is read twice, once for the objects and once for
+// the coordinates and numbers of the way nodes.
+func CollectAddresses() ([]*addressObject, []*interpolationLine) {
+	if !config.EnableAddresses {
+		return nil, nil
+	}
+
+	log.Printf("[ADDRESSES] Collecting addresses")
+	startTime := time.Now()
+
+	f, err := os.Open(config.Planet)
+	if err != nil {
+		log.Fatalf("[ADDRESSES] Failed to open planet file %s: %v", config.Planet, err)
+	}
+	defer f.Close()
+
+	hasAddress := func(tags osm.Tags) bool {
+		return tags.Find("addr:housenumber") != "" &&
+			(tags.Find("addr:street") != "" || tags.Find("addr:place") != "" || tags.Find("addr:city") != "")
+	}
+
+	// 1) Address nodes and ways
+	scanner := osmpbf.New(context.Background(), f, runtime.GOMAXPROCS(-1))
+	scanner.SkipRelations = true
+	scanner.FilterNode = func(node *osm.Node) bool {
+		return hasAddress(node.Tags)
+	}
+	scanner.FilterWay = func(way *osm.Way) bool {
+		return hasAddress(way.Tags) || way.Tags.Find("addr:interpolation") != ""
+	}
+
+	addresses := make([]*addressObject, 0)
+	interpolationWays := make([]*addressObject, 0)
+	neededNodes := make(map[int64]bool)
+	for scanner.Scan() {
+		switch o := scanner.Object().(type) {
+		case *osm.Node:
+			address := newAddressObject("node", int64(o.ID), o.Tags)
+			address.Lat, address.Lng = o.Lat, o.Lon
+			addresses = append(addresses, address)
+		case *osm.Way:
+			if interpolation := o.Tags.Find("addr:interpolation"); interpolation != "" {
+				way := newAddressObject("way", int64(o.ID), o.Tags)
+				way.Interpolation = interpolation
+				way.Refs = wayRefs(o)
+				for _, ref := range way.Refs {
+					neededNodes[ref] = true
+				}
+				interpolationWays = append(interpolationWays, way)
+				continue
+			}
+			address := newAddressObject("way", int64(o.ID), o.Tags)
+			address.Refs = wayRefs(o)
+			for _, ref := range address.Refs {
+				neededNodes[ref] = true
+			}
+			addresses = append(addresses, address)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("[ADDRESSES] Scanner error during first pass: %v", err)
+	}
+	scanner.Close()
+	log.Printf("[ADDRESSES] Found %d addresses and %d interpolation ways", len(addresses), len(interpolationWays))
+
+	// 2) Coordinates of the building outlines and interpolation ways
+	interpolations := make([]*interpolationLine, 0)
+	if len(neededNodes) > 0 {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			log.Fatalf("[ADDRESSES] Failed to seek to start of planet file: %v", err)
+		}
+		scanner = osmpbf.New(context.Background(), f, runtime.GOMAXPROCS(-1))
+		scanner.SkipWays = true
+		scanner.SkipRelations = true
+		scanner.FilterNode = func(node *osm.Node) bool {
+			return neededNodes[int64(node.ID)]
+		}
+
+		coordinates := make(map[int64]orb.Point, len(neededNodes))
+		endpoints := make(map[int64]*addressObject)
+		for scanner.Scan() {
+			if o, ok := scanner.Object().(*osm.Node); ok {
+				coordinates[int64(o.ID)] = orb.Point{o.Lon, o.Lat}
+				if o.Tags.Find("addr:housenumber") != "" {
+					endpoints[int64(o.ID)] = newAddressObject("node", int64(o.ID), o.Tags)
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Fatalf("[ADDRESSES] Scanner error during second pass: %v", err)
+		}
+		scanner.Close()
+
+		for _, address := range addresses {
+			if address.OSMType != "way" {
+				continue
+			}
+			outline := make(orb.Ring, 0, len(address.Refs))
+			for _, ref := range address.Refs {
+				if point, ok := coordinates[ref]; ok {
+					outline = append(outline, point)
+				}
+			}
+			if len(outline) == 0 {
+				continue
+			}
+
+			var centroid orb.Point
+			if len(outline) >= 4 && outline.Closed() {
+				centroid, _ = planar.CentroidArea(outline)
+			} else {
+				centroid = outline.Bound().Center()
+			}
+			address.Lat, address.Lng = centroid[1], centroid[0]
+			address.Refs = nil
+		}
+
+		for _, way := range interpolationWays {
+			interpolations = append(interpolations, newInterpolationLines(way, coordinates, endpoints)...)
+		}
+	}
+
+	log.Printf("[ADDRESSES] Collected addresses in %s", time.Since(startTime))
+
+	return addresses, interpolations
+}
+
+// BuildAddresses attaches every address and interpolation line to its
+// street, or to its place for addresses without addr:street, and returns the
+// address and interpolation indexes. The place is the one named in
+// addr:place, or else in addr:city. Addresses whose street or place can't be
+// found nearby are dropped.
+func BuildAddresses(
+	addresses []*addressObject,
+	interpolations []*interpolationLine,
+	nodes []structures.Node,
+	streetNames map[string][]int64,
+	placeNames map[string][]int64,
+	streetIndex *structures.StreetIndex,
+) (*structures.AddressIndex, *structures.InterpolationIndex) {
+	addressIndex := structures.NewAddressIndex()
+	interpolationIndex := structures.NewInterpolationIndex()
+
+	// findParent returns the document id of the street or place of an
+	// address at the coordinate, or -1. Addresses with a street are only
+	// attached to the street, under a place they would lose it.
+	findParent := func(lat, lng float64, street, place, city string) int64 {
+		if street != "" {
+			candidates := streetNames[normalizeName(street)]
+			accept := make(map[int64]bool, len(candidates))
+			for _, docID := range candidates {
+				accept[docID] = true
+			}
+			nearest, _ := streetIndex.NearestMatching(lat, lng, addressStreetRadius, func(docID int64) bool {
+				return accept[docID]
+			})
+			if nearest != nil {
+				return nearest.DocID
+			}
+			return -1
+		}
+
+		for _, name := range []string{place, city} {
+			if name == "" {
+				continue
+			}
+			parent := int64(-1)
+			best := float64(addressPlaceRadius)
+			for _, docID := range placeNames[normalizeName(name)] {
+				center := nodes[docID].Center
+				if d := geo.Haversine(lat, lng, float64(center[0]), float64(center[1])); d <= best {
+					best = d
+					parent = docID
+				}
+			}
+			if parent >= 0 {
+				return parent
+			}
+		}
+		return -1
+	}
+
+	unmatched := 0
+	for _, address := range addresses {
+		if address.Lat == 0 && address.Lng == 0 {
+			unmatched++
+			continue
+		}
+
+		parent := findParent(address.Lat, address.Lng, address.Street, address.Place, address.City)
+		if parent < 0 {
+			unmatched++
+			continue
+		}
+
+		// "1;3" and "1,3" are two house numbers on one object
+		for _, number := range strings.FieldsFunc(address.HouseNumber, func(r rune) bool { return r == ';' || r == ',' }) {
+			number = strings.TrimSpace(number)
+			if number == "" {
+				continue
+			}
+			addressIndex.Add(structures.Address{
+				Parent:      parent,
+				HouseNumber: number,
+				Postcode:    address.Postcode,
+				OSMType:     uint8(mapping.GetOSMTypeNumber(address.OSMType)),
+				ID:          address.ID,
+				Lat:         float32(address.Lat),
+				Lng:         float32(address.Lng),
+			})
+		}
+	}
+
+	unmatchedInterpolations := 0
+	for _, interpolation := range interpolations {
+		middle := interpolation.Line[len(interpolation.Line)/2]
+		parent := findParent(middle[1], middle[0], interpolation.Street, interpolation.Place, interpolation.City)
+		if parent < 0 {
+			unmatchedInterpolations++
+			continue
+		}
+
+		interpolationIndex.Add(structures.Interpolation{
+			Parent:   parent,
+			Start:    int32(interpolation.Start),
+			End:      int32(interpolation.End),
+			Step:     int32(interpolation.Step),
+			Postcode: interpolation.Postcode,
+			ID:       interpolation.ID,
+			Line:     interpolation.Line,
+		})
+	}
+
+	log.Printf("[ADDRESSES] Indexed %d house numbers, %d addresses without street or place", len(addressIndex.Addresses), unmatched)
+	log.Printf("[ADDRESSES] Indexed %d interpolation lines, %d without street or place", len(interpolationIndex.Interpolations), unmatchedInterpolations)
+
+	return addressIndex, interpolationIndex
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
@@ -0,0 +1,106 @@
+package generate
+
+import (
+	"hstin/gocoder/structures"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/orb"
+)
+
+func TestBuildAddresses(t *testing.T) {
+	// Document 0 is the street Unter den Linden, 1 the place Berlin
+	nodes := []structures.Node{
+		{Center: [2]float32{52.517, 13.389}},
+		{Center: [2]float32{52.520, 13.405}},
+	}
+	streetIndex := structures.NewStreetIndex()
+	streetIndex.Add(structures.StreetGeometry{
+		DocID:    0,
+		Geometry: orb.MultiLineString{{{13.377, 52.516}, {13.395, 52.517}}},
+	})
+	streetNames := map[string][]int64{"unter den linden": {0}}
+	placeNames := map[string][]int64{"berlin": {1}}
+
+	tests := []struct {
+		name    string
+		address addressObject
+		parent  int64
+	}{
+		{"street", addressObject{HouseNumber: "77", Street: "Unter den Linden", City: "Berlin", Lat: 52.5165, Lng: 13.380}, 0},
+		{"place", addressObject{HouseNumber: "5", Place: "Berlin", Lat: 52.52, Lng: 13.40}, 1},
+		{"city", addressObject{HouseNumber: "5", City: "Berlin", Lat: 52.52, Lng: 13.40}, 1},
+		// An unknown street must not put the house number under the city
+		{"unknown street", addressObject{HouseNumber: "77", Street: "Friedrichstraße", City: "Berlin", Lat: 52.52, Lng: 13.39}, -1},
+		{"street too far", addressObject{HouseNumber: "77", Street: "Unter den Linden", Lat: 52.6, Lng: 13.39}, -1},
+		{"place too far", addressObject{HouseNumber: "5", Place: "Berlin", Lat: 53.0, Lng: 13.40}, -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			address := test.address
+			index, _ := BuildAddresses([]*addressObject{&address}, nil, nodes, streetNames, placeNames, streetIndex)
+			if test.parent < 0 {
+				if len(index.Addresses) != 0 {
+					t.Fatalf("attached to %d, want dropped", index.Addresses[0].Parent)
+				}
+				return
+			}
+			if len(index.Addresses) != 1 || index.Addresses[0].Parent != test.parent {
+				t.Fatalf("got %+v, want parent %d", index.Addresses, test.parent)
+			}
+		})
+	}
+}
+
+func TestNewInterpolationLines(t *testing.T) {
+	coordinates := map[int64]orb.Point{
+		1: {13.380, 52.516}, 2: {13.381, 52.516}, 3: {13.382, 52.516}, 4: {13.383, 52.516}, 5: {13.384, 52.516},
+	}
+	endpoints := map[int64]*addressObject{
+		1: {HouseNumber: "2", Street: "Unter den Linden", Postcode: "10117"},
+		3: {HouseNumber: "10", Street: "Unter den Linden"},
+		5: {HouseNumber: "4"},
+	}
+	positions := map[int]orb.Point{2: coordinates[1], 10: coordinates[3], 4: coordinates[5]}
+
+	tests := []struct {
+		name          string
+		interpolation string
+		refs          []int64
+		want          [][3]int
+	}{
+		// The second part runs backwards and is turned around
+		{"even", "even", []int64{1, 2, 3, 4, 5}, [][3]int{{2, 10, 2}, {4, 10, 2}}},
+		{"all", "all", []int64{1, 2, 3}, [][3]int{{2, 10, 1}}},
+		{"step", "4", []int64{1, 2, 3}, [][3]int{{2, 10, 4}}},
+		{"odd between even numbers", "3", []int64{1, 2, 3}, [][3]int{}},
+		{"alphabetic", "alphabetic", []int64{1, 2, 3}, nil},
+		{"one end", "even", []int64{1, 2}, [][3]int{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			way := &addressObject{OSMType: "way", ID: 9, Interpolation: test.interpolation, Refs: test.refs}
+			lines := newInterpolationLines(way, coordinates, endpoints)
+			if test.want == nil {
+				if lines != nil {
+					t.Fatalf("got %d lines, want none", len(lines))
+				}
+				return
+			}
+			got := make([][3]int, len(lines))
+			for i, line := range lines {
+				got[i] = [3]int{line.Start, line.End, line.Step}
+				if line.Street != "Unter den Linden" {
+					t.Errorf("line %d: street %q, want the street of its end points", i, line.Street)
+				}
+				// Lines run from the lower to the higher number
+				if start := positions[line.Start]; line.Line[0] != start {
+					t.Errorf("line %d starts at %v, want %v", i, line.Line[0], start)
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
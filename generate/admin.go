@@ -5,36 +5,97 @@ import (
 	"database/sql"
 	"encoding/json"
 	"hstin/gocoder/config"
+	"hstin/gocoder/geo"
 	"hstin/gocoder/mapping"
 	"hstin/gocoder/structures"
+	"hstin/gocoder/utils"
 	"log"
+	"math"
 	"os"
 	"os/exec"
+	"strconv"
+	"strings"
 
 	"github.com/paulmach/orb"
 	geojson "github.com/paulmach/orb/geojson"
 	"github.com/paulmach/orb/planar"
+	"github.com/paulmach/orb/simplify"
 	"github.com/tidwall/rtree"
 	_ "modernc.org/sqlite"
 )
 
+// adminSimplifyTolerance is the Douglas-Peucker tolerance in degrees used when
+// persisting admin polygons in the database.
+const adminSimplifyTolerance = 0.001
+
+// AdminLayer is the layer of Who's On First admin areas and OSM admin
+// relations.
+const AdminLayer = "admin"
+
 type DataAdminArea struct {
+	ID        int64
 	Placetype string
 	Country   string
 	Names     map[string]string
 	Geometry  orb.Geometry
 }
 
+// localArea is a Who's On First area below county. Only its label position
+// and the IDs of its ancestors are kept, its polygon would take too much
+// memory for the millions of localities and neighbourhoods.
+type localArea struct {
+	DataAdminArea
+	center  orb.Point
+	parents []int64
+}
+
+func (l localArea) hasParent(id int64) bool {
+	for _, parent := range l.parents {
+		if parent == id {
+			return true
+		}
+	}
+	return false
+}
+
 type AdminArea struct {
-	Regions  map[string]structures.Region
-	Polygons []orb.Polygon
-	Country  string
+	Regions   map[string]structures.Region
+	Hierarchy []structures.AdminLevel
+	Polygons  []orb.Polygon
+	Country   string
 }
 
 type AdminTree struct {
 	adminTree   rtree.RTree
 	countryTree rtree.RTree
+	// localTree holds the areas below county by their bounding box.
+	localTree   rtree.RTree
 	countryGrid *structures.UniformGridIndex
+	Polygons    *structures.AdminIndex
+	documents   []adminDocument
+	countries   map[string]adminCountry
+}
+
+// adminCountry is the Who's On First record of a country, used for the
+// localized country names and bounding boxes.
+type adminCountry struct {
+	area  DataAdminArea
+	bound orb.Bound
+}
+
+// adminDocument is a Who's On First record that gets imported as a searchable
+// document.
+type adminDocument struct {
+	area       DataAdminArea
+	center     [2]float64
+	bound      orb.Bound
+	population int64
+	wikidata   string
+	// geometry is the simplified polygon of oceans and marine areas.
+	geometry orb.MultiPolygon
+	// aliases are the document keys of OSM admin relations describing the
+	// same area.
+	aliases []int64
 }
 
 func LoadAdminAreas() AdminTree {
@@ -47,12 +108,15 @@ func LoadAdminAreas() AdminTree {
 	}
 	defer db.Close()
 
-	ids, err := db.Query("SELECT geojson.id,spr.placetype,geojson.body,spr.country FROM geojson JOIN spr ON geojson.id = spr.id WHERE spr.placetype NOT IN ('locality', 'empire') GROUP BY geojson.id")
+	ids, err := db.Query("SELECT geojson.id,spr.placetype,geojson.body,spr.country FROM geojson JOIN spr ON geojson.id = spr.id WHERE spr.placetype NOT IN ('empire', 'postalcode') GROUP BY geojson.id")
 	if err != nil {
 		log.Fatalf("[ADMIN] Failed to query GeoJSON features: %v", err)
 	}
 
-	adminTrees := AdminTree{}
+	adminTrees := AdminTree{
+		Polygons:  structures.NewAdminIndex(),
+		countries: make(map[string]adminCountry),
+	}
 
 	stmt, err := db.Prepare("SELECT name, country, language FROM names WHERE id = ? AND privateuse = 'preferred'")
 	if err != nil {
@@ -138,6 +202,7 @@ func LoadAdminAreas() AdminTree {
 		}
 
 		admin := DataAdminArea{
+			ID:        id,
 			Placetype: placetype,
 			Names:     namesMap,
 			Geometry:  ugeojson.Geometry,
@@ -145,53 +210,408 @@ func LoadAdminAreas() AdminTree {
 
 		bounds := ugeojson.Geometry.Bound()
 
-		if admin.Placetype == "region" || admin.Placetype == "county" {
+		switch {
+		case admin.Placetype != "country" && hasAdminPolygon(admin.Placetype):
 			adminTrees.adminTree.Insert([2]float64{bounds.Min[0], bounds.Min[1]}, [2]float64{bounds.Max[0], bounds.Max[1]}, admin)
-		} else if admin.Names["country"] != "" {
+		case mapping.GetAdminLevel(admin.Placetype) > mapping.GetAdminLevel("county"):
+			adminTrees.localTree.Insert([2]float64{bounds.Min[0], bounds.Min[1]}, [2]float64{bounds.Max[0], bounds.Max[1]}, newLocalArea(admin, ugeojson))
+		case admin.Names["country"] != "":
 			adminTrees.countryTree.Insert([2]float64{bounds.Min[0], bounds.Min[1]}, [2]float64{bounds.Max[0], bounds.Max[1]}, admin)
 		}
+
+		if hasAdminPolygon(admin.Placetype) {
+			if polygon, ok := simplifyAdminArea(admin); ok {
+				adminTrees.Polygons.Add(polygon)
+			}
+		}
+
+		if admin.Placetype == "country" && namesMap["country"] != "" {
+			if _, ok := adminTrees.countries[namesMap["country"]]; !ok {
+				adminTrees.countries[namesMap["country"]] = adminCountry{area: admin, bound: bounds}
+			}
+		}
+
+		if mapping.SearchablePlacetypes[admin.Placetype] && namesMap["name"] != "" {
+			adminTrees.documents = append(adminTrees.documents, newAdminDocument(admin, ugeojson))
+		}
+
+		if config.EnableNatural && mapping.NaturalPlacetypes[admin.Placetype] && namesMap["name"] != "" {
+			document := newAdminDocument(admin, ugeojson)
+			document.geometry = simplifyGeometry(ugeojson.Geometry)
+			adminTrees.documents = append(adminTrees.documents, document)
+		}
+
 		loadedAdminAreas++
 	}
 
 	log.Printf("[ADMIN] Loaded %d admin areas", loadedAdminAreas)
+	log.Printf("[ADMIN] Persisting %d admin polygons", len(adminTrees.Polygons.Polygons))
+	log.Printf("[ADMIN] Importing %d admin areas as documents", len(adminTrees.documents))
 
 	adminTrees.countryGrid = GenerateCountries()
 
 	return adminTrees
 }
 
+// hasAdminPolygon reports whether the polygon of an admin area is kept, only
+// the levels down to county have one. The smaller areas are resolved
+// through the Who's On First hierarchy, see GetCounty.
+func hasAdminPolygon(placetype string) bool {
+	level := mapping.GetAdminLevel(placetype)
+	return level >= 0 && level <= mapping.GetAdminLevel("county")
+}
+
+// newLocalArea reduces an area below county to its label position and the
+// IDs of its ancestors from wof:hierarchy.
+func newLocalArea(admin DataAdminArea, feature *geojson.Feature) localArea {
+	admin.Geometry = nil
+	area := localArea{
+		DataAdminArea: admin,
+		center:        labelPosition(feature),
+	}
+
+	hierarchies, _ := feature.Properties["wof:hierarchy"].([]interface{})
+	for _, hierarchy := range hierarchies {
+		levels, _ := hierarchy.(map[string]interface{})
+		for _, id := range levels {
+			if id, ok := id.(float64); ok && int64(id) != admin.ID && !area.hasParent(int64(id)) {
+				area.parents = append(area.parents, int64(id))
+			}
+		}
+	}
+
+	return area
+}
+
+// labelPosition returns the label position of a Who's On First record, then
+// the geometric centroid WOF computed, then the centre of its bounding box.
+func labelPosition(feature *geojson.Feature) orb.Point {
+	center := feature.Geometry.Bound().Center()
+	if lat, ok := feature.Properties["lbl:latitude"].(float64); ok {
+		center = orb.Point{feature.Properties.MustFloat64("lbl:longitude", center[0]), lat}
+	} else if lat, ok := feature.Properties["geom:latitude"].(float64); ok {
+		center = orb.Point{feature.Properties.MustFloat64("geom:longitude", center[0]), lat}
+	}
+	return center
+}
+
+func newAdminDocument(admin DataAdminArea, feature *geojson.Feature) adminDocument {
+	bound := feature.Geometry.Bound()
+	center := labelPosition(feature)
+
+	wikidata := ""
+	if concordances, ok := feature.Properties["wof:concordances"].(map[string]interface{}); ok {
+		wikidata, _ = concordances["wd:id"].(string)
+	}
+
+	return adminDocument{
+		area:       admin,
+		center:     [2]float64{center[1], center[0]},
+		bound:      bound,
+		population: int64(feature.Properties.MustFloat64("wof:population", 0)),
+		wikidata:   wikidata,
+	}
+}
+
+// Documents returns the searchable admin areas as insertible nodes. It has to
+// be called after all admin areas are loaded, since the hierarchy of every
+// document is looked up in the admin trees.
+func (a AdminTree) Documents() []*InsertibleNode {
+	out := make([]*InsertibleNode, 0, len(a.documents))
+
+	for _, doc := range a.documents {
+		lat, lng := doc.center[0], doc.center[1]
+
+		layer := AdminLayer
+		if mapping.NaturalPlacetypes[doc.area.Placetype] {
+			layer = NaturalLayer
+		}
+
+		tmpNode := structures.TmpNode{
+			ID:         doc.area.ID,
+			Names:      make(map[string]string),
+			Center:     [2]float32{float32(lat), float32(lng)},
+			Population: doc.population,
+			Timezone:   utils.GetTimezone(lat, lng),
+			Layer:      layer,
+			PlaceType:  doc.area.Placetype,
+			Source:     "wof",
+			Wikidata:   doc.wikidata,
+			Importance: utils.GetImportance(doc.wikidata),
+			Country:    doc.area.Names["country"],
+			BoundingBox: [4]float32{
+				float32(doc.bound.Min[1]),
+				float32(doc.bound.Min[0]),
+				float32(doc.bound.Max[1]),
+				float32(doc.bound.Max[0]),
+			},
+		}
+
+		tmpNode.Rank = utils.CreateRank(map[string]string{
+			"place":    doc.area.Placetype,
+			"wikidata": doc.wikidata,
+		}, tmpNode.Population, tmpNode.Country)
+
+		adminArea := a.GetCounty(lat, lng)
+		hierarchy := parentLevels(adminArea.Hierarchy, doc.area.Placetype)
+		tmpNode.Hierarchy = hierarchy
+		tmpNode.Regions = regionsFromHierarchy(hierarchy)
+		if tmpNode.Country == "" {
+			tmpNode.Country = adminArea.Country
+		}
+
+		tmpNode.Names["name"] = doc.area.Names["name"]
+		for _, lang := range config.Languages {
+			tmpNode.Names[lang] = doc.area.Names["name:"+lang]
+		}
+
+		alternateNames := make([]string, 0)
+		for key, value := range doc.area.Names {
+			if strings.HasPrefix(key, "name:") && value != "" {
+				alternateNames = append(alternateNames, value)
+			}
+		}
+
+		out = append(out, &InsertibleNode{
+			Node:            tmpNode,
+			AlternamteNames: alternateNames,
+			Aliases:         doc.aliases,
+			Geometry:        doc.geometry,
+		})
+	}
+
+	return out
+}
+
+// parentLevels returns the levels of a hierarchy above an admin area of the
+// placetype. Hierarchies of other placetypes are returned unchanged.
+func parentLevels(hierarchy []structures.AdminLevel, placetype string) []structures.AdminLevel {
+	level := mapping.GetAdminLevel(placetype)
+	if level < 0 {
+		return hierarchy
+	}
+	parents := make([]structures.AdminLevel, 0, len(hierarchy))
+	for _, parent := range hierarchy {
+		if mapping.GetAdminLevel(parent.Placetype) < level {
+			parents = append(parents, parent)
+		}
+	}
+	return parents
+}
+
+// adminFilters are the tag filter rules of the OSM admin relations, one per
+// admin_level with the placetype of mapping.OSMAdminPlacetypes.
+func adminFilters() []TagFilter {
+	filters := make([]TagFilter, 0, len(mapping.OSMAdminPlacetypes))
+	for level, placetype := range mapping.OSMAdminPlacetypes {
+		if placetype == "" {
+			continue
+		}
+		filters = append(filters, TagFilter{
+			Tags:  []string{"boundary=administrative", "admin_level=" + strconv.Itoa(level)},
+			Types: []string{"relation"},
+			Layer: AdminLayer,
+			Type:  placetype,
+		})
+	}
+	return filters
+}
+
+// MergeRelations merges the OSM admin relations into the Who's On First
+// documents with the same Wikidata ID, so the area is only indexed once and
+// /relation/:id resolves to the WOF document. It has to be called before
+// Documents. The other relations are indexed as admin documents of their
+// own.
+func (a AdminTree) MergeRelations(areas []*osmObject) int {
+	byWikidata := make(map[string]int, len(a.documents))
+	for i, doc := range a.documents {
+		if doc.wikidata != "" && !mapping.NaturalPlacetypes[doc.area.Placetype] {
+			byWikidata[doc.wikidata] = i
+		}
+	}
+
+	merged := 0
+	for _, area := range areas {
+		if area.merged || area.Filter == nil || area.Filter.Layer != AdminLayer {
+			continue
+		}
+		i, ok := byWikidata[area.Tags.Find("wikidata")]
+		if !ok {
+			continue
+		}
+		a.documents[i].aliases = append(a.documents[i].aliases, structures.DocumentKey(area.Type, area.ID))
+		area.merged = true
+		merged++
+	}
+
+	return merged
+}
+
+// AddRelationPolygons persists the polygons of the OSM admin relations that
+// were not merged into a Who's On First area. Polygons of Who's On First
+// are added first, so they win if both have the same placetype.
+func (a AdminTree) AddRelationPolygons(areas []*osmObject) int {
+	added := 0
+	for _, area := range areas {
+		if area.merged || area.Filter == nil || area.Filter.Layer != AdminLayer || len(area.Boundary) == 0 {
+			continue
+		}
+
+		name := area.Tags.Find("name")
+		names := []string{name}
+		for _, lang := range config.Languages {
+			langName := area.Tags.Find("name:" + lang)
+			if langName == "" {
+				langName = name
+			}
+			names = append(names, langName)
+		}
+
+		a.Polygons.Add(structures.AdminPolygon{
+			ID:        area.ID,
+			Placetype: area.Filter.PlaceType(area.Tags),
+			Country:   a.GetCountry(float64(area.Center[0]), float64(area.Center[1])),
+			Source:    "osm",
+			Names:     names,
+			Geometry:  area.Boundary,
+		})
+		added++
+	}
+
+	return added
+}
+
+// Countries returns the country table stored in the database, with localized
+// names from Who's On First and the static metadata from mapping.CountryInfo.
+func (a AdminTree) Countries() *structures.CountryTable {
+	table := structures.NewCountryTable()
+
+	for _, code := range mapping.CountryCodes[1:] {
+		info := mapping.CountryInfo[code]
+		country := structures.Country{
+			Code:        code,
+			ISO3:        info.ISO3,
+			Continent:   info.Continent,
+			CallingCode: info.CallingCode,
+			Currency:    info.Currency,
+		}
+
+		wof, ok := a.countries[code]
+		if ok {
+			country.BoundingBox = [4]float32{
+				float32(wof.bound.Min[1]),
+				float32(wof.bound.Min[0]),
+				float32(wof.bound.Max[1]),
+				float32(wof.bound.Max[0]),
+			}
+		}
+
+		name := wof.area.Names["name"]
+		if name == "" {
+			name = code
+		}
+		country.Names = []string{name}
+		for _, lang := range config.Languages {
+			langName := wof.area.Names["name:"+lang]
+			if langName == "" {
+				langName = name
+			}
+			country.Names = append(country.Names, langName)
+		}
+
+		table.Add(country)
+	}
+
+	log.Printf("[ADMIN] Found localized names for %d of %d countries", len(a.countries), len(mapping.CountryCodes)-1)
+
+	return table
+}
+
+// simplifyAdminArea converts an admin area into the simplified polygon that is
+// stored in the database.
+func simplifyAdminArea(admin DataAdminArea) (structures.AdminPolygon, bool) {
+	multiPolygon := simplifyGeometry(admin.Geometry)
+	if multiPolygon == nil {
+		return structures.AdminPolygon{}, false
+	}
+
+	names := []string{admin.Names["name"]}
+	for _, lang := range config.Languages {
+		names = append(names, admin.Names["name:"+lang])
+	}
+
+	return structures.AdminPolygon{
+		ID:        admin.ID,
+		Placetype: admin.Placetype,
+		Country:   admin.Names["country"],
+		Source:    "wof",
+		Names:     names,
+		Geometry:  multiPolygon,
+	}, true
+}
+
+// simplifyGeometry simplifies the polygon of an admin area, admin relation
+// or natural feature. It returns nil for geometries without an area.
+func simplifyGeometry(geometry orb.Geometry) orb.MultiPolygon {
+	var multiPolygon orb.MultiPolygon
+	switch g := geometry.(type) {
+	case orb.Polygon:
+		multiPolygon = orb.MultiPolygon{g.Clone()}
+	case orb.MultiPolygon:
+		multiPolygon = g.Clone()
+	default:
+		return nil
+	}
+
+	multiPolygon = simplify.DouglasPeucker(adminSimplifyTolerance).MultiPolygon(multiPolygon)
+	if len(multiPolygon) == 0 {
+		return nil
+	}
+	return multiPolygon
+}
+
+// GetCountry returns the country code of a coordinate from the country
+// boundaries only, it is much faster than GetCounty.
+func (a AdminTree) GetCountry(lat, lng float64) string {
+	c := a.countryGrid.Search([2]float64{lng, lat})
+	if c == nil {
+		return ""
+	}
+	country, _ := c.Properties["ISO3166-1:alpha2"].(string)
+	return country
+}
+
 func (a AdminTree) GetCounty(lat, lng float64) AdminArea {
 	var adminAreas map[string]DataAdminArea = make(map[string]DataAdminArea)
 	var country string = ""
 
 	a.adminTree.Search([2]float64{lng, lat}, [2]float64{lng, lat}, func(min, max [2]float64, data interface{}) bool {
 		geojson := data.(DataAdminArea)
-		if geojson.Geometry.GeoJSONType() == "Polygon" {
-			polygon := geojson.Geometry.(orb.Polygon)
-			if planar.PolygonContains(polygon, orb.Point{lng, lat}) {
-				adminAreas[geojson.Placetype] = geojson
-				if geojson.Names["country"] != "" && country == "" {
-					country = geojson.Names["country"]
-				}
-			}
+		if _, ok := adminAreas[geojson.Placetype]; ok {
+			return true
+		}
 
-		} else if geojson.Geometry.GeoJSONType() == "MultiPolygon" {
-			multiPolygon := geojson.Geometry.(orb.MultiPolygon)
-			for _, polygon := range multiPolygon {
-				if planar.PolygonContains(polygon, orb.Point{lng, lat}) {
-					adminAreas[geojson.Placetype] = geojson
-					if geojson.Names["country"] != "" && country == "" {
-						country = geojson.Names["country"]
-					}
-				}
+		if geometryContains(geojson.Geometry, lat, lng) {
+			adminAreas[geojson.Placetype] = geojson
+			if geojson.Names["country"] != "" && country == "" {
+				country = geojson.Names["country"]
 			}
 		}
 
-		_, okRegion := adminAreas["region"]
+		return true
+	})
 
-		_, okCounty := adminAreas["county"]
+	a.countryTree.Search([2]float64{lng, lat}, [2]float64{lng, lat}, func(min, max [2]float64, data interface{}) bool {
+		geojson := data.(DataAdminArea)
+		if geojson.Names["country"] == "" || !geometryContains(geojson.Geometry, lat, lng) {
+			return true
+		}
 
-		if okRegion && okCounty {
+		if country == "" {
+			country = geojson.Names["country"]
+		}
+		if geojson.Placetype == "country" {
+			adminAreas["country"] = geojson
 			return false
 		}
 
@@ -199,36 +619,90 @@ func (a AdminTree) GetCounty(lat, lng float64) AdminArea {
 	})
 
 	if country == "" {
-		a.countryTree.Search([2]float64{lng, lat}, [2]float64{lng, lat}, func(min, max [2]float64, data interface{}) bool {
-			geojson := data.(DataAdminArea)
-			if geojson.Geometry.GeoJSONType() == "Polygon" {
-				polygon := geojson.Geometry.(orb.Polygon)
-				if planar.PolygonContains(polygon, orb.Point{lng, lat}) {
-					if geojson.Names["country"] != "" {
-						country = geojson.Names["country"]
-						return false
-					}
-				}
-			} else if geojson.Geometry.GeoJSONType() == "MultiPolygon" {
-				multiPolygon := geojson.Geometry.(orb.MultiPolygon)
-				for _, polygon := range multiPolygon {
-					if planar.PolygonContains(polygon, orb.Point{lng, lat}) {
-						if geojson.Names["country"] != "" {
-							country = geojson.Names["country"]
-							return false
-						}
-					}
-				}
-			}
+		c := a.countryGrid.Search([2]float64{lng, lat})
+		if c != nil {
+			country = c.Properties["ISO3166-1:alpha2"].(string)
+		}
+	}
 
-			return true
+	a.findLocalAreas(adminAreas, lat, lng)
+
+	hierarchy := make([]structures.AdminLevel, 0, len(adminAreas))
+	for _, placetype := range mapping.AdminPlacetypes {
+		area, ok := adminAreas[placetype]
+		if !ok {
+			continue
+		}
+
+		names := map[string]string{"name": area.Names["name"]}
+		for _, lang := range config.Languages {
+			names[lang] = area.Names["name:"+lang]
+		}
+
+		hierarchy = append(hierarchy, structures.AdminLevel{
+			Placetype: placetype,
+			ID:        area.ID,
+			Names:     names,
 		})
 	}
 
-	if country == "" {
-		c := a.countryGrid.Search([2]float64{lng, lat})
-		if c != nil {
-			country = c.Properties["ISO3166-1:alpha2"].(string)
+	return AdminArea{
+		Regions:   regionsFromHierarchy(hierarchy),
+		Hierarchy: hierarchy,
+		Country:   country,
+	}
+}
+
+// findLocalAreas adds the areas below county to the areas found by their
+// polygons. Of every placetype the area with the nearest label position is
+// taken whose bounding box contains the coordinate and whose Who's On First
+// hierarchy contains the smallest area found so far.
+func (a AdminTree) findLocalAreas(adminAreas map[string]DataAdminArea, lat, lng float64) {
+	var parent int64
+	for _, placetype := range mapping.AdminPlacetypes {
+		if area, ok := adminAreas[placetype]; ok && hasAdminPolygon(placetype) {
+			parent = area.ID
+		}
+	}
+	if parent == 0 {
+		return
+	}
+
+	candidates := make(map[string][]localArea)
+	a.localTree.Search([2]float64{lng, lat}, [2]float64{lng, lat}, func(min, max [2]float64, data interface{}) bool {
+		area := data.(localArea)
+		candidates[area.Placetype] = append(candidates[area.Placetype], area)
+		return true
+	})
+
+	for _, placetype := range mapping.AdminPlacetypes {
+		var best *localArea
+		bestDistance := math.Inf(1)
+		for i, area := range candidates[placetype] {
+			if !area.hasParent(parent) {
+				continue
+			}
+			if distance := geo.Haversine(lat, lng, area.center[1], area.center[0]); distance < bestDistance {
+				best, bestDistance = &candidates[placetype][i], distance
+			}
+		}
+		if best != nil {
+			adminAreas[placetype] = best.DataAdminArea
+			parent = best.ID
+		}
+	}
+}
+
+// regionsFromHierarchy returns the region and county names of a hierarchy per
+// language.
+func regionsFromHierarchy(hierarchy []structures.AdminLevel) map[string]structures.Region {
+	var region, county structures.AdminLevel
+	for _, level := range hierarchy {
+		switch level.Placetype {
+		case "region":
+			region = level
+		case "county":
+			county = level
 		}
 	}
 
@@ -236,20 +710,27 @@ func (a AdminTree) GetCounty(lat, lng float64) AdminArea {
 
 	for _, lang := range config.Languages {
 		regions[lang] = structures.Region{
-			Region:    adminAreas["region"].Names["name:"+lang],
-			SubRegion: adminAreas["county"].Names["name:"+lang],
+			Region:    region.Names[lang],
+			SubRegion: county.Names[lang],
 		}
 	}
 
 	regions["name"] = structures.Region{
-		Region:    adminAreas["region"].Names["name"],
-		SubRegion: adminAreas["county"].Names["name"],
+		Region:    region.Names["name"],
+		SubRegion: county.Names["name"],
 	}
 
-	return AdminArea{
-		Regions: regions,
-		Country: country,
+	return regions
+}
+
+func geometryContains(geometry orb.Geometry, lat, lng float64) bool {
+	switch g := geometry.(type) {
+	case orb.Polygon:
+		return planar.PolygonContains(g, orb.Point{lng, lat})
+	case orb.MultiPolygon:
+		return planar.MultiPolygonContains(g, orb.Point{lng, lat})
 	}
+	return false
 }
 
 func GenerateCountries() *structures.UniformGridIndex {
@@ -0,0 +1,171 @@
+package generate
+
+import (
+	"hstin/gocoder/structures"
+	"hstin/gocoder/utils"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/osm"
+)
+
+// testAdminTree returns an admin tree of the areas, sorted into the trees
+// like LoadAdminAreas does. parents are the wof:hierarchy IDs of the areas
+// below county.
+func testAdminTree(areas []DataAdminArea, parents map[int64][]int64) AdminTree {
+	var tree AdminTree
+	for _, admin := range areas {
+		bounds := admin.Geometry.Bound()
+		min, max := [2]float64{bounds.Min[0], bounds.Min[1]}, [2]float64{bounds.Max[0], bounds.Max[1]}
+		switch {
+		case admin.Placetype == "country":
+			tree.countryTree.Insert(min, max, admin)
+		case hasAdminPolygon(admin.Placetype):
+			tree.adminTree.Insert(min, max, admin)
+		default:
+			feature := geojson.NewFeature(admin.Geometry)
+			hierarchy := map[string]interface{}{}
+			for i, id := range parents[admin.ID] {
+				hierarchy["parent_"+strconv.Itoa(i)+"_id"] = float64(id)
+			}
+			feature.Properties["wof:hierarchy"] = []interface{}{hierarchy}
+			tree.localTree.Insert(min, max, newLocalArea(admin, feature))
+		}
+	}
+	return tree
+}
+
+func testArea(id int64, placetype, name string, minLng, minLat, maxLng, maxLat float64) DataAdminArea {
+	return DataAdminArea{
+		ID:        id,
+		Placetype: placetype,
+		Names:     map[string]string{"name": name, "name:en": name, "country": "DE"},
+		Geometry: orb.Polygon{{
+			{minLng, minLat}, {maxLng, minLat}, {maxLng, maxLat}, {minLng, maxLat}, {minLng, minLat},
+		}},
+	}
+}
+
+func TestGetCounty(t *testing.T) {
+	tree := testAdminTree([]DataAdminArea{
+		testArea(85633111, "country", "Germany", 6, 47, 15, 55),
+		testArea(85682523, "region", "Berlin", 13, 52.3, 13.8, 52.7),
+		testArea(102063261, "county", "Berlin", 13, 52.3, 13.8, 52.7),
+		testArea(85682555, "region", "Brandenburg", 11, 51, 13, 53.5),
+		// Localities only keep their label position, the center of the box
+		testArea(101748799, "locality", "Berlin", 13, 52.3, 13.8, 52.7),
+		testArea(101752475, "locality", "Potsdam", 13.35, 52.5, 13.45, 52.55),
+		testArea(421205771, "borough", "Mitte", 13.3, 52.5, 13.45, 52.6),
+	}, map[int64][]int64{
+		101748799: {85633111, 85682523, 102063261},
+		101752475: {85633111, 85682555},
+		421205771: {85633111, 85682523, 102063261, 101748799},
+	})
+
+	tests := []struct {
+		name       string
+		lat, lng   float64
+		placetypes []string
+		region     string
+	}{
+		// Potsdam is nearer, but not in the county of Berlin
+		{"mitte", 52.52, 13.40, []string{"country", "region", "county", "locality", "borough"}, "Berlin"},
+		{"brandenburg", 52.4, 12.5, []string{"country", "region"}, "Brandenburg"},
+		{"country only", 48, 9, []string{"country"}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			area := tree.GetCounty(test.lat, test.lng)
+			placetypes := make([]string, len(area.Hierarchy))
+			for i, level := range area.Hierarchy {
+				placetypes[i] = level.Placetype
+			}
+			if !reflect.DeepEqual(placetypes, test.placetypes) {
+				t.Errorf("got %v, want %v", placetypes, test.placetypes)
+			}
+			if area.Country != "DE" {
+				t.Errorf("country %q, want DE", area.Country)
+			}
+			if region := area.Regions["en"].Region; region != test.region {
+				t.Errorf("region %q, want %q", region, test.region)
+			}
+		})
+	}
+}
+
+func TestParentLevels(t *testing.T) {
+	hierarchy := []structures.AdminLevel{
+		{Placetype: "country", ID: 85633111},
+		{Placetype: "region", ID: 85682523},
+		{Placetype: "county", ID: 102063261},
+		{Placetype: "locality", ID: 101748799},
+	}
+
+	tests := []struct {
+		placetype string
+		want      int
+	}{
+		{"country", 0},
+		{"region", 1},
+		{"county", 2},
+		{"borough", 4},
+		// Places that aren't admin areas keep their hierarchy
+		{"hamlet", 4},
+	}
+	for _, test := range tests {
+		if got := parentLevels(hierarchy, test.placetype); !reflect.DeepEqual(got, hierarchy[:test.want]) {
+			t.Errorf("%s: got %+v, want %+v", test.placetype, got, hierarchy[:test.want])
+		}
+	}
+}
+
+func TestMergeRelations(t *testing.T) {
+	tree := AdminTree{documents: []adminDocument{
+		{area: DataAdminArea{ID: 85682571, Placetype: "region"}, wikidata: "Q980"},
+		{area: DataAdminArea{ID: 404528583, Placetype: "marinearea"}, wikidata: "Q4918"},
+	}}
+	admin := &TagFilter{Layer: AdminLayer}
+	relations := []*osmObject{
+		{Type: "relation", ID: 2145268, Tags: osm.Tags{{Key: "wikidata", Value: "Q980"}}, Filter: admin},
+		// Natural features are no admin areas, other layers aren't merged
+		{Type: "relation", ID: 1543125, Tags: osm.Tags{{Key: "wikidata", Value: "Q4918"}}, Filter: admin},
+		{Type: "relation", ID: 62422, Tags: osm.Tags{{Key: "wikidata", Value: "Q64"}}, Filter: admin},
+		{Type: "way", ID: 5, Tags: osm.Tags{{Key: "wikidata", Value: "Q980"}}, Filter: &TagFilter{}},
+	}
+
+	if merged := tree.MergeRelations(relations); merged != 1 {
+		t.Errorf("merged %d relations, want 1", merged)
+	}
+	if want := []int64{structures.DocumentKey("relation", 2145268)}; !reflect.DeepEqual(tree.documents[0].aliases, want) {
+		t.Errorf("aliases %v, want %v", tree.documents[0].aliases, want)
+	}
+	for i, relation := range relations {
+		if relation.merged != (i == 0) {
+			t.Errorf("%s %d: merged %v", relation.Type, relation.ID, relation.merged)
+		}
+	}
+}
+
+func TestAdminRank(t *testing.T) {
+	rank := func(placetype string, population int64) int {
+		return utils.CreateRank(map[string]string{"place": placetype}, population, "DE")
+	}
+
+	tests := []struct {
+		name          string
+		higher, lower int
+	}{
+		// A city comes before the region of the same name and size
+		{"city state", rank("city", 3700000), rank("region", 3700000)},
+		{"region before village", rank("region", 13000000), rank("village", 900)},
+		{"country before city", rank("country", 83000000), rank("city", 3700000)},
+	}
+	for _, test := range tests {
+		if test.higher <= test.lower {
+			t.Errorf("%s: rank %d, want more than %d", test.name, test.higher, test.lower)
+		}
+	}
+}
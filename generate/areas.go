@@ -0,0 +1,398 @@
+package generate
+
+import (
+	"context"
+	"hstin/gocoder/config"
+	"hstin/gocoder/geo"
+	"hstin/gocoder/structures"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+)
+
+// osmObject is a node, way or relation selected by the tag filters, reduced
+// to what is needed to index it.
+type osmObject struct {
+	Type   string
+	ID     int64
+	Tags   osm.Tags
+	Filter *TagFilter
+	// Center is the node position or the point on surface of an area.
+	Center      [2]float32
+	Centroid    [2]float32
+	BoundingBox [4]float32
+	// HasBoundingBox is false for nodes, their bounding box is looked up in
+	// the admin boundary bounding boxes.
+	HasBoundingBox bool
+	// Aliases are the document keys of areas merged into this object.
+	Aliases []int64
+	// Geometry is the simplified polygon of natural features, nil for
+	// other objects.
+	Geometry orb.MultiPolygon
+	// Boundary is the simplified polygon of admin relations down to county,
+	// persisted in the admin index.
+	Boundary orb.MultiPolygon
+
+	merged bool
+}
+
+// areaMember is a way member of a relation.
+type areaMember struct {
+	ID   int64
+	Role string
+}
+
+// areaLabel links a node to the relation it is the label or admin centre of.
+type areaLabel struct {
+	Area *osmObject
+	Role string
+}
+
+// mergeLabel merges an area into the node that labels it. Label nodes always
+// describe the area, an admin centre only if it has the same name, e.g. the
+// town node of a municipality but not the capital of a county.
+func (o *osmObject) mergeLabel(label areaLabel) bool {
+	if label.Area.merged {
+		return false
+	}
+	if label.Role != "label" && o.Tags.Find("name") != label.Area.Tags.Find("name") {
+		return false
+	}
+
+	o.BoundingBox = label.Area.BoundingBox
+	o.Centroid = label.Area.Centroid
+	o.Geometry = label.Area.Geometry
+	o.HasBoundingBox = true
+	o.Aliases = append(o.Aliases, label.Area.Aliases...)
+	o.Aliases = append(o.Aliases, structures.DocumentKey(label.Area.Type, label.Area.ID))
+	label.Area.merged = true
+
+	return true
+}
+
+// CollectAreas returns the ways and relations selected by the tag filters
+// with their geometry, and the nodes that label one of the relations. The
+// planet file is read up to three times: once for the matching ways and
+// relations, once for the member ways of the relations and once for the
+// coordinates of all referenced nodes.
+func CollectAreas(filters *TagFilters) ([]*osmObject, map[int64]areaLabel) {
+	labels := make(map[int64]areaLabel)
+
+	collectWays := filters.HasType("way")
+	collectRelations := filters.HasType("relation")
+	if !collectWays && !collectRelations {
+		return nil, labels
+	}
+
+	log.Printf("[AREAS] Collecting ways and relations")
+	startTime := time.Now()
+
+	f, err := os.Open(config.Planet)
+	if err != nil {
+		log.Fatalf("[AREAS] Failed to open planet file %s: %v", config.Planet, err)
+	}
+	defer f.Close()
+
+	newPlanetScanner := func() *osmpbf.Scanner {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			log.Fatalf("[AREAS] Failed to seek to start of planet file: %v", err)
+		}
+		return osmpbf.New(context.Background(), f, runtime.GOMAXPROCS(-1))
+	}
+
+	areas := make([]*osmObject, 0)
+	members := make(map[*osmObject][]areaMember)
+	wayNodes := make(map[int64][]int64)
+	neededWays := make(map[int64]bool)
+
+	// 1) Matching ways and relations
+	scanner := newPlanetScanner()
+	scanner.SkipNodes = true
+	scanner.SkipWays = !collectWays
+	scanner.SkipRelations = !collectRelations
+	scanner.FilterWay = func(way *osm.Way) bool {
+		return filters.Includes("way", way.Tags)
+	}
+	scanner.FilterRelation = func(relation *osm.Relation) bool {
+		return filters.Includes("relation", relation.Tags)
+	}
+
+	for scanner.Scan() {
+		switch o := scanner.Object().(type) {
+		case *osm.Way:
+			area := &osmObject{
+				Type:   "way",
+				ID:     int64(o.ID),
+				Tags:   o.Tags,
+				Filter: filters.Match("way", o.Tags),
+			}
+			wayNodes[area.ID] = wayRefs(o)
+			members[area] = []areaMember{{ID: area.ID, Role: "outer"}}
+			areas = append(areas, area)
+		case *osm.Relation:
+			area := &osmObject{
+				Type:   "relation",
+				ID:     int64(o.ID),
+				Tags:   o.Tags,
+				Filter: filters.Match("relation", o.Tags),
+			}
+			ways := make([]areaMember, 0)
+			for _, member := range o.Members {
+				switch member.Type {
+				case osm.TypeWay:
+					ways = append(ways, areaMember{ID: member.Ref, Role: member.Role})
+					neededWays[member.Ref] = true
+				case osm.TypeNode:
+					if member.Role != "label" && member.Role != "admin_centre" {
+						continue
+					}
+					// A label wins over an admin centre
+					if existing, ok := labels[member.Ref]; ok && existing.Role == "label" {
+						continue
+					}
+					labels[member.Ref] = areaLabel{Area: area, Role: member.Role}
+				}
+			}
+			members[area] = ways
+			areas = append(areas, area)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("[AREAS] Scanner error during first pass: %v", err)
+	}
+	scanner.Close()
+	log.Printf("[AREAS] Found %d ways and relations", len(areas))
+
+	// 2) Member ways of relations
+	for wayID := range wayNodes {
+		delete(neededWays, wayID)
+	}
+	if len(neededWays) > 0 {
+		scanner = newPlanetScanner()
+		scanner.SkipNodes = true
+		scanner.SkipRelations = true
+		scanner.FilterWay = func(way *osm.Way) bool {
+			return neededWays[int64(way.ID)]
+		}
+
+		for scanner.Scan() {
+			if o, ok := scanner.Object().(*osm.Way); ok {
+				wayNodes[int64(o.ID)] = wayRefs(o)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Fatalf("[AREAS] Scanner error during second pass: %v", err)
+		}
+		scanner.Close()
+		log.Printf("[AREAS] Loaded %d member ways", len(neededWays))
+	}
+
+	// 3) Node coordinates
+	neededNodes := make(map[int64]bool)
+	for _, refs := range wayNodes {
+		for _, ref := range refs {
+			neededNodes[ref] = true
+		}
+	}
+
+	coordinates := make(map[int64]orb.Point, len(neededNodes))
+	scanner = newPlanetScanner()
+	scanner.SkipWays = true
+	scanner.SkipRelations = true
+	scanner.FilterNode = func(node *osm.Node) bool {
+		return neededNodes[int64(node.ID)]
+	}
+
+	for scanner.Scan() {
+		if o, ok := scanner.Object().(*osm.Node); ok {
+			coordinates[int64(o.ID)] = orb.Point{o.Lon, o.Lat}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("[AREAS] Scanner error during third pass: %v", err)
+	}
+	scanner.Close()
+	log.Printf("[AREAS] Loaded %d node coordinates", len(coordinates))
+
+	out := make([]*osmObject, 0, len(areas))
+	polygons := 0
+	for _, area := range areas {
+		geometry, points := assembleArea(members[area], wayNodes, coordinates)
+
+		// Objects outside of an extract have no coordinates
+		if len(points) == 0 {
+			continue
+		}
+
+		var center, centroid orb.Point
+		var bound orb.Bound
+		if len(geometry) > 0 {
+			bound = geometry.Bound()
+			centroid = geo.Centroid(geometry)
+			center = geo.PointOnSurface(geometry)
+			polygons++
+			switch area.Filter.Layer {
+			case NaturalLayer:
+				area.Geometry = simplifyGeometry(geometry)
+			case AdminLayer:
+				if hasAdminPolygon(area.Filter.PlaceType(area.Tags)) {
+					area.Boundary = simplifyGeometry(geometry)
+				}
+			}
+		} else {
+			// Unclosed ways and broken relations only have a bounding box
+			bound = points.Bound()
+			centroid = bound.Center()
+			center = points[len(points)/2]
+		}
+
+		area.BoundingBox = [4]float32{
+			float32(bound.Min[1]),
+			float32(bound.Min[0]),
+			float32(bound.Max[1]),
+			float32(bound.Max[0]),
+		}
+		area.HasBoundingBox = true
+		area.Center = [2]float32{float32(center[1]), float32(center[0])}
+		area.Centroid = [2]float32{float32(centroid[1]), float32(centroid[0])}
+		out = append(out, area)
+	}
+
+	log.Printf("[AREAS] Collected %d ways and relations (%d with polygons) in %s", len(out), polygons, time.Since(startTime))
+
+	return out, labels
+}
+
+func wayRefs(way *osm.Way) []int64 {
+	refs := make([]int64, 0, len(way.Nodes))
+	for _, node := range way.Nodes {
+		refs = append(refs, int64(node.ID))
+	}
+	return refs
+}
+
+// assembleArea joins the member ways of an area into rings and returns the
+// resulting multipolygon together with all member coordinates. Inner rings
+// are added to the outer ring that contains them.
+func assembleArea(areaMembers []areaMember, wayNodes map[int64][]int64, coordinates map[int64]orb.Point) (orb.MultiPolygon, orb.MultiPoint) {
+	outer := make([][]int64, 0)
+	inner := make([][]int64, 0)
+	points := make(orb.MultiPoint, 0)
+
+	for _, member := range areaMembers {
+		refs := wayNodes[member.ID]
+		if len(refs) < 2 {
+			continue
+		}
+		for _, ref := range refs {
+			if point, ok := coordinates[ref]; ok {
+				points = append(points, point)
+			}
+		}
+		if member.Role == "inner" {
+			inner = append(inner, refs)
+		} else {
+			outer = append(outer, refs)
+		}
+	}
+
+	toRing := func(refs []int64) (orb.Ring, bool) {
+		ring := make(orb.Ring, 0, len(refs))
+		for _, ref := range refs {
+			point, ok := coordinates[ref]
+			if !ok {
+				return nil, false
+			}
+			ring = append(ring, point)
+		}
+		return ring, len(ring) >= 4
+	}
+
+	geometry := make(orb.MultiPolygon, 0)
+	for _, refs := range joinRings(outer) {
+		if ring, ok := toRing(refs); ok {
+			if ring.Orientation() != orb.CCW {
+				ring.Reverse()
+			}
+			geometry = append(geometry, orb.Polygon{ring})
+		}
+	}
+
+	for _, refs := range joinRings(inner) {
+		ring, ok := toRing(refs)
+		if !ok {
+			continue
+		}
+		if ring.Orientation() != orb.CW {
+			ring.Reverse()
+		}
+		for i := range geometry {
+			if planar.RingContains(geometry[i][0], ring[0]) {
+				geometry[i] = append(geometry[i], ring)
+				break
+			}
+		}
+	}
+
+	return geometry, points
+}
+
+// joinRings joins way segments that share end nodes into closed rings.
+// Segments that can't be closed are dropped.
+func joinRings(segments [][]int64) [][]int64 {
+	rings := make([][]int64, 0)
+	used := make([]bool, len(segments))
+
+	// End node -> segments that start or end there
+	ends := make(map[int64][]int, len(segments)*2)
+	for i, segment := range segments {
+		ends[segment[0]] = append(ends[segment[0]], i)
+		ends[segment[len(segment)-1]] = append(ends[segment[len(segment)-1]], i)
+	}
+
+	for i, segment := range segments {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		ring := append([]int64(nil), segment...)
+
+		for ring[0] != ring[len(ring)-1] {
+			last := ring[len(ring)-1]
+			next := -1
+			for _, j := range ends[last] {
+				if !used[j] {
+					next = j
+					break
+				}
+			}
+			if next < 0 {
+				break
+			}
+			used[next] = true
+
+			candidate := segments[next]
+			if candidate[0] != last {
+				reversed := make([]int64, len(candidate))
+				for k := range candidate {
+					reversed[k] = candidate[len(candidate)-1-k]
+				}
+				candidate = reversed
+			}
+			ring = append(ring, candidate[1:]...)
+		}
+
+		if len(ring) >= 4 && ring[0] == ring[len(ring)-1] {
+			rings = append(rings, ring)
+		}
+	}
+
+	return rings
+}
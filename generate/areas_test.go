@@ -0,0 +1,116 @@
+package generate
+
+import (
+	"hstin/gocoder/geo"
+	"hstin/gocoder/structures"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+)
+
+func TestJoinRings(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments [][]int64
+		want     [][]int64
+	}{
+		{"closed way", [][]int64{{1, 2, 3, 1}}, [][]int64{{1, 2, 3, 1}}},
+		{"two segments", [][]int64{{1, 2, 3}, {3, 4, 1}}, [][]int64{{1, 2, 3, 4, 1}}},
+		// Members may point either way
+		{"reversed segment", [][]int64{{1, 2, 3}, {1, 4, 3}}, [][]int64{{1, 2, 3, 4, 1}}},
+		{"two rings", [][]int64{{1, 2, 3, 1}, {5, 6}, {6, 7, 5}}, [][]int64{{1, 2, 3, 1}, {5, 6, 7, 5}}},
+		{"unclosed", [][]int64{{1, 2, 3}, {3, 4}}, [][]int64{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := joinRings(test.segments); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAssembleArea(t *testing.T) {
+	// A C-shaped island of two outer ways with a lake, the center of its
+	// bounding box lies in the bay
+	coordinates := map[int64]orb.Point{
+		1: {0, 0}, 2: {4, 0}, 3: {4, 1}, 4: {1, 1}, 5: {1, 3}, 6: {4, 3}, 7: {4, 4}, 8: {0, 4},
+		10: {0.2, 0.2}, 11: {0.8, 0.2}, 12: {0.8, 0.8},
+	}
+	wayNodes := map[int64][]int64{
+		100: {1, 2, 3, 4, 5},
+		101: {8, 7, 6, 5},
+		102: {8, 1},
+		103: {10, 11, 12, 10},
+		// Its nodes are outside of the extract
+		104: {20, 21, 22, 20},
+	}
+	members := []areaMember{{100, "outer"}, {101, "outer"}, {102, ""}, {103, "inner"}, {104, "outer"}}
+
+	geometry, points := assembleArea(members, wayNodes, coordinates)
+	if len(geometry) != 1 || len(geometry[0]) != 2 {
+		t.Fatalf("got %d polygons, want one with a hole: %v", len(geometry), geometry)
+	}
+	if len(points) != 15 {
+		t.Errorf("got %d member points, want 15", len(points))
+	}
+	if geometry[0][0].Orientation() != orb.CCW || geometry[0][1].Orientation() != orb.CW {
+		t.Errorf("outer ring %v, inner ring %v", geometry[0][0].Orientation(), geometry[0][1].Orientation())
+	}
+
+	if bay := geometry.Bound().Center(); planar.MultiPolygonContains(geometry, bay) {
+		t.Fatalf("center %v of the bounding box is on the island", bay)
+	}
+	if center := geo.PointOnSurface(geometry); !planar.MultiPolygonContains(geometry, center) {
+		t.Errorf("point on surface %v is not on the island", center)
+	}
+}
+
+func TestMergeLabel(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     string
+		area     string
+		role     string
+		merged   bool
+		previous bool
+	}{
+		{"label", "Berlin", "Land Berlin", "label", true, false},
+		{"admin centre", "Potsdam", "Potsdam", "admin_centre", true, false},
+		// The capital of a county is not the county
+		{"capital", "Potsdam", "Landkreis Potsdam-Mittelmark", "admin_centre", false, false},
+		{"merged before", "Berlin", "Berlin", "label", false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := &osmObject{Type: "node", ID: 1, Tags: tags("name=" + test.node)}
+			area := &osmObject{
+				Type:        "relation",
+				ID:          62422,
+				Tags:        tags("name=" + test.area),
+				BoundingBox: [4]float32{52.3, 13.0, 52.7, 13.8},
+				Aliases:     []int64{structures.DocumentKey("way", 5)},
+				merged:      test.previous,
+			}
+
+			if merged := node.mergeLabel(areaLabel{Area: area, Role: test.role}); merged != test.merged {
+				t.Fatalf("merged %v, want %v", merged, test.merged)
+			}
+			if !test.merged {
+				if node.HasBoundingBox || len(node.Aliases) != 0 {
+					t.Errorf("node changed: %+v", node)
+				}
+				return
+			}
+			if !area.merged || !node.HasBoundingBox || node.BoundingBox != area.BoundingBox {
+				t.Errorf("area not merged into the node: %+v", node)
+			}
+			want := []int64{structures.DocumentKey("way", 5), structures.DocumentKey("relation", 62422)}
+			if !reflect.DeepEqual(node.Aliases, want) {
+				t.Errorf("aliases %v, want %v", node.Aliases, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package generate
+
+import (
+	"encoding/json"
+	"hstin/gocoder/config"
+	"hstin/gocoder/mapping"
+	"log"
+	"os"
+	"strings"
+)
+
+// Taxonomy is the POI taxonomy used for the database, set by LoadCategories.
+var Taxonomy []mapping.Category
+
+// LoadCategories reads the POI taxonomy. Categories of the config file come
+// first and replace the built-in categories with the same name, the other
+// built-in categories are kept.
+func LoadCategories() []mapping.Category {
+	categories := make([]mapping.Category, 0, len(mapping.DefaultCategories))
+
+	if config.PoiCategories != "" {
+		data, err := os.ReadFile(config.PoiCategories)
+		if err != nil {
+			log.Fatalf("[CATEGORIES] Failed to read POI categories: %v", err)
+		}
+
+		var file struct {
+			Categories []mapping.Category `json:"categories"`
+		}
+		if err := json.Unmarshal(data, &file); err != nil {
+			log.Fatalf("[CATEGORIES] Failed to parse POI categories: %v", err)
+		}
+		categories = append(categories, file.Categories...)
+	}
+
+	configured := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		configured[category.Name] = true
+	}
+	for _, category := range mapping.DefaultCategories {
+		if !configured[category.Name] {
+			categories = append(categories, category)
+		}
+	}
+
+	Taxonomy = categories
+	mapping.SetCategoryKeywords(categories)
+	log.Printf("[CATEGORIES] Loaded %d POI categories", len(categories))
+
+	return categories
+}
+
+// categoryFilters turns the taxonomy into tag filter rules for the poi layer,
+// one per tag expression. Rules that match any value of a key store the last
+// part of the category name as place type instead of the value, so keys like
+// shop=* don't add a place type per value.
+func categoryFilters(categories []mapping.Category) []TagFilter {
+	filters := make([]TagFilter, 0, len(categories))
+	for _, category := range categories {
+		for _, tag := range category.Tags {
+			filter := TagFilter{
+				Tags:     []string{tag},
+				Layer:    "poi",
+				Rank:     category.Rank,
+				Category: category.Name,
+			}
+			if expr, ok := parseTagExpr(tag); ok && expr.values == nil {
+				parts := strings.Split(category.Name, ".")
+				filter.Type = parts[len(parts)-1]
+			}
+			filters = append(filters, filter)
+		}
+	}
+	return filters
+}
@@ -0,0 +1,26 @@
+package generate
+
+import (
+	"hstin/gocoder/mapping"
+	"hstin/gocoder/structures"
+	"strings"
+)
+
+// parseCodes returns the transport identifiers of the identifier tags of an
+// object, e.g. the IATA and ICAO codes of an airport. Codes found in more than
+// one tag of the same type are returned once.
+func parseCodes(tags map[string]string) []structures.Code {
+	codes := make([]structures.Code, 0)
+	seen := make(map[string]bool)
+	for _, tag := range mapping.CodeTags {
+		for _, value := range strings.Split(tags[tag.Key], ";") {
+			code := mapping.NormalizeCode(value)
+			if len(code) < 2 || seen[tag.Type+":"+code] {
+				continue
+			}
+			seen[tag.Type+":"+code] = true
+			codes = append(codes, structures.Code{Type: tag.Type, Code: code})
+		}
+	}
+	return codes
+}
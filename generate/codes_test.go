@@ -0,0 +1,27 @@
+package generate
+
+import (
+	"hstin/gocoder/structures"
+	"reflect"
+	"testing"
+)
+
+func TestParseCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		tags map[string]string
+		want []structures.Code
+	}{
+		{"airport", map[string]string{"iata": "FRA", "icao": "EDDF", "name": "Frankfurt"}, []structures.Code{{Type: "iata", Code: "FRA"}, {Type: "icao", Code: "EDDF"}}},
+		// ref:IATA is the same type as iata and duplicates are dropped
+		{"ref tags", map[string]string{"iata": "fra", "ref:IATA": "FRA"}, []structures.Code{{Type: "iata", Code: "FRA"}}},
+		{"several codes", map[string]string{"uic_ref": "8000105; 8098105"}, []structures.Code{{Type: "uic", Code: "8000105"}, {Type: "uic", Code: "8098105"}}},
+		{"too short", map[string]string{"iata": "F", "railway:ref": " "}, []structures.Code{}},
+		{"no codes", map[string]string{"name": "Frankfurt", "ref": "A5"}, []structures.Code{}},
+	}
+	for _, test := range tests {
+		if got := parseCodes(test.tags); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
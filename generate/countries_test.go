@@ -0,0 +1,59 @@
+package generate
+
+import (
+	"hstin/gocoder/config"
+	"hstin/gocoder/mapping"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/orb"
+)
+
+func TestCountries(t *testing.T) {
+	defer func(languages []string) { config.Languages = languages }(config.Languages)
+	config.Languages = []string{"en", "fr"}
+
+	tree := AdminTree{countries: map[string]adminCountry{
+		"DE": {
+			area:  DataAdminArea{Names: map[string]string{"name": "Deutschland", "name:en": "Germany"}},
+			bound: orb.Bound{Min: orb.Point{5.875, 47.25}, Max: orb.Point{15.0625, 55.0625}},
+		},
+	}}
+	table := tree.Countries()
+
+	if len(table.Countries) != len(mapping.CountryCodes)-1 {
+		t.Errorf("got %d countries, want %d", len(table.Countries), len(mapping.CountryCodes)-1)
+	}
+
+	tests := []struct {
+		code        string
+		iso3        string
+		continent   string
+		callingCode string
+		currency    string
+		bbox        [4]float32
+		// names are the default name and the names in config.Languages
+		names []string
+	}{
+		// Languages without a name use the default name
+		{"DE", "DEU", "EU", "+49", "EUR", [4]float32{47.25, 5.875, 55.0625, 15.0625}, []string{"Deutschland", "Germany", "Deutschland"}},
+		// Countries without a Who's On First record are named by their code
+		{"ML", "MLI", "AF", "+223", "XOF", [4]float32{}, []string{"ML", "ML", "ML"}},
+	}
+	for _, test := range tests {
+		country, ok := table.Get(test.code)
+		if !ok {
+			t.Errorf("%s: not found", test.code)
+			continue
+		}
+		if country.ISO3 != test.iso3 || country.Continent != test.continent || country.CallingCode != test.callingCode || country.Currency != test.currency {
+			t.Errorf("%s: got %s %s %s %s, want %s %s %s %s", test.code, country.ISO3, country.Continent, country.CallingCode, country.Currency, test.iso3, test.continent, test.callingCode, test.currency)
+		}
+		if country.BoundingBox != test.bbox {
+			t.Errorf("%s: bounding box %v, want %v", test.code, country.BoundingBox, test.bbox)
+		}
+		if !reflect.DeepEqual(country.Names, test.names) {
+			t.Errorf("%s: names %v, want %v", test.code, country.Names, test.names)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package generate
+
+import (
+	"encoding/json"
+	"fmt"
+	"hstin/gocoder/config"
+	"hstin/gocoder/mapping"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/paulmach/osm"
+)
+
+// TagFilter is one rule of the tag filter config. An OSM object matches the
+// rule if it has one of the listed types and all tag expressions match.
+//
+// Tag expressions:
+//
+//	key           the tag is present (same as key=*)
+//	key=v1|v2     the tag has one of the values
+//	key!=v1|v2    the tag is missing or has none of the values
+//	!key          the tag is missing
+type TagFilter struct {
+	Tags  []string `json:"tags"`
+	Types []string `json:"types,omitempty"`
+	// Exclude drops matching objects instead of indexing them.
+	Exclude bool `json:"exclude,omitempty"`
+	// Layer defaults to the layer of the place type in mapping.PlaceLayer.
+	Layer string `json:"layer,omitempty"`
+	// Type is the place type stored for matching objects. It defaults to
+	// the value of the first positive tag expression, e.g. "aerodrome" for
+	// aeroway=aerodrome.
+	Type string `json:"type,omitempty"`
+	// Rank replaces the place type weight of the ranking model.
+	Rank *float64 `json:"rank,omitempty"`
+	// Category is the POI category of matching objects, see
+	// mapping.Category.
+	Category string `json:"category,omitempty"`
+
+	exprs []tagExpr
+	types map[string]bool
+}
+
+type tagExpr struct {
+	key    string
+	values []string
+	negate bool
+}
+
+// TagFilters is an ordered list of rules, the first matching rule decides
+// what happens to an object.
+type TagFilters struct {
+	Filters []TagFilter `json:"filters"`
+}
+
+// DefaultTagFilters indexes named places, except for the large areas that
+// are covered by Who's On First or make no sense as a point.
+func DefaultTagFilters() *TagFilters {
+	return &TagFilters{
+		Filters: []TagFilter{
+			{
+				Tags: []string{
+					"place=country|state|region|province|district|county|subdistrict|continent|" +
+						"archipelago|islet|square|locality|polder|sea|ocean",
+				},
+				Exclude: true,
+			},
+			{
+				Tags: []string{"place"},
+			},
+		},
+	}
+}
+
+// LoadTagFilters reads the tag filter config if one is set, otherwise the
+// default filters are used.
+func LoadTagFilters() *TagFilters {
+	filters := DefaultTagFilters()
+
+	// Natural feature rules go before the built-in rules, which exclude
+	// seas and islets, but after the rules of a tag filter file
+	if config.EnableNatural && config.TagFilters == "" {
+		filters.Filters = append(naturalFilters(), filters.Filters...)
+	}
+	// The same goes for admin relations, the built-in rules exclude
+	// place=state and the like
+	if config.EnableAdminRelations && config.TagFilters == "" {
+		filters.Filters = append(adminFilters(), filters.Filters...)
+	}
+
+	if config.TagFilters != "" {
+		data, err := os.ReadFile(config.TagFilters)
+		if err != nil {
+			log.Fatalf("[FILTER] Failed to read tag filters: %v", err)
+		}
+
+		filters = &TagFilters{}
+		if err := json.Unmarshal(data, filters); err != nil {
+			log.Fatalf("[FILTER] Failed to parse tag filters: %v", err)
+		}
+		if config.EnableNatural {
+			filters.Filters = append(filters.Filters, naturalFilters()...)
+		}
+		if config.EnableAdminRelations {
+			filters.Filters = append(filters.Filters, adminFilters()...)
+		}
+	}
+
+	// POI rules go last, so the configured rules decide first
+	if config.EnablePOIs {
+		filters.Filters = append(filters.Filters, categoryFilters(LoadCategories())...)
+	}
+
+	if err := filters.compile(); err != nil {
+		log.Fatalf("[FILTER] Invalid tag filters: %v", err)
+	}
+
+	return filters
+}
+
+func (f *TagFilters) compile() error {
+	for i := range f.Filters {
+		filter := &f.Filters[i]
+
+		if len(filter.Tags) == 0 {
+			return fmt.Errorf("filter %d: no tag expressions", i)
+		}
+
+		filter.exprs = make([]tagExpr, 0, len(filter.Tags))
+		for _, tag := range filter.Tags {
+			expr, ok := parseTagExpr(tag)
+			if !ok {
+				return fmt.Errorf("filter %d: invalid tag expression %q", i, tag)
+			}
+			filter.exprs = append(filter.exprs, expr)
+		}
+
+		filter.types = make(map[string]bool, len(filter.Types))
+		for _, osmType := range filter.Types {
+			if mapping.GetOSMTypeNumber(osmType) == 0 {
+				return fmt.Errorf("filter %d: unknown type %q", i, osmType)
+			}
+			filter.types[osmType] = true
+		}
+
+		if filter.Layer != "" && mapping.AddLayer(filter.Layer) == 0 {
+			return fmt.Errorf("filter %d: too many layers", i)
+		}
+		if filter.Type != "" && mapping.AddPlaceType(filter.Type) == 0 {
+			return fmt.Errorf("filter %d: too many place types", i)
+		}
+		// Without a type the listed values of the tag are the place types,
+		// free-form values are only kept if they are built in
+		if filter.Type == "" && !filter.Exclude {
+			for _, placeType := range filter.placeTypeValues() {
+				if mapping.AddPlaceType(placeType) == 0 {
+					return fmt.Errorf("filter %d: too many place types", i)
+				}
+			}
+		}
+		if filter.Category != "" && mapping.AddCategory(filter.Category) == 0 {
+			return fmt.Errorf("filter %d: too many categories", i)
+		}
+	}
+
+	return nil
+}
+
+func parseTagExpr(s string) (tagExpr, bool) {
+	s = strings.TrimSpace(s)
+
+	var expr tagExpr
+	if key, values, ok := strings.Cut(s, "!="); ok {
+		expr = tagExpr{key: key, values: strings.Split(values, "|"), negate: true}
+	} else if key, values, ok := strings.Cut(s, "="); ok {
+		expr = tagExpr{key: key}
+		if values != "*" {
+			expr.values = strings.Split(values, "|")
+		}
+	} else if strings.HasPrefix(s, "!") {
+		expr = tagExpr{key: s[1:], negate: true}
+	} else {
+		expr = tagExpr{key: s}
+	}
+
+	expr.key = strings.TrimSpace(expr.key)
+	return expr, expr.key != ""
+}
+
+func (e tagExpr) match(tags osm.Tags) bool {
+	value := tags.Find(e.key)
+	found := value != "" || tags.HasTag(e.key)
+
+	matched := found
+	if found && e.values != nil {
+		matched = false
+		for _, v := range e.values {
+			if v == value {
+				matched = true
+				break
+			}
+		}
+	}
+
+	return matched != e.negate
+}
+
+// Match returns the rule that decides about the object, or nil if no rule
+// matches. Objects without a name never match.
+func (f *TagFilters) Match(osmType string, tags osm.Tags) *TagFilter {
+	if !tags.HasTag("name") {
+		return nil
+	}
+
+	for i := range f.Filters {
+		filter := &f.Filters[i]
+		if len(filter.types) > 0 && !filter.types[osmType] {
+			continue
+		}
+
+		matched := true
+		for _, expr := range filter.exprs {
+			if !expr.match(tags) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return filter
+		}
+	}
+
+	return nil
+}
+
+// Includes reports whether an object is indexed.
+func (f *TagFilters) Includes(osmType string, tags osm.Tags) bool {
+	filter := f.Match(osmType, tags)
+	return filter != nil && !filter.Exclude
+}
+
+// HasType reports whether any including rule applies to the OSM type.
+func (f *TagFilters) HasType(osmType string) bool {
+	for _, filter := range f.Filters {
+		if !filter.Exclude && (len(filter.types) == 0 || filter.types[osmType]) {
+			return true
+		}
+	}
+	return false
+}
+
+// PlaceType returns the place type stored for an object matched by the rule.
+func (t *TagFilter) PlaceType(tags osm.Tags) string {
+	if t.Type != "" {
+		return t.Type
+	}
+	for _, expr := range t.exprs {
+		if !expr.negate {
+			return tags.Find(expr.key)
+		}
+	}
+	return ""
+}
+
+// placeTypeValues returns the values listed by the expression PlaceType
+// takes the place type from, nil if it matches any value.
+func (t *TagFilter) placeTypeValues() []string {
+	for _, expr := range t.exprs {
+		if !expr.negate {
+			return expr.values
+		}
+	}
+	return nil
+}
+
+// LayerName returns the layer of an object matched by the rule.
+func (t *TagFilter) LayerName(placeType string) string {
+	if t.Layer != "" {
+		return t.Layer
+	}
+	return mapping.PlaceLayer[placeType]
+}
@@ -0,0 +1,122 @@
+package generate
+
+import (
+	"hstin/gocoder/config"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestLoadTagFiltersAdminRelations(t *testing.T) {
+	defer func(enabled bool) { config.EnableAdminRelations = enabled }(config.EnableAdminRelations)
+
+	tests := []struct {
+		enabled bool
+		want    bool
+	}{
+		// The default leaves out the relation passes
+		{false, false},
+		{true, true},
+	}
+	for _, test := range tests {
+		config.EnableAdminRelations = test.enabled
+		found := false
+		for _, filter := range LoadTagFilters().Filters {
+			if filter.Layer == AdminLayer {
+				found = true
+			}
+		}
+		if found != test.want {
+			t.Errorf("ENABLE_ADMIN_RELATIONS=%v: admin filters %v, want %v", test.enabled, found, test.want)
+		}
+	}
+}
+
+// tags returns OSM tags from key=value pairs.
+func tags(pairs ...string) osm.Tags {
+	out := make(osm.Tags, 0, len(pairs))
+	for _, pair := range pairs {
+		key, value, _ := strings.Cut(pair, "=")
+		out = append(out, osm.Tag{Key: key, Value: value})
+	}
+	return out
+}
+
+func TestTagFilters(t *testing.T) {
+	rank := 650.0
+	filters := &TagFilters{Filters: []TagFilter{
+		{Tags: []string{"aeroway=aerodrome", "!military"}, Types: []string{"node", "way"}, Layer: "poi", Rank: &rank},
+		{Tags: []string{"railway=station|halt"}, Layer: "poi"},
+		{Tags: []string{"natural=peak", "disused!=yes"}, Type: "mountain"},
+		{Tags: []string{"place=islet"}, Exclude: true},
+		{Tags: []string{"place"}},
+	}}
+	if err := filters.compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		osmType   string
+		tags      osm.Tags
+		rule      int
+		placeType string
+		layer     string
+	}{
+		{"aerodrome", "way", tags("name=Tegel", "aeroway=aerodrome"), 0, "aerodrome", "poi"},
+		{"military aerodrome", "way", tags("name=Wunstorf", "aeroway=aerodrome", "military=airfield"), -1, "", ""},
+		{"aerodrome relation", "relation", tags("name=Tegel", "aeroway=aerodrome"), -1, "", ""},
+		{"halt", "node", tags("name=Ostkreuz", "railway=halt"), 1, "halt", "poi"},
+		{"peak", "node", tags("name=Zugspitze", "natural=peak"), 2, "mountain", ""},
+		{"disused peak", "node", tags("name=Zugspitze", "natural=peak", "disused=yes"), -1, "", ""},
+		{"excluded", "way", tags("name=Scharfenberg", "place=islet"), 3, "", ""},
+		{"place", "relation", tags("name=Mitte", "place=suburb"), 4, "suburb", "neighbourhood"},
+		// Objects without a name are never indexed
+		{"no name", "node", tags("place=city"), -1, "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter := filters.Match(test.osmType, test.tags)
+			if test.rule < 0 {
+				if filter != nil {
+					t.Fatalf("matched %v, want none", filter.Tags)
+				}
+				return
+			}
+			if filter != &filters.Filters[test.rule] {
+				t.Fatalf("matched %v, want rule %d", filter, test.rule)
+			}
+			if filter.Exclude {
+				if filters.Includes(test.osmType, test.tags) {
+					t.Error("excluded object included")
+				}
+				return
+			}
+			placeType := filter.PlaceType(test.tags)
+			if placeType != test.placeType {
+				t.Errorf("place type %q, want %q", placeType, test.placeType)
+			}
+			if layer := filter.LayerName(placeType); layer != test.layer {
+				t.Errorf("layer %q, want %q", layer, test.layer)
+			}
+		})
+	}
+}
+
+func TestTagFiltersInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter TagFilter
+	}{
+		{"no tags", TagFilter{}},
+		{"empty key", TagFilter{Tags: []string{"=station"}}},
+		{"unknown type", TagFilter{Tags: []string{"place"}, Types: []string{"area"}}},
+	}
+	for _, test := range tests {
+		filters := &TagFilters{Filters: []TagFilter{test.filter}}
+		if err := filters.compile(); err == nil {
+			t.Errorf("%s: compiled", test.name)
+		}
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"hstin/gocoder/mapping"
 	"hstin/gocoder/structures"
 	"log"
+	"math"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -16,6 +18,7 @@ import (
 	"hstin/gocoder/config"
 	"hstin/gocoder/utils"
 
+	"github.com/paulmach/orb"
 	"github.com/paulmach/osm"
 	"github.com/paulmach/osm/osmpbf"
 )
@@ -23,12 +26,12 @@ import (
 var (
 	refBBoxMap map[int64][4]float32
 	adminTree  AdminTree
-	nodeChan   = make(chan *osm.Node)
+	objectChan = make(chan *osmObject)
 	insertChan = make(chan *InsertibleNode)
 	wg         sync.WaitGroup
 )
 
-func CreateScanner() *osmpbf.Scanner {
+func CreateScanner(filters *TagFilters) *osmpbf.Scanner {
 	f, err := os.Open(config.Planet)
 	if err != nil {
 		panic(err)
@@ -36,29 +39,13 @@ func CreateScanner() *osmpbf.Scanner {
 
 	scanner := osmpbf.New(context.Background(), f, runtime.GOMAXPROCS(-1))
 
+	// Ways and relations need their geometry, they are collected separately
+	// by CollectAreas.
 	scanner.SkipRelations = true
 	scanner.SkipWays = true
 
 	scanner.FilterNode = func(node *osm.Node) bool {
-		place := node.Tags.Find("place")
-		if place == "" {
-			return false
-		}
-
-		if !node.Tags.HasTag("name") {
-			return false
-		}
-
-		if place == "country" || place == "state" || place == "region" ||
-			place == "province" || place == "district" || place == "county" ||
-			place == "subdistrict" || place == "continent" ||
-			place == "archipelago" || place == "islet" || place == "square" ||
-			place == "locality" || place == "polder" || place == "sea" ||
-			place == "ocean" {
-			return false
-		}
-
-		return true
+		return filters.Includes("node", node.Tags)
 	}
 
 	return scanner
@@ -67,6 +54,12 @@ func CreateScanner() *osmpbf.Scanner {
 type InsertibleNode struct {
 	AlternamteNames []string
 	Node            structures.TmpNode
+	// Aliases are additional document keys that resolve to the node, e.g.
+	// of areas merged into their label node.
+	Aliases []int64
+	// Geometry is the polygon of natural features, stored in the natural
+	// index.
+	Geometry orb.MultiPolygon
 }
 
 func GenerateDatabase() {
@@ -86,11 +79,22 @@ func GenerateDatabase() {
 		return
 	}
 
+	// Addresses belong to their addr:street, which is only known with the
+	// street index
+	if config.EnableAddresses && !config.EnableStreets {
+		log.Fatal("ENABLE_ADDRESSES needs ENABLE_STREETS, addresses are attached to their street")
+		return
+	}
+
 	utils.LoadTimezones()
 	utils.LoadImportanceMap()
+	utils.LoadRankingModel()
 	log.Println("[GENERATE] Using >", config.Planet, "< as input file.")
 	log.Println("[GENERATE] Who's on First database:", config.WhosOnFirst)
 	log.Println("[GENERATE] Wikimedia Importance:", config.WikimediaImportance)
+	if config.RankingConfig != "" {
+		log.Println("[GENERATE] Ranking config:", config.RankingConfig)
+	}
 
 	startTime := time.Now()
 
@@ -102,13 +106,26 @@ func GenerateDatabase() {
 		log.Fatal(err)
 	}
 
-	scanner := CreateScanner()
+	filters := LoadTagFilters()
+	areas, labels := CollectAreas(filters)
+	if config.EnableAdminRelations {
+		log.Println("[GENERATE] Admin relations merged into Who's On First areas:", adminTree.MergeRelations(areas))
+		log.Println("[GENERATE] Admin relation polygons:", adminTree.AddRelationPolygons(areas))
+	}
+	streetSegments := CollectStreets()
+	addresses, interpolations := CollectAddresses()
+	postcodeIndex := CollectPostcodes(adminTree, addresses)
+
+	scanner := CreateScanner(filters)
 	defer scanner.Close()
 
 	var nodes = make([]structures.Node, 0)
 	var documentMap = make(structures.DocumentMap)
 	trie := structures.NewTrie()
 	index := structures.NewIndex()
+	wikidataIndex := structures.NewWikidataIndex()
+	codeIndex := structures.NewCodeIndex()
+	naturalIndex := structures.NewNaturalIndex()
 
 	kdPoints := make([]*structures.Point, 0)
 
@@ -118,36 +135,77 @@ func GenerateDatabase() {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for node := range nodeChan {
+			for object := range objectChan {
+				lat, lon := float64(object.Center[0]), float64(object.Center[1])
+
 				tmpNode := structures.TmpNode{
-					ID:       int64(node.ID),
+					ID:       object.ID,
 					Names:    make(map[string]string),
-					Center:   [2]float32{float32(node.Lat), float32(node.Lon)},
-					Timezone: utils.GetTimezone(node.Lat, node.Lon),
+					Center:   object.Center,
+					Centroid: object.Centroid,
+					Timezone: utils.GetTimezone(lat, lon),
 				}
 
-				tags := node.Tags.Map()
+				tags := object.Tags.Map()
 
 				// POPULATION
 				tmpNode.Population = utils.ParseStringAsNumber(tags["population"])
 
-				// RANK
-				tmpNode.Rank = utils.CreateRank(tags, tmpNode.Population)
+				// LAYER
+				tmpNode.PlaceType = object.Filter.PlaceType(object.Tags)
+				tmpNode.Layer = object.Filter.LayerName(tmpNode.PlaceType)
+				tmpNode.Category = object.Filter.Category
+				tmpNode.Source = "osm"
+				tmpNode.OSMType = object.Type
+				tmpNode.Wikidata = tags["wikidata"]
+				tmpNode.Importance = utils.GetImportance(tags["wikidata"])
+				tmpNode.Codes = parseCodes(tags)
+				if elevation, ok := utils.ParseElevation(tags["ele"]); ok {
+					tmpNode.Elevation = elevation
+				}
+				for _, key := range []string{"is_in:city", "is_in:town", "is_in"} {
+					if tags[key] != "" {
+						tmpNode.IsIn = tags[key]
+						break
+					}
+				}
 
 				// ADMIN AREAS
-				adminArea := adminTree.GetCounty(node.Lat, node.Lon)
+				adminArea := adminTree.GetCounty(lat, lon)
 				tmpNode.Regions = adminArea.Regions
+				tmpNode.Hierarchy = adminArea.Hierarchy
 				tmpNode.Country = adminArea.Country
+				if tmpNode.Layer == AdminLayer {
+					// Admin relations only keep the levels above themselves
+					tmpNode.Hierarchy = parentLevels(adminArea.Hierarchy, tmpNode.PlaceType)
+					tmpNode.Regions = regionsFromHierarchy(tmpNode.Hierarchy)
+				}
+
+				// POSTCODE
+				postcode := tags["postal_code"]
+				if postcode == "" {
+					postcode = tags["addr:postcode"]
+				}
+				tmpNode.Postcode = findPostcode(postcodeIndex, tmpNode.Country, postcode, lat, lon)
+
+				// RANK
+				baseRank := utils.Ranking.BaseRank(tmpNode.PlaceType)
+				if object.Filter.Rank != nil {
+					baseRank = *object.Filter.Rank
+				}
+				tmpNode.Rank = utils.CreateRankWithBase(baseRank, tags, tmpNode.Population, tmpNode.Country)
 
 				// BOUNDING BOX
-				if bbox, ok := refBBoxMap[int64(node.ID)]; ok {
+				if object.HasBoundingBox {
+					tmpNode.BoundingBox = object.BoundingBox
+				} else if bbox, ok := refBBoxMap[object.ID]; ok {
 					tmpNode.BoundingBox = bbox
 				} else {
 					tmpNode.BoundingBox = [4]float32{
-						float32(node.Lat),
-						float32(node.Lon),
-						float32(node.Lat),
-						float32(node.Lon),
+						float32(lat),
+						float32(lon),
+						float32(lat),
+						float32(lon),
 					}
 				}
 
@@ -175,6 +233,8 @@ func GenerateDatabase() {
 				insertChan <- &InsertibleNode{
 					Node:            tmpNode,
 					AlternamteNames: alternateNames,
+					Aliases:         object.Aliases,
+					Geometry:        object.Geometry,
 				}
 			}
 		}()
@@ -182,98 +242,230 @@ func GenerateDatabase() {
 
 	var nodeWithoutCountry = 0
 	var insertedIntoTrie = 0
+	var withImportance = 0
+	var unknownPlaceTypes = 0
+	insertDone := make(chan struct{})
 
-	go func() {
-		var documentID int64 = 0
+	var documentID int64 = 0
 
-		for insertibleNode := range insertChan {
-			cityNode := insertibleNode.Node
+	// Streets and places by their normalized default name, used to attach
+	// addresses to them.
+	streetNames := make(map[string][]int64)
+	placeNames := make(map[string][]int64)
 
-			nameArray := []string{cityNode.Names["name"]}
-			regionArray := []string{
-				cityNode.Regions["name"].Region,
-				cityNode.Regions["name"].SubRegion,
-			}
+	// Names of the settlements the sub-settlements may belong to
+	subSettlements := make(map[int64][]string)
+
+	// insert adds a document to all data structures and returns its id. It
+	// is only called from one goroutine at a time.
+	insert := func(insertibleNode *InsertibleNode) int64 {
+		cityNode := insertibleNode.Node
+
+		nameArray := []string{cityNode.Names["name"]}
+		regionArray := []string{
+			cityNode.Regions["name"].Region,
+			cityNode.Regions["name"].SubRegion,
+		}
 
+		for _, lang := range config.Languages {
+			nameArray = append(nameArray, cityNode.Names[lang])
+			regionArray = append(regionArray, cityNode.Regions[lang].Region)
+			regionArray = append(
+				regionArray,
+				cityNode.Regions[lang].SubRegion,
+			)
+		}
+
+		nameOffset, err := stringStore.Store(nameArray)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		regionOffset, err := stringStore.Store(regionArray)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Every hierarchy level is stored as placetype, id, default
+		// name and one name per language.
+		hierarchyArray := make([]string, 0, len(cityNode.Hierarchy)*(len(config.Languages)+3))
+		for _, level := range cityNode.Hierarchy {
+			hierarchyArray = append(
+				hierarchyArray,
+				level.Placetype,
+				strconv.FormatInt(level.ID, 10),
+				level.Names["name"],
+			)
 			for _, lang := range config.Languages {
-				nameArray = append(nameArray, cityNode.Names[lang])
-				regionArray = append(regionArray, cityNode.Regions[lang].Region)
-				regionArray = append(
-					regionArray,
-					cityNode.Regions[lang].SubRegion,
-				)
+				hierarchyArray = append(hierarchyArray, level.Names[lang])
 			}
+		}
 
-			nameOffset, err := stringStore.Store(nameArray)
-			if err != nil {
-				log.Fatal(err)
-			}
+		hierarchyOffset, err := stringStore.Store(hierarchyArray)
+		if err != nil {
+			log.Fatal(err)
+		}
 
-			regionOffset, err := stringStore.Store(regionArray)
-			if err != nil {
-				log.Fatal(err)
-			}
+		// Only built-in place types and the ones of the tag filters are
+		// stored, free-form tag values would fill up the list
+		placeType := mapping.GetPlaceTypeNumber(cityNode.PlaceType)
+		if placeType == 0 && cityNode.PlaceType != "" {
+			unknownPlaceTypes++
+		}
 
-			tzIndex := 0
-			for i, tz := range utils.TimezoneNames {
-				if tz == cityNode.Timezone {
-					tzIndex = i
-					break
-				}
+		tzIndex := 0
+		for i, tz := range utils.TimezoneNames {
+			if tz == cityNode.Timezone {
+				tzIndex = i
+				break
 			}
+		}
 
-			nodes = append(nodes, structures.Node{
-				ID:           cityNode.ID,
-				NameOffset:   uint64(nameOffset),
-				RegionOffset: uint64(regionOffset),
-				Population:   uint32(cityNode.Population),
-				Rank:         uint16(cityNode.Rank),
-				Timezone:     uint16(tzIndex),
-				Country:      uint8(mapping.GetCountryNumber(cityNode.Country)),
-				Center:       cityNode.Center,
-				BoundingBox:  cityNode.BoundingBox,
-			})
-
-			documentMap[cityNode.ID] = int32(documentID)
-
-			trie.Insert(documentID, cityNode.Names["name"])
-			index.AddDocument(documentID, cityNode.Names["name"])
-
-			for _, altName := range insertibleNode.AlternamteNames {
-				trie.Insert(documentID, altName)
-				index.AddDocument(documentID, altName)
-			}
+		nodes = append(nodes, structures.Node{
+			ID:              cityNode.ID,
+			NameOffset:      uint64(nameOffset),
+			RegionOffset:    uint64(regionOffset),
+			HierarchyOffset: uint64(hierarchyOffset),
+			Population:      uint32(cityNode.Population),
+			Rank:            uint16(cityNode.Rank),
+			Timezone:        uint16(tzIndex),
+			Country:         uint8(mapping.GetCountryNumber(cityNode.Country)),
+			Layer:           uint8(mapping.AddLayer(cityNode.Layer)),
+			PlaceType:       uint8(placeType),
+			OSMType:         uint8(mapping.GetOSMTypeNumber(cityNode.OSMType)),
+			Wikidata:        structures.ParseWikidataID(cityNode.Wikidata),
+			Importance:      float32(cityNode.Importance),
+			Parent:          uint32(cityNode.Parent),
+			Postcode:        uint32(cityNode.Postcode),
+			Category:        uint16(mapping.AddCategory(cityNode.Category)),
+			Elevation:       int16(max(math.MinInt16, min(math.MaxInt16, cityNode.Elevation))),
+			Center:          cityNode.Center,
+			Centroid:        cityNode.Centroid,
+			BoundingBox:     cityNode.BoundingBox,
+		})
+
+		// Who's On First IDs may collide with OSM node IDs, so only OSM
+		// nodes are reachable through the document map.
+		if cityNode.Source == "osm" {
+			documentMap[structures.DocumentKey(cityNode.OSMType, cityNode.ID)] = int32(documentID)
+		}
+		for _, alias := range insertibleNode.Aliases {
+			documentMap[alias] = int32(documentID)
+		}
+
+		wikidataIndex.Add(structures.ParseWikidataID(cityNode.Wikidata), documentID)
+		if len(insertibleNode.Geometry) > 0 {
+			naturalIndex.Add(structures.NaturalArea{DocID: documentID, Geometry: insertibleNode.Geometry})
+		}
+		for _, code := range cityNode.Codes {
+			code.DocID = documentID
+			codeIndex.Add(code)
+		}
+		if cityNode.Importance > 0 {
+			withImportance++
+		}
+
+		trie.Insert(documentID, cityNode.Names["name"])
+		index.AddDocument(documentID, cityNode.Names["name"])
+
+		for _, altName := range insertibleNode.AlternamteNames {
+			trie.Insert(documentID, altName)
+			index.AddDocument(documentID, altName)
+		}
 
-			insertedIntoTrie += len(insertibleNode.AlternamteNames) + 1
+		insertedIntoTrie += len(insertibleNode.AlternamteNames) + 1
 
+		// Admin areas and streets are resolved through their geometries when
+		// reverse geocoding, their point would only shadow nearby places.
+		if cityNode.Layer != "admin" && cityNode.Layer != "street" {
 			kdPoints = append(
 				kdPoints,
 				structures.NewPoint(documentID, cityNode.Center),
 			)
+		}
+
+		switch cityNode.Layer {
+		case "street":
+			key := normalizeName(cityNode.Names["name"])
+			streetNames[key] = append(streetNames[key], documentID)
+		case "locality", "neighbourhood":
+			key := normalizeName(cityNode.Names["name"])
+			placeNames[key] = append(placeNames[key], documentID)
+		}
+		if cityNode.Layer == "neighbourhood" {
+			subSettlements[documentID] = settlementCandidates(cityNode)
+		}
 
-			documentID++
+		if cityNode.Country == "" {
+			nodeWithoutCountry++
+		}
 
-			if cityNode.Country == "" {
-				nodeWithoutCountry++
-			}
+		documentID++
+		return documentID - 1
+	}
+
+	go func() {
+		defer close(insertDone)
+		for insertibleNode := range insertChan {
+			insert(insertibleNode)
 		}
 	}()
 
+	var mergedLabels = 0
+
+	for _, adminDocument := range adminTree.Documents() {
+		insertChan <- adminDocument
+	}
+
 	for scanner.Scan() {
 		switch o := scanner.Object().(type) {
 		case *osm.Node:
-			nodeChan <- o
+			object := &osmObject{
+				Type:   "node",
+				ID:     int64(o.ID),
+				Tags:   o.Tags,
+				Filter: filters.Match("node", o.Tags),
+				Center: [2]float32{float32(o.Lat), float32(o.Lon)},
+			}
+			if label, ok := labels[object.ID]; ok && object.mergeLabel(label) {
+				mergedLabels++
+			}
+			objectChan <- object
 		default:
 			break
 		}
 	}
 
-	close(nodeChan)
+	for _, area := range areas {
+		if !area.merged {
+			objectChan <- area
+		}
+	}
+	log.Println("[GENERATE] Areas merged into label nodes:", mergedLabels)
+
+	close(objectChan)
 	wg.Wait()
 	close(insertChan)
+	<-insertDone
+
+	linked := LinkSettlements(nodes, subSettlements, placeNames, stringStore, trie, index)
+	log.Printf("[GENERATE] Sub-settlements linked to a city or town: %d of %d", linked, len(subSettlements))
+
+	streetIndex := structures.NewStreetIndex()
+	streets := BuildStreets(streetSegments, nodes, insert, streetIndex)
+	log.Println("[GENERATE] Streets:", streets)
+
+	addressIndex, interpolationIndex := BuildAddresses(addresses, interpolations, nodes, streetNames, placeNames, streetIndex)
 
 	log.Println("[GENERATE] Inserted into trie:", insertedIntoTrie)
 	log.Println("[GENERATE] Nodes without country:", nodeWithoutCountry)
+	log.Println("[GENERATE] Nodes with importance:", withImportance)
+	log.Println("[GENERATE] Nodes with a place type that is not registered:", unknownPlaceTypes)
+	log.Println("[GENERATE] Transport codes:", len(codeIndex.Codes))
+	log.Println("[GENERATE] Natural feature polygons:", len(naturalIndex.Areas))
+	if withImportance == 0 {
+		log.Println("[GENERATE] Warning: no node matched an entry of the wikimedia importance file, check", config.WikimediaImportance)
+	}
 	log.Println(
 		"[GENERATE] Building KD tree for fast nearest neighbor search.",
 	)
@@ -289,6 +481,25 @@ func GenerateDatabase() {
 		trie,
 		index,
 		KDTree,
+		adminTree.Polygons,
+		adminTree.Countries(),
+		wikidataIndex,
+		streetIndex,
+		addressIndex,
+		interpolationIndex,
+		postcodeIndex,
+		codeIndex,
+		naturalIndex,
+		&structures.Metadata{
+			Languages:   config.Languages,
+			RankFormula: utils.RankFormula,
+			Ranking:     utils.Ranking,
+			Filters:     filters,
+			Layers:      mapping.Layers,
+			PlaceTypes:  mapping.PlaceTypes,
+			Categories:  mapping.Categories,
+			Taxonomy:    Taxonomy,
+		},
 		config.Output,
 	)
 	if err != nil {
@@ -306,6 +517,16 @@ func SaveSingleDatabase(
 	trie *structures.Trie,
 	index *structures.Index,
 	kdTree *structures.KDTree,
+	adminIndex *structures.AdminIndex,
+	countries *structures.CountryTable,
+	wikidataIndex *structures.WikidataIndex,
+	streetIndex *structures.StreetIndex,
+	addressIndex *structures.AddressIndex,
+	interpolationIndex *structures.InterpolationIndex,
+	postcodeIndex *structures.PostcodeIndex,
+	codeIndex *structures.CodeIndex,
+	naturalIndex *structures.NaturalIndex,
+	metadata *structures.Metadata,
 	filename string,
 ) error {
 	// Serialize all components
@@ -366,10 +587,86 @@ func SaveSingleDatabase(
 	}
 	KDTreeBytes := KDTreeBytesBuffer.Bytes()
 
-	// Calculate offsets - header is 10 uint64 values (80 bytes) + kdTreeSize (8 bytes)
+	// Serialize sections, these follow the KD tree
+	var AdminBytesBuffer bytes.Buffer
+	if err := adminIndex.Save(&AdminBytesBuffer); err != nil {
+		return err
+	}
+
+	var CountriesBytesBuffer bytes.Buffer
+	if err := countries.Save(&CountriesBytesBuffer); err != nil {
+		return err
+	}
+
+	var WikidataBytesBuffer bytes.Buffer
+	if err := wikidataIndex.Save(&WikidataBytesBuffer); err != nil {
+		return err
+	}
+
+	var MetadataBytesBuffer bytes.Buffer
+	if err := metadata.Save(&MetadataBytesBuffer); err != nil {
+		return err
+	}
+
+	var StreetBytesBuffer bytes.Buffer
+	if err := streetIndex.Save(&StreetBytesBuffer); err != nil {
+		return err
+	}
+
+	var AddressBytesBuffer bytes.Buffer
+	if err := addressIndex.Save(&AddressBytesBuffer); err != nil {
+		return err
+	}
+
+	var InterpolationBytesBuffer bytes.Buffer
+	if err := interpolationIndex.Save(&InterpolationBytesBuffer); err != nil {
+		return err
+	}
+
+	var PostcodeBytesBuffer bytes.Buffer
+	if err := postcodeIndex.Save(&PostcodeBytesBuffer); err != nil {
+		return err
+	}
+
+	var CodeBytesBuffer bytes.Buffer
+	if err := codeIndex.Save(&CodeBytesBuffer); err != nil {
+		return err
+	}
+
+	var NaturalBytesBuffer bytes.Buffer
+	if err := naturalIndex.Save(&NaturalBytesBuffer); err != nil {
+		return err
+	}
+
+	sectionNames := []string{
+		structures.SectionMetadata,
+		structures.SectionAdmin,
+		structures.SectionCountries,
+		structures.SectionWikidata,
+		structures.SectionStreets,
+		structures.SectionAddresses,
+		structures.SectionInterpolations,
+		structures.SectionPostcodes,
+		structures.SectionCodes,
+		structures.SectionNatural,
+	}
+	sectionData := [][]byte{
+		MetadataBytesBuffer.Bytes(),
+		AdminBytesBuffer.Bytes(),
+		CountriesBytesBuffer.Bytes(),
+		WikidataBytesBuffer.Bytes(),
+		StreetBytesBuffer.Bytes(),
+		AddressBytesBuffer.Bytes(),
+		InterpolationBytesBuffer.Bytes(),
+		PostcodeBytesBuffer.Bytes(),
+		CodeBytesBuffer.Bytes(),
+		NaturalBytesBuffer.Bytes(),
+	}
+
+	// Calculate offsets - format version (16 bytes), header is 10 uint64 values (80 bytes) + kdTreeSize (8 bytes)
 	headerSize := 8 * 10
 	kdTreeSizeFieldSize := 8
-	languagesOffset := headerSize + kdTreeSizeFieldSize
+	languagesOffset := structures.FormatHeaderSize + headerSize + kdTreeSizeFieldSize
 	nodesOffset := languagesOffset + len(serializedLanguages)
 	stringsOffset := nodesOffset + len(nodesBytes)
 	dmOffset := stringsOffset + len(stringsBytes)
@@ -384,6 +681,10 @@ func SaveSingleDatabase(
 	}
 	defer f.Close()
 
+	if err := structures.WriteFormatHeader(f); err != nil {
+		return err
+	}
+
 	// Write complete header with all offsets and sizes (10 uint64 values)
 	header := make([]byte, headerSize)
 	binary.LittleEndian.PutUint64(header[0:], uint64(nodesOffset))
@@ -428,6 +729,9 @@ func SaveSingleDatabase(
 	if _, err := f.Write(KDTreeBytes); err != nil {
 		return err
 	}
+	if err := structures.WriteSections(f, sectionNames, sectionData); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -0,0 +1,17 @@
+package generate
+
+// NaturalLayer is the layer of peaks, water bodies, islands and seas.
+const NaturalLayer = "natural"
+
+// naturalFilters are the tag filter rules of the natural features layer.
+// Place types default to the tag value, e.g. "peak" or "sea". Water areas
+// take their type from the water tag, named natural=water areas without one
+// are indexed as lakes.
+func naturalFilters() []TagFilter {
+	return []TagFilter{
+		{Tags: []string{"place=ocean|sea|archipelago|island|islet"}, Layer: NaturalLayer},
+		{Tags: []string{"natural=peak|volcano"}, Types: []string{"node"}, Layer: NaturalLayer},
+		{Tags: []string{"water=lake|reservoir|lagoon", "natural=water"}, Types: []string{"way", "relation"}, Layer: NaturalLayer},
+		{Tags: []string{"natural=water", "!water"}, Types: []string{"way", "relation"}, Layer: NaturalLayer, Type: "lake"},
+	}
+}
@@ -0,0 +1,55 @@
+package generate
+
+import (
+	"hstin/gocoder/config"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestNaturalFilters(t *testing.T) {
+	defer func(enabled bool) { config.EnableNatural = enabled }(config.EnableNatural)
+	config.EnableNatural = true
+	filters := LoadTagFilters()
+
+	tests := []struct {
+		name      string
+		osmType   string
+		tags      osm.Tags
+		placeType string
+	}{
+		// The built-in rules exclude seas and islets, the natural rules go first
+		{"sea", "relation", tags("name=North Sea", "place=sea"), "sea"},
+		{"islet", "way", tags("name=Scharfenberg", "place=islet"), "islet"},
+		{"peak", "node", tags("name=Zugspitze", "natural=peak", "ele=2962"), "peak"},
+		{"lake", "relation", tags("name=Bodensee", "natural=water", "water=lake"), "lake"},
+		{"reservoir", "way", tags("name=Edersee", "natural=water", "water=reservoir"), "reservoir"},
+		// Water areas without a water tag are lakes
+		{"water", "way", tags("name=Müggelsee", "natural=water"), "lake"},
+		{"river", "way", tags("name=Spree", "natural=water", "water=river"), ""},
+		{"peak way", "way", tags("name=Zugspitze", "natural=peak"), ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter := filters.Match(test.osmType, test.tags)
+			if test.placeType == "" {
+				if filter != nil && filter.Layer == NaturalLayer {
+					t.Fatalf("matched natural rule %v", filter.Tags)
+				}
+				return
+			}
+			if filter == nil || filter.Layer != NaturalLayer {
+				t.Fatalf("matched %v, want a natural rule", filter)
+			}
+			if placeType := filter.PlaceType(test.tags); placeType != test.placeType {
+				t.Errorf("place type %q, want %q", placeType, test.placeType)
+			}
+		})
+	}
+
+	// Without the natural layer seas are still excluded
+	config.EnableNatural = false
+	if LoadTagFilters().Includes("relation", tags("name=North Sea", "place=sea")) {
+		t.Error("sea included without ENABLE_NATURAL")
+	}
+}
@@ -0,0 +1,385 @@
+package generate
+
+import (
+	"context"
+	"database/sql"
+	"hstin/gocoder/config"
+	"hstin/gocoder/geo"
+	"hstin/gocoder/mapping"
+	"hstin/gocoder/structures"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/paulmach/orb"
+	geojson "github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/simplify"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+)
+
+// postcodeSimplifyTolerance is the Douglas-Peucker tolerance in degrees used
+// when persisting postcode polygons in the database.
+const postcodeSimplifyTolerance = 0.0005
+
+// Places outside of every postcode polygon get the postcode with the nearest
+// centre within this distance in metres.
+const placePostcodeRadius = 2000
+
+// CollectPostcodes builds the postcode index from boundary=postal_code ways
+// and relations, Who's On First postalcode records and the addr:postcode of
+// the addresses, in this order of precedence. The index is final, nodes can
+// reference postcodes by position.
+func CollectPostcodes(adminTree AdminTree, addresses []*addressObject) *structures.PostcodeIndex {
+	index := structures.NewPostcodeIndex()
+	if !config.EnablePostcodes {
+		return index
+	}
+
+	log.Printf("[POSTCODES] Collecting postcodes")
+	startTime := time.Now()
+
+	seen := make(map[string]bool)
+	counts := make(map[string]int)
+	add := func(postcode structures.Postcode) {
+		key := postcode.Country + "|" + mapping.PostcodeKey(postcode.Code)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		counts[postcode.Source]++
+		index.Add(postcode)
+	}
+
+	for _, postcode := range collectOSMPostcodes(adminTree) {
+		add(postcode)
+	}
+
+	for _, path := range []string{config.WhosOnFirst, config.WhosOnFirstPostcodes} {
+		if path == "" {
+			continue
+		}
+		for _, postcode := range loadWOFPostcodes(path) {
+			add(postcode)
+		}
+	}
+
+	for _, postcode := range addressPostcodes(adminTree, addresses) {
+		add(postcode)
+	}
+
+	index.Optimize()
+
+	log.Printf(
+		"[POSTCODES] Collected %d postcodes (%d from OSM boundaries, %d from Who's On First, %d from addresses) in %s",
+		len(index.Postcodes), counts["osm"], counts["wof"], counts["addresses"], time.Since(startTime),
+	)
+
+	return index
+}
+
+// newPostcode creates a postcode from its geometry. Polygons are simplified,
+// any other geometry is only used for the centre and bounding box.
+func newPostcode(code, country, source string, geometry orb.Geometry) structures.Postcode {
+	bound := geometry.Bound()
+	center := bound.Center()
+
+	var multiPolygon orb.MultiPolygon
+	switch g := geometry.(type) {
+	case orb.Polygon:
+		multiPolygon = orb.MultiPolygon{g.Clone()}
+	case orb.MultiPolygon:
+		multiPolygon = g.Clone()
+	}
+	if len(multiPolygon) > 0 {
+		center = geo.Centroid(multiPolygon)
+		multiPolygon = simplify.DouglasPeucker(postcodeSimplifyTolerance).MultiPolygon(multiPolygon)
+	}
+
+	return structures.Postcode{
+		Code:     mapping.NormalizePostcode(code),
+		Country:  country,
+		Source:   source,
+		Center:   [2]float32{float32(center[1]), float32(center[0])},
+		Geometry: multiPolygon,
+		BoundingBox: [4]float32{
+			float32(bound.Min[1]),
+			float32(bound.Min[0]),
+			float32(bound.Max[1]),
+			float32(bound.Max[0]),
+		},
+	}
+}
+
+// collectOSMPostcodes reads the boundary=postal_code ways and relations with
+// their geometry. The planet file is read three times, like in CollectAreas.
+func collectOSMPostcodes(adminTree AdminTree) []structures.Postcode {
+	f, err := os.Open(config.Planet)
+	if err != nil {
+		log.Fatalf("[POSTCODES] Failed to open planet file %s: %v", config.Planet, err)
+	}
+	defer f.Close()
+
+	newPlanetScanner := func() *osmpbf.Scanner {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			log.Fatalf("[POSTCODES] Failed to seek to start of planet file: %v", err)
+		}
+		return osmpbf.New(context.Background(), f, runtime.GOMAXPROCS(-1))
+	}
+
+	postcodeTag := func(tags osm.Tags) string {
+		if code := tags.Find("postal_code"); code != "" {
+			return code
+		}
+		return tags.Find("name")
+	}
+	isPostcode := func(tags osm.Tags) bool {
+		return tags.Find("boundary") == "postal_code" && postcodeTag(tags) != ""
+	}
+
+	type postcodeArea struct {
+		object  *osmObject
+		members []areaMember
+	}
+
+	areas := make([]postcodeArea, 0)
+	wayNodes := make(map[int64][]int64)
+	neededWays := make(map[int64]bool)
+
+	// 1) Postcode ways and relations
+	scanner := newPlanetScanner()
+	scanner.SkipNodes = true
+	scanner.FilterWay = func(way *osm.Way) bool {
+		return isPostcode(way.Tags)
+	}
+	scanner.FilterRelation = func(relation *osm.Relation) bool {
+		return isPostcode(relation.Tags)
+	}
+
+	for scanner.Scan() {
+		switch o := scanner.Object().(type) {
+		case *osm.Way:
+			object := &osmObject{Type: "way", ID: int64(o.ID), Tags: o.Tags}
+			wayNodes[object.ID] = wayRefs(o)
+			areas = append(areas, postcodeArea{object, []areaMember{{ID: object.ID, Role: "outer"}}})
+		case *osm.Relation:
+			object := &osmObject{Type: "relation", ID: int64(o.ID), Tags: o.Tags}
+			members := make([]areaMember, 0)
+			for _, member := range o.Members {
+				if member.Type == osm.TypeWay {
+					members = append(members, areaMember{ID: member.Ref, Role: member.Role})
+					neededWays[member.Ref] = true
+				}
+			}
+			areas = append(areas, postcodeArea{object, members})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("[POSTCODES] Scanner error during first pass: %v", err)
+	}
+	scanner.Close()
+
+	if len(areas) == 0 {
+		return nil
+	}
+
+	// 2) Member ways of relations
+	for wayID := range wayNodes {
+		delete(neededWays, wayID)
+	}
+	if len(neededWays) > 0 {
+		scanner = newPlanetScanner()
+		scanner.SkipNodes = true
+		scanner.SkipRelations = true
+		scanner.FilterWay = func(way *osm.Way) bool {
+			return neededWays[int64(way.ID)]
+		}
+
+		for scanner.Scan() {
+			if o, ok := scanner.Object().(*osm.Way); ok {
+				wayNodes[int64(o.ID)] = wayRefs(o)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Fatalf("[POSTCODES] Scanner error during second pass: %v", err)
+		}
+		scanner.Close()
+	}
+
+	// 3) Node coordinates
+	neededNodes := make(map[int64]bool)
+	for _, refs := range wayNodes {
+		for _, ref := range refs {
+			neededNodes[ref] = true
+		}
+	}
+
+	coordinates := make(map[int64]orb.Point, len(neededNodes))
+	scanner = newPlanetScanner()
+	scanner.SkipWays = true
+	scanner.SkipRelations = true
+	scanner.FilterNode = func(node *osm.Node) bool {
+		return neededNodes[int64(node.ID)]
+	}
+
+	for scanner.Scan() {
+		if o, ok := scanner.Object().(*osm.Node); ok {
+			coordinates[int64(o.ID)] = orb.Point{o.Lon, o.Lat}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("[POSTCODES] Scanner error during third pass: %v", err)
+	}
+	scanner.Close()
+
+	out := make([]structures.Postcode, 0, len(areas))
+	invalid := 0
+	for _, area := range areas {
+		multiPolygon, points := assembleArea(area.members, wayNodes, coordinates)
+		if len(points) == 0 {
+			continue
+		}
+
+		var geometry orb.Geometry = multiPolygon
+		if len(multiPolygon) == 0 {
+			geometry = points
+		}
+
+		center := geometry.Bound().Center()
+		code := postcodeTag(area.object.Tags)
+		country := adminTree.GetCountry(center[1], center[0])
+		if !mapping.ValidPostcode(country, code) {
+			invalid++
+			continue
+		}
+
+		postcode := newPostcode(code, country, "osm", geometry)
+		postcode.OSMType = uint8(mapping.GetOSMTypeNumber(area.object.Type))
+		postcode.ID = area.object.ID
+		out = append(out, postcode)
+	}
+
+	log.Printf("[POSTCODES] Found %d postcode boundaries, skipped %d with an invalid postcode", len(out), invalid)
+
+	return out
+}
+
+// loadWOFPostcodes reads the postalcode records of a Who's On First database.
+func loadWOFPostcodes(path string) []structures.Postcode {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		log.Fatalf("[POSTCODES] Failed to initialize Who's on First database: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT geojson.id,geojson.body FROM geojson JOIN spr ON geojson.id = spr.id WHERE spr.placetype = 'postalcode' GROUP BY geojson.id")
+	if err != nil {
+		log.Fatalf("[POSTCODES] Failed to query postalcodes: %v", err)
+	}
+	defer rows.Close()
+
+	out := make([]structures.Postcode, 0)
+	for rows.Next() {
+		var id int64
+		var body string
+		if err := rows.Scan(&id, &body); err != nil {
+			log.Fatalf("[POSTCODES] Failed to scan row: %v", err)
+		}
+
+		feature, err := geojson.UnmarshalFeature([]byte(body))
+		if err != nil || feature.Geometry == nil {
+			continue
+		}
+
+		code := feature.Properties.MustString("name", "")
+		country := feature.Properties.MustString("wof:country", "")
+		if code == "" || !mapping.ValidPostcode(country, code) {
+			continue
+		}
+
+		postcode := newPostcode(code, country, "wof", feature.Geometry)
+		postcode.ID = id
+
+		// Prefer the label position WOF computed for point records
+		if len(postcode.Geometry) == 0 {
+			if lat, ok := feature.Properties["lbl:latitude"].(float64); ok {
+				postcode.Center = [2]float32{float32(lat), float32(feature.Properties.MustFloat64("lbl:longitude", 0))}
+			}
+		}
+
+		out = append(out, postcode)
+	}
+
+	log.Printf("[POSTCODES] Loaded %d postalcodes from %s", len(out), path)
+
+	return out
+}
+
+// addressPostcodes groups the addresses by country and addr:postcode. Every
+// group becomes a postcode at the mean position of its addresses.
+func addressPostcodes(adminTree AdminTree, addresses []*addressObject) []structures.Postcode {
+	type cluster struct {
+		code    string
+		country string
+		sumLat  float64
+		sumLng  float64
+		count   int
+		bound   orb.Bound
+	}
+
+	clusters := make(map[string]*cluster)
+	keys := make([]string, 0)
+	for _, address := range addresses {
+		if address.Postcode == "" || (address.Lat == 0 && address.Lng == 0) {
+			continue
+		}
+
+		country := adminTree.GetCountry(address.Lat, address.Lng)
+		if !mapping.ValidPostcode(country, address.Postcode) {
+			continue
+		}
+
+		key := country + "|" + mapping.PostcodeKey(address.Postcode)
+		point := orb.Point{address.Lng, address.Lat}
+		c, ok := clusters[key]
+		if !ok {
+			c = &cluster{code: address.Postcode, country: country, bound: point.Bound()}
+			clusters[key] = c
+			keys = append(keys, key)
+		}
+		c.sumLat += address.Lat
+		c.sumLng += address.Lng
+		c.count++
+		c.bound = c.bound.Extend(point)
+	}
+
+	out := make([]structures.Postcode, 0, len(clusters))
+	for _, key := range keys {
+		c := clusters[key]
+		postcode := newPostcode(c.code, c.country, "addresses", c.bound)
+		postcode.Center = [2]float32{float32(c.sumLat / float64(c.count)), float32(c.sumLng / float64(c.count))}
+		out = append(out, postcode)
+	}
+
+	return out
+}
+
+// findPostcode returns the position + 1 of the postcode of a place, 0 if it
+// has none. A postcode tag of the place wins over the postcode area it lies
+// in.
+func findPostcode(index *structures.PostcodeIndex, country, code string, lat, lng float64) int64 {
+	if code != "" {
+		for _, i := range index.Lookup(code) {
+			if index.Postcodes[i].Country == country {
+				return int64(i) + 1
+			}
+		}
+	}
+
+	if i, _ := index.Find(lat, lng, placePostcodeRadius); i >= 0 {
+		return int64(i) + 1
+	}
+	return 0
+}
@@ -0,0 +1,110 @@
+package generate
+
+import (
+	"hstin/gocoder/structures"
+	"math"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// testCountryTree returns an admin tree that only knows the countries of the
+// boxes.
+func testCountryTree(countries map[string]orb.Polygon) AdminTree {
+	features := make([]*geojson.Feature, 0, len(countries))
+	for code, polygon := range countries {
+		feature := geojson.NewFeature(polygon)
+		feature.Properties["ISO3166-1:alpha2"] = code
+		features = append(features, feature)
+	}
+	return AdminTree{countryGrid: structures.NewUniformGridIndex(features, 2)}
+}
+
+func TestAddressPostcodes(t *testing.T) {
+	tree := testCountryTree(map[string]orb.Polygon{
+		"DE": testArea(0, "country", "Germany", 6, 47, 15, 55).Geometry.(orb.Polygon),
+		"GB": testArea(0, "country", "United Kingdom", -8, 49, 2, 59).Geometry.(orb.Polygon),
+	})
+
+	postcodes := addressPostcodes(tree, []*addressObject{
+		{Postcode: "10115", Lat: 52.53, Lng: 13.38},
+		{Postcode: "sw1a  1aa", Lat: 51.501, Lng: -0.141},
+		{Postcode: "10115", Lat: 52.54, Lng: 13.40},
+		// Postcodes in the wrong format and addresses without a position are
+		// skipped
+		{Postcode: "1011", Lat: 52.53, Lng: 13.38},
+		{Postcode: "10117"},
+		{Lat: 52.53, Lng: 13.38},
+		// Outside of every country any postcode with a digit is kept
+		{Postcode: "10117", Lat: 40.7, Lng: -74},
+	})
+
+	tests := []struct {
+		code    string
+		country string
+		lat     float64
+		lng     float64
+		bbox    [4]float64
+	}{
+		{"10115", "DE", 52.535, 13.39, [4]float64{52.53, 13.38, 52.54, 13.40}},
+		{"SW1A 1AA", "GB", 51.501, -0.141, [4]float64{51.501, -0.141, 51.501, -0.141}},
+		{"10117", "", 40.7, -74, [4]float64{40.7, -74, 40.7, -74}},
+	}
+	if len(postcodes) != len(tests) {
+		t.Fatalf("got %d postcodes %+v, want %d", len(postcodes), postcodes, len(tests))
+	}
+	for i, test := range tests {
+		got := postcodes[i]
+		if got.Code != test.code || got.Country != test.country || got.Source != "addresses" {
+			t.Errorf("%d: got %q %q from %q, want %q %q", i, got.Code, got.Country, got.Source, test.code, test.country)
+		}
+		if math.Abs(float64(got.Center[0])-test.lat) > 1e-5 || math.Abs(float64(got.Center[1])-test.lng) > 1e-5 {
+			t.Errorf("%s: centre %v, want %v, %v", test.code, got.Center, test.lat, test.lng)
+		}
+		for j := range test.bbox {
+			if math.Abs(float64(got.BoundingBox[j])-test.bbox[j]) > 1e-5 {
+				t.Errorf("%s: bounding box %v, want %v", test.code, got.BoundingBox, test.bbox)
+				break
+			}
+		}
+	}
+}
+
+func TestFindPostcode(t *testing.T) {
+	index := structures.NewPostcodeIndex()
+	index.Add(structures.Postcode{Code: "10117", Country: "DE", Center: [2]float32{52.515, 13.39}, Geometry: orb.MultiPolygon{testArea(0, "", "", 13.37, 52.5, 13.41, 52.53).Geometry.(orb.Polygon)}})
+	index.Add(structures.Postcode{Code: "10115", Country: "US", Center: [2]float32{40.75, -73.5}})
+	index.Add(structures.Postcode{Code: "10115", Country: "DE", Center: [2]float32{52.535, 13.39}})
+	index.Optimize()
+
+	tests := []struct {
+		name     string
+		country  string
+		code     string
+		lat, lng float64
+		want     string
+		wantIn   string
+	}{
+		// The postcode tag wins over the area the place is in
+		{"tag", "DE", "10115", 52.51, 13.39, "10115", "DE"},
+		{"tag of the country", "US", "10115", 40.75, -73.5, "10115", "US"},
+		{"area", "DE", "", 52.51, 13.39, "10117", "DE"},
+		{"unknown tag", "DE", "10999", 52.51, 13.39, "10117", "DE"},
+		{"nearest centre", "DE", "", 52.545, 13.39, "10115", "DE"},
+		{"none", "DE", "", 53.5, 10, "", ""},
+	}
+	for _, test := range tests {
+		position := findPostcode(index, test.country, test.code, test.lat, test.lng)
+		if test.want == "" {
+			if position != 0 {
+				t.Errorf("%s: got %q, want none", test.name, index.Get(int(position)-1).Code)
+			}
+			continue
+		}
+		got := index.Get(int(position) - 1)
+		if got == nil || got.Code != test.want || got.Country != test.wantIn {
+			t.Errorf("%s: got %+v, want %s in %s", test.name, got, test.want, test.wantIn)
+		}
+	}
+}
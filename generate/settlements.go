@@ -0,0 +1,128 @@
+package generate
+
+import (
+	"hstin/gocoder/geo"
+	"hstin/gocoder/mapping"
+	"hstin/gocoder/structures"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Sub-settlements are linked to a city or town within this distance in
+// metres.
+const parentSettlementRadius = 20000
+
+// parentPlaceTypes are the place types a sub-settlement can belong to.
+var parentPlaceTypes = []string{"city", "town"}
+
+// settlementCandidates returns the names of the settlement a sub-settlement
+// may belong to, most specific first: the is_in tags, then the Who's On
+// First locality and localadmin areas containing it.
+func settlementCandidates(node structures.TmpNode) []string {
+	candidates := make([]string, 0)
+	for _, name := range strings.FieldsFunc(node.IsIn, func(r rune) bool { return r == ',' || r == ';' }) {
+		if name = strings.TrimSpace(name); name != "" {
+			candidates = append(candidates, name)
+		}
+	}
+
+	for _, placetype := range []string{"locality", "localadmin"} {
+		for _, level := range node.Hierarchy {
+			if level.Placetype == placetype && level.Names["name"] != "" {
+				candidates = append(candidates, level.Names["name"])
+			}
+		}
+	}
+	return candidates
+}
+
+// LinkSettlements sets the parent of every sub-settlement to the city or
+// town it belongs to. The parent is the nearest city or town named by the
+// settlement candidates, otherwise the smallest one whose bounding box
+// contains the sub-settlement, otherwise the nearest one. Linked
+// sub-settlements are also searchable as "name city", e.g. "Schwabing
+// München". It has to run after all places are inserted.
+func LinkSettlements(
+	nodes []structures.Node,
+	candidates map[int64][]string,
+	placeNames map[string][]int64,
+	names *structures.Names,
+	trie *structures.Trie,
+	index *structures.Index,
+) int {
+	if len(candidates) == 0 {
+		return 0
+	}
+
+	parentTypes := make(map[uint8]bool, len(parentPlaceTypes))
+	for _, placeType := range parentPlaceTypes {
+		if number := mapping.GetPlaceTypeNumber(placeType); number != 0 {
+			parentTypes[uint8(number)] = true
+		}
+	}
+	isParent := func(node *structures.Node) bool {
+		return parentTypes[node.PlaceType]
+	}
+	settlements := newSettlementFinder(nodes, parentSettlementRadius, isParent)
+
+	// findNamed returns the nearest city or town with one of the names
+	findNamed := func(node *structures.Node, candidates []string) int64 {
+		lat, lng := float64(node.Center[0]), float64(node.Center[1])
+		for _, name := range candidates {
+			parent := int64(-1)
+			best := float64(parentSettlementRadius)
+			for _, docID := range placeNames[normalizeName(name)] {
+				if !isParent(&nodes[docID]) {
+					continue
+				}
+				center := nodes[docID].Center
+				if d := geo.Haversine(lat, lng, float64(center[0]), float64(center[1])); d <= best {
+					best = d
+					parent = docID
+				}
+			}
+			if parent >= 0 {
+				return parent
+			}
+		}
+		return -1
+	}
+
+	docIDs := make([]int64, 0, len(candidates))
+	for docID := range candidates {
+		docIDs = append(docIDs, docID)
+	}
+	sort.Slice(docIDs, func(i, j int) bool { return docIDs[i] < docIDs[j] })
+
+	linked := 0
+	for _, docID := range docIDs {
+		node := &nodes[docID]
+
+		parent := findNamed(node, candidates[docID])
+		if parent < 0 {
+			parent = settlements.Find(node.Center[0], node.Center[1])
+		}
+		if parent < 0 {
+			continue
+		}
+		node.Parent = uint32(parent + 1)
+		linked++
+
+		ownNames, err := names.Read(int64(node.NameOffset))
+		if err != nil {
+			log.Fatal(err)
+		}
+		parentNames, err := names.Read(int64(nodes[parent].NameOffset))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if ownNames[0] != "" && parentNames[0] != "" {
+			alias := ownNames[0] + " " + parentNames[0]
+			trie.Insert(docID, alias)
+			index.AddDocument(docID, alias)
+		}
+	}
+
+	return linked
+}
@@ -0,0 +1,119 @@
+package generate
+
+import (
+	"hstin/gocoder/mapping"
+	"hstin/gocoder/structures"
+	"reflect"
+	"testing"
+)
+
+func TestSettlementCandidates(t *testing.T) {
+	tests := []struct {
+		name string
+		node structures.TmpNode
+		want []string
+	}{
+		{"none", structures.TmpNode{}, []string{}},
+		{"is_in", structures.TmpNode{IsIn: "Garching; Freising, "}, []string{"Garching", "Freising"}},
+		{
+			// Localities come before localadmin areas, is_in before both
+			"hierarchy",
+			structures.TmpNode{IsIn: "München", Hierarchy: []structures.AdminLevel{
+				{Placetype: "localadmin", Names: map[string]string{"name": "Landeshauptstadt München"}},
+				{Placetype: "county", Names: map[string]string{"name": "Oberbayern"}},
+				{Placetype: "locality", Names: map[string]string{"name": "München"}},
+			}},
+			[]string{"München", "München", "Landeshauptstadt München"},
+		},
+	}
+	for _, test := range tests {
+		if got := settlementCandidates(test.node); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLinkSettlements(t *testing.T) {
+	names, err := structures.NewNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := func(name, placeType string, lat, lng float32, bbox [4]float32) structures.Node {
+		offset, err := names.Store([]string{name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return structures.Node{
+			NameOffset:  uint64(offset),
+			PlaceType:   uint8(mapping.GetPlaceTypeNumber(placeType)),
+			Center:      [2]float32{lat, lng},
+			BoundingBox: bbox,
+		}
+	}
+
+	nodes := []structures.Node{
+		node("München", "city", 48.137, 11.575, [4]float32{48.06, 11.36, 48.25, 11.72}),
+		node("Schwabing", "suburb", 48.160, 11.580, [4]float32{}),
+		node("Garching", "town", 48.249, 11.651, [4]float32{48.24, 11.63, 48.26, 11.67}),
+		node("Hochbrück", "suburb", 48.245, 11.630, [4]float32{}),
+		node("Neuhausen", "suburb", 48.150, 11.540, [4]float32{}),
+		node("Aubing", "suburb", 48.155, 11.410, [4]float32{}),
+		node("Hohenschäftlarn", "village", 47.980, 11.470, [4]float32{}),
+		node("Ebenhausen", "suburb", 47.960, 11.470, [4]float32{}),
+		node("Helgoland", "suburb", 54.180, 7.880, [4]float32{}),
+	}
+	placeNames := map[string][]int64{
+		"münchen":         {0},
+		"garching":        {2},
+		"hohenschäftlarn": {6},
+	}
+	candidates := map[int64][]string{
+		1: {"München"},
+		// is_in wins over the bounding box of München
+		3: {"Garching"},
+		// Without candidates the bounding box decides
+		4: {},
+		// Unknown names fall back to the bounding box
+		5: {"Pasing"},
+		// Villages aren't parents, the nearest city or town is too far
+		7: {"Hohenschäftlarn"},
+		8: {},
+	}
+	trie := structures.NewTrie()
+	index := structures.NewIndex()
+
+	linked := LinkSettlements(nodes, candidates, placeNames, names, trie, index)
+	if linked != 4 {
+		t.Errorf("linked %d, want 4", linked)
+	}
+
+	parents := []struct {
+		docID int64
+		want  uint32
+	}{
+		{1, 1},
+		{3, 3},
+		{4, 1},
+		{5, 1},
+		{7, 0},
+		{8, 0},
+		// Parents themselves aren't linked
+		{0, 0},
+	}
+	for _, test := range parents {
+		if got := nodes[test.docID].Parent; got != test.want {
+			t.Errorf("document %d: parent %d, want %d", test.docID, got, test.want)
+		}
+	}
+
+	// Linked sub-settlements are searchable with their parent
+	if got := trie.Search("schwabing münchen"); !reflect.DeepEqual(got, []int64{1}) {
+		t.Errorf("trie: got %v, want [1]", got)
+	}
+	if got := trie.Search("hochbrück garching"); !reflect.DeepEqual(got, []int64{3}) {
+		t.Errorf("trie: got %v, want [3]", got)
+	}
+	if got := trie.Search("ebenhausen"); len(got) != 0 {
+		t.Errorf("trie: got %v for an unlinked settlement", got)
+	}
+}
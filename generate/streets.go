@@ -0,0 +1,384 @@
+package generate
+
+import (
+	"context"
+	"hstin/gocoder/config"
+	"hstin/gocoder/mapping"
+	"hstin/gocoder/structures"
+	"hstin/gocoder/utils"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+)
+
+// Segments of a street within this distance (in degrees, roughly 200m) are
+// merged even if they don't share a node.
+const streetMergeDistance = 0.002
+
+// Streets are attached to the nearest settlement within this distance in
+// metres if no settlement bounding box contains them.
+const streetSettlementRadius = 20000
+
+// streetSegment is one named highway way.
+type streetSegment struct {
+	ID   int64
+	Tags osm.Tags
+	Refs []int64
+	Line orb.LineString
+}
+
+// CollectStreets reads the named highway ways and their geometry. The planet
+// file is read twice, once for the ways and once for the node coordinates.
+func CollectStreets() []*streetSegment {
+	if !config.EnableStreets {
+		return nil
+	}
+
+	log.Printf("[STREETS] Collecting streets")
+	startTime := time.Now()
+
+	f, err := os.Open(config.Planet)
+	if err != nil {
+		log.Fatalf("[STREETS] Failed to open planet file %s: %v", config.Planet, err)
+	}
+	defer f.Close()
+
+	// 1) Named highways
+	scanner := osmpbf.New(context.Background(), f, runtime.GOMAXPROCS(-1))
+	scanner.SkipNodes = true
+	scanner.SkipRelations = true
+	scanner.FilterWay = func(way *osm.Way) bool {
+		return mapping.StreetHighways[way.Tags.Find("highway")] && way.Tags.HasTag("name")
+	}
+
+	segments := make([]*streetSegment, 0)
+	neededNodes := make(map[int64]bool)
+	for scanner.Scan() {
+		if o, ok := scanner.Object().(*osm.Way); ok {
+			refs := wayRefs(o)
+			for _, ref := range refs {
+				neededNodes[ref] = true
+			}
+			segments = append(segments, &streetSegment{
+				ID:   int64(o.ID),
+				Tags: o.Tags,
+				Refs: refs,
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("[STREETS] Scanner error during first pass: %v", err)
+	}
+	scanner.Close()
+	log.Printf("[STREETS] Found %d street segments", len(segments))
+
+	// 2) Node coordinates
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		log.Fatalf("[STREETS] Failed to seek to start of planet file: %v", err)
+	}
+	scanner = osmpbf.New(context.Background(), f, runtime.GOMAXPROCS(-1))
+	scanner.SkipWays = true
+	scanner.SkipRelations = true
+	scanner.FilterNode = func(node *osm.Node) bool {
+		return neededNodes[int64(node.ID)]
+	}
+
+	coordinates := make(map[int64]orb.Point, len(neededNodes))
+	for scanner.Scan() {
+		if o, ok := scanner.Object().(*osm.Node); ok {
+			coordinates[int64(o.ID)] = orb.Point{o.Lon, o.Lat}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("[STREETS] Scanner error during second pass: %v", err)
+	}
+	scanner.Close()
+
+	out := make([]*streetSegment, 0, len(segments))
+	for _, segment := range segments {
+		line := make(orb.LineString, 0, len(segment.Refs))
+		for _, ref := range segment.Refs {
+			if point, ok := coordinates[ref]; ok {
+				line = append(line, point)
+			}
+		}
+		if len(line) < 2 {
+			continue
+		}
+		segment.Line = line
+		out = append(out, segment)
+	}
+
+	log.Printf("[STREETS] Collected %d street segments in %s", len(out), time.Since(startTime))
+
+	return out
+}
+
+// settlementFinder finds the settlement a coordinate belongs to.
+type settlementFinder struct {
+	nodes  []structures.Node
+	tree   *structures.KDTree
+	radius float64
+}
+
+// newSettlementFinder indexes the nodes accepted by include as settlements.
+// Coordinates are attached to settlements within radius metres.
+func newSettlementFinder(nodes []structures.Node, radius float64, include func(node *structures.Node) bool) *settlementFinder {
+	points := make([]*structures.Point, 0)
+	for docID := range nodes {
+		if include(&nodes[docID]) {
+			points = append(points, structures.NewPoint(int64(docID), nodes[docID].Center))
+		}
+	}
+
+	return &settlementFinder{
+		nodes:  nodes,
+		tree:   structures.New(points),
+		radius: radius,
+	}
+}
+
+// Find returns the document id of the settlement whose bounding box contains
+// the coordinate, preferring the smallest one, or of the nearest settlement.
+// It returns -1 if there is no settlement nearby.
+func (s *settlementFinder) Find(lat, lng float32) int64 {
+	neighbors := s.tree.Nearest(
+		structures.NewPoint(0, [2]float32{lat, lng}),
+		8,
+		s.radius,
+		nil,
+	)
+	if len(neighbors) == 0 {
+		return -1
+	}
+
+	best := int64(-1)
+	bestArea := float32(0)
+	for _, neighbor := range neighbors {
+		bbox := s.nodes[neighbor.ID].BoundingBox
+		if lat < bbox[0] || lat > bbox[2] || lng < bbox[1] || lng > bbox[3] {
+			continue
+		}
+		area := (bbox[2] - bbox[0]) * (bbox[3] - bbox[1])
+		if area > 0 && (best < 0 || area < bestArea) {
+			best, bestArea = neighbor.ID, area
+		}
+	}
+	if best >= 0 {
+		return best
+	}
+
+	return neighbors[0].ID
+}
+
+// BuildStreets merges the segments of each street within a settlement into
+// one document, inserts it and records its geometry in the street index.
+// It has to run after all places are inserted, since streets reference their
+// settlement by document id.
+func BuildStreets(
+	segments []*streetSegment,
+	nodes []structures.Node,
+	insert func(*InsertibleNode) int64,
+	streetIndex *structures.StreetIndex,
+) int {
+	if len(segments) == 0 {
+		return 0
+	}
+
+	locality := uint8(mapping.GetLayerNumber("locality"))
+	settlements := newSettlementFinder(nodes, streetSettlementRadius, func(node *structures.Node) bool {
+		return node.Layer == locality
+	})
+
+	// Group the segments by name and settlement
+	groups := make(map[string][]*streetSegment)
+	parents := make(map[*streetSegment]int64, len(segments))
+	for _, segment := range segments {
+		middle := segment.Line[len(segment.Line)/2]
+		parent := settlements.Find(float32(middle[1]), float32(middle[0]))
+		parents[segment] = parent
+
+		key := normalizeName(segment.Tags.Find("name")) + "|" + strconv.FormatInt(parent, 10)
+		groups[key] = append(groups[key], segment)
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	streets := 0
+	for _, key := range keys {
+		for _, cluster := range clusterSegments(groups[key]) {
+			street := newStreet(cluster, parents[cluster[0]])
+			docID := insert(street)
+
+			geometry := make(orb.MultiLineString, 0, len(cluster))
+			for _, segment := range cluster {
+				geometry = append(geometry, segment.Line)
+			}
+			streetIndex.Add(structures.StreetGeometry{
+				DocID:    docID,
+				Geometry: geometry,
+			})
+			streets++
+		}
+	}
+
+	return streets
+}
+
+// clusterSegments splits segments with the same name into connected streets.
+// Segments belong together if they touch or lie close to each other.
+func clusterSegments(segments []*streetSegment) [][]*streetSegment {
+	parent := make([]int, len(segments))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	bounds := make([]orb.Bound, len(segments))
+	for i, segment := range segments {
+		bounds[i] = segment.Line.Bound().Pad(streetMergeDistance / 2)
+	}
+
+	// Sweep over the segments ordered by their west edge. Segments sharing
+	// a node always have intersecting bounds.
+	order := make([]int, len(segments))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return bounds[order[a]].Min[0] < bounds[order[b]].Min[0] })
+
+	for a, i := range order {
+		for _, j := range order[a+1:] {
+			if bounds[j].Min[0] > bounds[i].Max[0] {
+				break
+			}
+			if find(i) != find(j) && bounds[i].Intersects(bounds[j]) {
+				parent[find(i)] = find(j)
+			}
+		}
+	}
+
+	clusters := make(map[int][]*streetSegment)
+	roots := make([]int, 0)
+	for i, segment := range segments {
+		root := find(i)
+		if _, ok := clusters[root]; !ok {
+			roots = append(roots, root)
+		}
+		clusters[root] = append(clusters[root], segment)
+	}
+
+	out := make([][]*streetSegment, 0, len(clusters))
+	for _, root := range roots {
+		out = append(out, clusters[root])
+	}
+	return out
+}
+
+// newStreet creates the document of a merged street. Its position is the
+// vertex closest to the centre of the bounding box, so it lies on the street.
+func newStreet(cluster []*streetSegment, parent int64) *InsertibleNode {
+	sort.Slice(cluster, func(i, j int) bool { return cluster[i].ID < cluster[j].ID })
+
+	bound := cluster[0].Line.Bound()
+	for _, segment := range cluster[1:] {
+		bound = bound.Union(segment.Line.Bound())
+	}
+
+	centre := bound.Center()
+	position := cluster[0].Line[0]
+	bestDistance := -1.0
+	for _, segment := range cluster {
+		for _, point := range segment.Line {
+			dx, dy := point[0]-centre[0], point[1]-centre[1]
+			if d := dx*dx + dy*dy; bestDistance < 0 || d < bestDistance {
+				bestDistance = d
+				position = point
+			}
+		}
+	}
+
+	lat, lng := position[1], position[0]
+	tags := cluster[0].Tags.Map()
+
+	tmpNode := structures.TmpNode{
+		ID:       cluster[0].ID,
+		Names:    make(map[string]string),
+		Center:   [2]float32{float32(lat), float32(lng)},
+		Timezone: utils.GetTimezone(lat, lng),
+		BoundingBox: [4]float32{
+			float32(bound.Min[1]),
+			float32(bound.Min[0]),
+			float32(bound.Max[1]),
+			float32(bound.Max[0]),
+		},
+		Layer:     "street",
+		PlaceType: "street",
+		Source:    "osm",
+		OSMType:   "way",
+		Wikidata:  tags["wikidata"],
+		Parent:    parent + 1,
+	}
+	tmpNode.Importance = utils.GetImportance(tmpNode.Wikidata)
+
+	adminArea := adminTree.GetCounty(lat, lng)
+	tmpNode.Regions = adminArea.Regions
+	tmpNode.Hierarchy = adminArea.Hierarchy
+	tmpNode.Country = adminArea.Country
+
+	tmpNode.Rank = utils.CreateRank(map[string]string{
+		"place":    "street",
+		"wikidata": tmpNode.Wikidata,
+	}, 0, tmpNode.Country)
+
+	name := tags["name"]
+	tmpNode.Names["name"] = name
+	for _, lang := range config.Languages {
+		if langName, ok := tags["name:"+lang]; ok {
+			tmpNode.Names[lang] = langName
+			continue
+		}
+		tmpNode.Names[lang] = name
+	}
+
+	// Alternate names of all segments, the other ways resolve to the street
+	seen := map[string]bool{name: true}
+	alternateNames := make([]string, 0)
+	aliases := make([]int64, 0, len(cluster)-1)
+	for i, segment := range cluster {
+		if i > 0 {
+			aliases = append(aliases, structures.DocumentKey("way", segment.ID))
+		}
+		for _, tag := range segment.Tags {
+			if (strings.HasPrefix(tag.Key, "name:") || tag.Key == "old_name" || tag.Key == "alt_name") && !seen[tag.Value] {
+				seen[tag.Value] = true
+				alternateNames = append(alternateNames, tag.Value)
+			}
+		}
+	}
+
+	return &InsertibleNode{
+		Node:            tmpNode,
+		AlternamteNames: alternateNames,
+		Aliases:         aliases,
+	}
+}
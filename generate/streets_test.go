@@ -0,0 +1,95 @@
+package generate
+
+import (
+	"hstin/gocoder/structures"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/paulmach/orb"
+)
+
+func TestClusterSegments(t *testing.T) {
+	segment := func(id int64, line ...orb.Point) *streetSegment {
+		return &streetSegment{ID: id, Line: orb.LineString(line)}
+	}
+
+	tests := []struct {
+		name     string
+		segments []*streetSegment
+		want     [][]int64
+	}{
+		{
+			name:     "shared node",
+			segments: []*streetSegment{segment(1, orb.Point{8.0, 50.0}, orb.Point{8.01, 50.0}), segment(2, orb.Point{8.01, 50.0}, orb.Point{8.02, 50.0})},
+			want:     [][]int64{{1, 2}},
+		},
+		{
+			// Dual carriageways run next to each other
+			name:     "close",
+			segments: []*streetSegment{segment(1, orb.Point{8.0, 50.0}, orb.Point{8.01, 50.0}), segment(2, orb.Point{8.0, 50.0005}, orb.Point{8.01, 50.0005})},
+			want:     [][]int64{{1, 2}},
+		},
+		{
+			name: "chain",
+			segments: []*streetSegment{
+				segment(3, orb.Point{8.02, 50.0}, orb.Point{8.03, 50.0}),
+				segment(1, orb.Point{8.0, 50.0}, orb.Point{8.01, 50.0}),
+				segment(2, orb.Point{8.01, 50.0}, orb.Point{8.02, 50.0}),
+			},
+			want: [][]int64{{1, 2, 3}},
+		},
+		{
+			// Streets of the same name in other parts of the settlement
+			name:     "apart",
+			segments: []*streetSegment{segment(1, orb.Point{8.0, 50.0}, orb.Point{8.01, 50.0}), segment(2, orb.Point{8.1, 50.0}, orb.Point{8.11, 50.0})},
+			want:     [][]int64{{1}, {2}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clusters := clusterSegments(test.segments)
+			got := make([][]int64, len(clusters))
+			for i, cluster := range clusters {
+				for _, segment := range cluster {
+					got[i] = append(got[i], segment.ID)
+				}
+				sort.Slice(got[i], func(a, b int) bool { return got[i][a] < got[i][b] })
+			}
+			sort.Slice(got, func(a, b int) bool { return got[a][0] < got[b][0] })
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSettlementFinder(t *testing.T) {
+	nodes := []structures.Node{
+		// Mainz, with a large bounding box
+		{Center: [2]float32{50.000, 8.270}, BoundingBox: [4]float32{49.90, 8.14, 50.04, 8.40}, Layer: 1},
+		// Mainz-Kastel, a smaller settlement inside the box of Mainz
+		{Center: [2]float32{50.009, 8.285}, BoundingBox: [4]float32{50.00, 8.27, 50.02, 8.30}, Layer: 1},
+		// Wiesbaden, without bounding box
+		{Center: [2]float32{50.082, 8.240}, Layer: 1},
+		// Not a settlement
+		{Center: [2]float32{50.010, 8.290}, BoundingBox: [4]float32{49.0, 7.0, 51.0, 9.0}, Layer: 2},
+	}
+	finder := newSettlementFinder(nodes, 5000, func(node *structures.Node) bool { return node.Layer == 1 })
+
+	tests := []struct {
+		name     string
+		lat, lng float32
+		want     int64
+	}{
+		{"inside", 49.99, 8.25, 0},
+		{"smallest box", 50.01, 8.28, 1},
+		{"nearest", 50.08, 8.25, 2},
+		{"too far", 50.5, 8.5, -1},
+	}
+	for _, test := range tests {
+		if got := finder.Find(test.lat, test.lng); got != test.want {
+			t.Errorf("%s: got %d, want %d", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package geo
+
+import (
+	"math"
+	"sort"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+)
+
+// Centroid returns the area weighted centroid of a multipolygon. The
+// centroid of a concave area may lie outside of it, see PointOnSurface.
+func Centroid(mp orb.MultiPolygon) orb.Point {
+	centroid, _ := planar.CentroidArea(mp)
+	return centroid
+}
+
+// PointOnSurface returns a point that is guaranteed to lie inside the
+// multipolygon. It is the centroid if that is inside, otherwise the middle of
+// the widest section of a horizontal line through the largest polygon.
+func PointOnSurface(mp orb.MultiPolygon) orb.Point {
+	if len(mp) == 0 {
+		return orb.Point{}
+	}
+
+	centroid := Centroid(mp)
+	if planar.MultiPolygonContains(mp, centroid) {
+		return centroid
+	}
+
+	largest := mp[0]
+	largestArea := 0.0
+	for _, polygon := range mp {
+		if area := math.Abs(planar.Area(polygon)); area > largestArea {
+			largest, largestArea = polygon, area
+		}
+	}
+
+	bound := largest.Bound()
+	y := (bound.Min[1] + bound.Max[1]) / 2
+
+	// Intersections of the scanline with all rings of the polygon, the
+	// sections between pairs of intersections are inside.
+	xs := make([]float64, 0)
+	for _, ring := range largest {
+		for i := 0; i+1 < len(ring); i++ {
+			a, b := ring[i], ring[i+1]
+			if (a[1] > y) == (b[1] > y) {
+				continue
+			}
+			xs = append(xs, a[0]+(y-a[1])*(b[0]-a[0])/(b[1]-a[1]))
+		}
+	}
+	sort.Float64s(xs)
+
+	best := orb.Point{(bound.Min[0] + bound.Max[0]) / 2, y}
+	widest := -1.0
+	for i := 0; i+1 < len(xs); i += 2 {
+		if width := xs[i+1] - xs[i]; width > widest {
+			widest = width
+			best = orb.Point{(xs[i] + xs[i+1]) / 2, y}
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,60 @@
+package geo
+
+import "math"
+
+// EarthRadius is the mean earth radius in metres.
+const EarthRadius = 6371008.8
+
+const degToRad = math.Pi / 180
+
+// ToUnitVector converts a latitude/longitude pair into an earth-centred,
+// earth-fixed (ECEF) position on the unit sphere.
+func ToUnitVector(lat, lng float64) [3]float64 {
+	latRad := lat * degToRad
+	lngRad := lng * degToRad
+	cosLat := math.Cos(latRad)
+	return [3]float64{
+		cosLat * math.Cos(lngRad),
+		cosLat * math.Sin(lngRad),
+		math.Sin(latRad),
+	}
+}
+
+// ChordToDistance converts the straight-line distance between two points on
+// the unit sphere into the great-circle distance on earth in metres.
+func ChordToDistance(chord float64) float64 {
+	if chord >= 2 {
+		return math.Pi * EarthRadius
+	}
+	return 2 * EarthRadius * math.Asin(chord/2)
+}
+
+// DistanceToChord is the inverse of ChordToDistance.
+func DistanceToChord(metres float64) float64 {
+	if metres >= math.Pi*EarthRadius {
+		return 2
+	}
+	return 2 * math.Sin(metres/(2*EarthRadius))
+}
+
+// Haversine returns the great-circle distance between two points in metres.
+func Haversine(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := (lat2 - lat1) * degToRad
+	dLng := (lng2 - lng1) * degToRad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*degToRad)*math.Cos(lat2*degToRad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Bearing returns the initial bearing in degrees (0-360, clockwise from north)
+// when travelling from the first point to the second.
+func Bearing(lat1, lng1, lat2, lng2 float64) float64 {
+	phi1 := lat1 * degToRad
+	phi2 := lat2 * degToRad
+	dLng := (lng2 - lng1) * degToRad
+
+	y := math.Sin(dLng) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dLng)
+
+	return math.Mod(math.Atan2(y, x)/degToRad+360, 360)
+}
@@ -0,0 +1,156 @@
+package geocoder
+
+import (
+	"hstin/gocoder/geo"
+	"hstin/gocoder/mapping"
+	"hstin/gocoder/structures"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// scoreHouseNumber is added to the score of the street for an address result,
+// so a found house number ranks above the street itself.
+const scoreHouseNumber = 200.0
+
+// DefaultAddressRadius is the default of ReverseOptions.AddressRadius.
+const DefaultAddressRadius = 100.0
+
+// splitHouseNumber splits a query like "unter den linden 77" or "77 main
+// street" into the street and the house number. House numbers start with a
+// digit, e.g. "12", "12a" or "12-14".
+func splitHouseNumber(query string) (string, string, bool) {
+	tokens := strings.Fields(query)
+	if len(tokens) < 2 {
+		return "", "", false
+	}
+
+	isNumber := func(token string) bool {
+		return len(token) <= 8 && unicode.IsDigit([]rune(token)[0])
+	}
+
+	if last := tokens[len(tokens)-1]; isNumber(last) {
+		return strings.Join(tokens[:len(tokens)-1], " "), last, true
+	}
+	if first := tokens[0]; isNumber(first) {
+		return strings.Join(tokens[1:], " "), first, true
+	}
+	return "", "", false
+}
+
+// addressResults returns the addresses with the house number on the given
+// streets, ordered like the streets. Streets without the house number use
+// their interpolation lines.
+func (g *Geocoder) addressResults(streets []Node, number string, explain bool, layers map[string]bool) []Node {
+	if len(layers) > 0 && !layers["address"] {
+		return nil
+	}
+
+	results := make([]Node, 0)
+	for _, street := range streets {
+		nodes := make([]Node, 0)
+		for _, address := range g.Addresses.Lookup(street.DocumentID, number) {
+			nodes = append(nodes, g.addressNode(street, address))
+		}
+		if len(nodes) == 0 {
+			for _, address := range g.Interpolations.Lookup(street.DocumentID, number) {
+				nodes = append(nodes, g.interpolatedNode(street, address))
+			}
+		}
+
+		for _, node := range nodes {
+			node.Score = street.Score + scoreHouseNumber
+			if explain && street.Explain != nil {
+				scoreExplain := *street.Explain
+				scoreExplain.HouseNumber = scoreHouseNumber
+				scoreExplain.Total = node.Score
+				node.Explain = &scoreExplain
+			}
+			results = append(results, node)
+		}
+	}
+	return results
+}
+
+// addressNode returns the result for an address of the given parent, which
+// is its street or, for addresses without a street, its place.
+func (g *Geocoder) addressNode(parent Node, address structures.Address) Node {
+	node := parent
+	node.ID = address.ID
+	node.DocumentID = -1
+	node.Name = mapping.FormatStreetAddress(mapping.AddressComponents{
+		Street:      parent.Name,
+		HouseNumber: address.HouseNumber,
+		CountryCode: parent.Country,
+	}, parent.lang)
+	node.HouseNumber = address.HouseNumber
+	node.Postcode = address.Postcode
+	if parent.Layer == "street" {
+		node.Street = parent.Name
+	} else {
+		node.City = parent.Name
+	}
+	node.Coordinates = [2]float32{address.Lat, address.Lng}
+	node.Centroid = nil
+	node.BoundingBox = [4]float32{address.Lat, address.Lng, address.Lat, address.Lng}
+	node.Population = 0
+	node.Layer = "address"
+	node.PlaceType = "house"
+	node.OSMType = mapping.GetOSMTypeName(int(address.OSMType))
+	node.Wikidata = ""
+	node.Importance = 0
+	node.Merged = nil
+	node.formatDisplayName()
+	return node
+}
+
+// interpolatedNode returns the result for a house number interpolated on a
+// line of the given parent.
+func (g *Geocoder) interpolatedNode(parent Node, address structures.InterpolatedAddress) Node {
+	lat, lng := float32(address.Lat), float32(address.Lng)
+	node := g.addressNode(parent, structures.Address{
+		HouseNumber: strconv.Itoa(address.Number),
+		Postcode:    address.Interpolation.Postcode,
+		OSMType:     uint8(mapping.GetOSMTypeNumber("way")),
+		ID:          address.Interpolation.ID,
+		Lat:         lat,
+		Lng:         lng,
+	})
+	node.Interpolated = true
+	node.Accuracy = address.Accuracy
+	return node
+}
+
+// nearestAddress returns the address closest to the coordinate within
+// radius metres, or nil if there is none. Interpolated house numbers are
+// only returned if they are closer than every mapped address.
+func (g *Geocoder) nearestAddress(lat, lng float64, lang string, radius float64) *ReverseResult {
+	if radius <= 0 {
+		radius = DefaultAddressRadius
+	}
+
+	var node Node
+	address, distance := g.Addresses.Nearest(lat, lng, radius)
+	if address != nil {
+		node = g.addressNode(g.nSearch.GetNode(address.Parent, lang), *address)
+	}
+
+	interpolated, interpolatedDistance := g.Interpolations.Nearest(lat, lng, radius)
+	if interpolated != nil && (address == nil || interpolatedDistance < distance) {
+		node = g.interpolatedNode(g.nSearch.GetNode(interpolated.Interpolation.Parent, lang), *interpolated)
+		distance = interpolatedDistance
+	} else if address == nil {
+		return nil
+	}
+
+	return &ReverseResult{
+		Node:     node,
+		Distance: distance,
+		Bearing: geo.Bearing(
+			lat,
+			lng,
+			float64(node.Coordinates[0]),
+			float64(node.Coordinates[1]),
+		),
+	}
+}
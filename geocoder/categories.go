@@ -0,0 +1,126 @@
+package geocoder
+
+import (
+	"hstin/gocoder/geo"
+	"hstin/gocoder/mapping"
+	"hstin/gocoder/structures"
+	"regexp"
+)
+
+// DefaultCategoryRadius is the search radius in metres around places without
+// an extent, e.g. villages mapped as a single node.
+const DefaultCategoryRadius = 5000.0
+
+// maxCategoryRadius limits the search radius around large places.
+const maxCategoryRadius = 50000.0
+
+// nearPattern splits "pharmacy in bonn" or "museums near köln" into the
+// category and the place.
+var nearPattern = regexp.MustCompile(`^(.+?)\s+(?:in der nähe von|close to|nearby|around|near|nahe|bei|in)\s+(.+)$`)
+
+// splitCategoryQuery recognizes queries for POIs of a category near a place.
+// The category comes first and is joined to the place by a word like "in" or
+// "near". Queries like "museum, berlin" are left to the name search, as they
+// might as well name a place called museum in berlin.
+func splitCategoryQuery(query string) ([]string, string, bool) {
+	match := nearPattern.FindStringSubmatch(query)
+	if match == nil {
+		return nil, "", false
+	}
+	categories := mapping.CategoriesForKeyword(match[1])
+	if len(categories) == 0 {
+		return nil, "", false
+	}
+	return categories, match[2], true
+}
+
+// matchCategories reports whether a category is selected by one of the
+// filters.
+func matchCategories(category string, filters []string) bool {
+	for _, filter := range filters {
+		if mapping.MatchCategory(category, filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterCategories keeps the categories of a query that are selected by the
+// category filter of the request.
+func filterCategories(categories []string, filters []string) []string {
+	out := make([]string, 0, len(categories))
+	for _, category := range categories {
+		if matchCategories(category, filters) {
+			out = append(out, category)
+		}
+	}
+	return out
+}
+
+// categoryResults resolves the place of a category query and returns the
+// POIs of the categories around it, ordered by distance and rank. It returns
+// false if the place can't be found.
+func (g *Geocoder) categoryResults(categories []string, placeQuery string, lang string, langKey int, opts SearchOptions, countries map[string]bool) ([]Node, bool) {
+	placeQuery, context := splitContext(placeQuery)
+
+	var place *Node
+	for _, candidate := range g.candidates(placeQuery, context, lang, langKey, SearchOptions{Focus: opts.Focus}, countries, nil) {
+		if candidate.Layer != "poi" && candidate.Layer != "street" {
+			place = &candidate
+			break
+		}
+	}
+	if place == nil {
+		return nil, false
+	}
+
+	// Search the extent of the place, or around its point
+	radius := geo.Haversine(
+		float64(place.BoundingBox[0]), float64(place.BoundingBox[1]),
+		float64(place.BoundingBox[2]), float64(place.BoundingBox[3]),
+	) / 2
+	radius = min(max(radius, DefaultCategoryRadius), maxCategoryRadius)
+
+	poiLayer := uint8(mapping.GetLayerNumber("poi"))
+	accept := func(p *structures.Point) bool {
+		node := &g.nSearch.Nodes[p.ID]
+		if poiLayer == 0 || node.Layer != poiLayer {
+			return false
+		}
+		return matchCategories(mapping.GetCategoryName(int(node.Category)), categories)
+	}
+
+	neighbors := g.KDTree.Nearest(structures.NewPoint(0, place.Coordinates), 0, radius, accept)
+
+	focus := &Focus{
+		Lat:   float64(place.Coordinates[0]),
+		Lng:   float64(place.Coordinates[1]),
+		Scale: radius / 1000 / 2,
+	}
+
+	results := make([]Node, 0, len(neighbors))
+	for _, neighbor := range neighbors {
+		node := g.nSearch.GetNode(neighbor.ID, lang)
+		if len(countries) > 0 && !countries[node.Country] {
+			continue
+		}
+
+		explain := ScoreExplain{
+			Match:       MatchCategory,
+			MatchedName: place.Name,
+			Rank:        float64(node.Rank),
+		}
+		var distance float64
+		explain.Focus, distance = focusScore(focus, node)
+		explain.Distance = &distance
+		explain.Total = explain.Rank + explain.Focus
+
+		node.Score = explain.Total
+		if opts.Explain {
+			node.Explain = &explain
+		}
+		results = append(results, node)
+	}
+
+	return sortByScore(results), true
+}
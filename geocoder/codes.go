@@ -0,0 +1,91 @@
+package geocoder
+
+import (
+	"hstin/gocoder/mapping"
+)
+
+// scoreCode is added to the documents whose transport code is the query, so
+// "FRA" ranks Frankfurt Airport above places with a similar name.
+const scoreCode = 2000.0
+
+// isCode reports whether the first part of a query is a known code.
+func (g *Geocoder) isCode(query string) bool {
+	searchQuery, _ := splitContext(query)
+	return len(g.Codes.Lookup(mapping.NormalizeCode(searchQuery))) > 0
+}
+
+// codeResults returns the documents with a transport code equal to the
+// query, in any case, with scoreCode so "FRA" and "fra" rank the facility
+// first. Codes are the same in every language, so they count as a match in
+// the requested one.
+func (g *Geocoder) codeResults(query string, context []string, lang string, langKey int, opts SearchOptions, countries map[string]bool, layers map[string]bool) []Node {
+	code := mapping.NormalizeCode(query)
+
+	seen := make(map[int64]bool)
+	results := make([]Node, 0)
+	for _, entry := range g.Codes.Lookup(code) {
+		if seen[entry.DocID] {
+			continue
+		}
+		seen[entry.DocID] = true
+
+		node := g.nSearch.GetNode(entry.DocID, lang)
+		if len(countries) > 0 && !countries[node.Country] {
+			continue
+		}
+		if len(layers) > 0 && !layers[node.Layer] {
+			continue
+		}
+		if len(opts.Categories) > 0 && !matchCategories(node.Category, opts.Categories) {
+			continue
+		}
+
+		explain := ScoreExplain{
+			Match:       MatchCode,
+			MatchedName: entry.Code,
+			MatchedLang: entry.Type,
+			Text:        scoreExact,
+			Language:    scoreLanguage,
+			Rank:        float64(node.Rank),
+			Code:        scoreCode,
+		}
+		if opts.Focus != nil {
+			var distance float64
+			explain.Focus, distance = focusScore(opts.Focus, node)
+			explain.Distance = &distance
+		}
+		explain.Total = explain.Text + explain.Language + explain.Rank + explain.Focus + explain.Code
+		if len(context) > 0 && matchContext(node, context) {
+			explain.Context = scoreContext
+			explain.Total += scoreContext
+		}
+
+		node.Score = explain.Total
+		if opts.Explain {
+			node.Explain = &explain
+		}
+		results = append(results, node)
+	}
+	return filterContext(results, context)
+}
+
+// nodeCodes returns the codes of a document by code type. Several codes of
+// one type are joined with ";" like in OSM.
+func (g *NodesSearch) nodeCodes(docID int64) map[string]string {
+	if g.Codes == nil {
+		return nil
+	}
+	codes := g.Codes.Document(docID)
+	if len(codes) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(codes))
+	for _, code := range codes {
+		if out[code.Type] != "" {
+			out[code.Type] += ";" + code.Code
+		} else {
+			out[code.Type] = code.Code
+		}
+	}
+	return out
+}
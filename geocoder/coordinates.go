@@ -0,0 +1,61 @@
+package geocoder
+
+import (
+	"hstin/gocoder/config"
+	"hstin/gocoder/parser"
+	"strings"
+)
+
+// coordinateResults answers a query that is a coordinate with the parsed
+// point as "coordinate" and the reverse geocoding response of the point.
+// Short plus codes are recovered from their reference place, or from the
+// focus if they have none. It returns false for short plus codes without a
+// reference, so the query is searched as a name.
+func (g *Geocoder) coordinateResults(coordinate parser.Coordinate, lang string, opts SearchOptions) (map[string]interface{}, bool) {
+	if coordinate.IsShort() {
+		lat, lng, ok := g.referencePoint(coordinate.Reference, lang, opts)
+		if !ok || !coordinate.Recover(lat, lng) {
+			return nil, false
+		}
+	}
+
+	response := map[string]interface{}{
+		"results": []ReverseResult{},
+	}
+	if config.EnableReverse {
+		response = g.Reverse(coordinate.Lat, coordinate.Lng, lang, ReverseOptions{K: 1, Layers: opts.Layers})
+	}
+
+	found := 0
+	if results, ok := response["results"].([]ReverseResult); ok {
+		found = len(results)
+	}
+	response["found"] = found
+	response["coordinate"] = coordinate
+
+	return response, true
+}
+
+// referencePoint returns the position of the best place matching the
+// reference of a short plus code, or the focus if there is no reference.
+func (g *Geocoder) referencePoint(reference string, lang string, opts SearchOptions) (float64, float64, bool) {
+	if reference == "" {
+		if opts.Focus == nil {
+			return 0, 0, false
+		}
+		return opts.Focus.Lat, opts.Focus.Lng, true
+	}
+
+	langKey := g.nSearch.LanguageMap[lang]
+	countries := make(map[string]bool, len(opts.Countries))
+	for _, country := range opts.Countries {
+		countries[strings.ToUpper(country)] = true
+	}
+
+	query, context := splitContext(strings.ToLower(reference))
+	places := g.candidates(query, context, lang, langKey, SearchOptions{Focus: opts.Focus}, countries, nil)
+	if len(places) == 0 {
+		return 0, 0, false
+	}
+	return float64(places[0].Coordinates[0]), float64(places[0].Coordinates[1]), true
+}
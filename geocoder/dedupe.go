@@ -0,0 +1,70 @@
+package geocoder
+
+import (
+	"hstin/gocoder/geo"
+	"hstin/gocoder/mapping"
+	"strconv"
+	"strings"
+)
+
+// DefaultDedupeRadius is the distance in metres within which places with the
+// same name and admin parent are considered duplicates.
+const DefaultDedupeRadius = 10000.0
+
+// dedupe collapses results that share the normalized name and admin parent
+// and lie within radius metres of each other. Each group keeps the position
+// of its best scored member but is represented by its highest ranked one,
+// the IDs of the other members are listed in Merged.
+func dedupe(nodes []Node, radius float64) []Node {
+	out := make([]Node, 0, len(nodes))
+	groups := make(map[string][]int)
+
+	for _, node := range nodes {
+		key := strings.ToLower(strings.TrimSpace(node.Name)) + "|" + parentKey(node)
+
+		merged := false
+		for _, i := range groups[key] {
+			kept := &out[i]
+			distance := geo.Haversine(
+				float64(kept.Coordinates[0]), float64(kept.Coordinates[1]),
+				float64(node.Coordinates[0]), float64(node.Coordinates[1]),
+			)
+			if distance > radius {
+				continue
+			}
+
+			if node.Rank > kept.Rank {
+				// Swap in the higher ranked place, keep score and position
+				score, explain, ids := kept.Score, kept.Explain, append(kept.Merged, kept.ID)
+				*kept = node
+				kept.Score, kept.Explain, kept.Merged = score, explain, append(ids, node.Merged...)
+			} else {
+				kept.Merged = append(kept.Merged, node.ID)
+				kept.Merged = append(kept.Merged, node.Merged...)
+			}
+			merged = true
+			break
+		}
+
+		if !merged {
+			groups[key] = append(groups[key], len(out))
+			out = append(out, node)
+		}
+	}
+
+	return out
+}
+
+// parentKey identifies the admin area a place belongs to: the smallest level
+// of its hierarchy down to county, or the country and region names if the
+// place has no hierarchy.
+func parentKey(node Node) string {
+	county := mapping.GetAdminLevel("county")
+	for i := len(node.Hierarchy) - 1; i >= 0; i-- {
+		level := mapping.GetAdminLevel(node.Hierarchy[i].Placetype)
+		if level >= 0 && level <= county {
+			return strconv.FormatInt(node.Hierarchy[i].ID, 10)
+		}
+	}
+	return node.Country + "|" + node.Region + "|" + node.SubRegion
+}
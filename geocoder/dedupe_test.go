@@ -0,0 +1,83 @@
+package geocoder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDedupe(t *testing.T) {
+	berlin := []AdminLevel{{Placetype: "country", ID: 85633111}, {Placetype: "county", ID: 102063261}}
+	potsdam := []AdminLevel{{Placetype: "country", ID: 85633111}, {Placetype: "county", ID: 102063263}}
+
+	city := Node{ID: 240109189, Name: "Berlin", Rank: 900, Score: 1900, Coordinates: [2]float32{52.517, 13.389}, Hierarchy: berlin}
+	suburb := Node{ID: 2, Name: "Berlin ", Rank: 450, Score: 2000, Coordinates: [2]float32{52.52, 13.40}, Hierarchy: berlin}
+	otherCounty := Node{ID: 3, Name: "berlin", Rank: 300, Score: 1300, Coordinates: [2]float32{52.52, 13.40}, Hierarchy: potsdam}
+	farAway := Node{ID: 4, Name: "Berlin", Rank: 500, Score: 1500, Coordinates: [2]float32{53.5, 13.40}, Hierarchy: berlin}
+	// Without hierarchy the country and region names are the parent
+	village := Node{ID: 5, Name: "Neustadt", Rank: 300, Score: 1300, Country: "DE", Region: "Hessen"}
+	hamlet := Node{ID: 6, Name: "Neustadt", Rank: 200, Score: 1200, Country: "DE", Region: "Hessen"}
+	otherRegion := Node{ID: 7, Name: "Neustadt", Rank: 200, Score: 1200, Country: "DE", Region: "Bayern"}
+
+	tests := []struct {
+		name   string
+		nodes  []Node
+		ids    []int64
+		merged [][]int64
+		scores []float64
+	}{
+		{
+			// The city replaces the better scored suburb in its position
+			name:   "same parent",
+			nodes:  []Node{suburb, city},
+			ids:    []int64{240109189},
+			merged: [][]int64{{2}},
+			scores: []float64{2000},
+		},
+		{
+			name:   "lower ranked later",
+			nodes:  []Node{city, suburb},
+			ids:    []int64{240109189},
+			merged: [][]int64{{2}},
+			scores: []float64{1900},
+		},
+		{
+			name:   "other county",
+			nodes:  []Node{city, otherCounty},
+			ids:    []int64{240109189, 3},
+			merged: [][]int64{nil, nil},
+			scores: []float64{1900, 1300},
+		},
+		{
+			name:   "too far",
+			nodes:  []Node{city, farAway},
+			ids:    []int64{240109189, 4},
+			merged: [][]int64{nil, nil},
+			scores: []float64{1900, 1500},
+		},
+		{
+			name:   "region names",
+			nodes:  []Node{village, hamlet, otherRegion},
+			ids:    []int64{5, 7},
+			merged: [][]int64{{6}, nil},
+			scores: []float64{1300, 1200},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := dedupe(append([]Node(nil), test.nodes...), DefaultDedupeRadius)
+			ids := make([]int64, len(out))
+			merged := make([][]int64, len(out))
+			scores := make([]float64, len(out))
+			for i, node := range out {
+				ids[i], merged[i], scores[i] = node.ID, node.Merged, node.Score
+			}
+			if !reflect.DeepEqual(ids, test.ids) || !reflect.DeepEqual(merged, test.merged) || !reflect.DeepEqual(scores, test.scores) {
+				t.Errorf("got %v %v %v, want %v %v %v", ids, merged, scores, test.ids, test.merged, test.scores)
+			}
+		})
+	}
+
+	if nodes := (SearchOptions{}).dedupe([]Node{city, suburb}); len(nodes) != 2 {
+		t.Errorf("deduped %d nodes without Dedupe, want 2", len(nodes))
+	}
+}
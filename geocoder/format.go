@@ -0,0 +1,38 @@
+package geocoder
+
+import "hstin/gocoder/mapping"
+
+// DisplayName is the label of a result in the address format of its country,
+// see mapping.AddressFormats. Short only names the place and its settlement,
+// Long adds the postcode, region and country.
+type DisplayName struct {
+	Short string `json:"short"`
+	Long  string `json:"long"`
+}
+
+// formatDisplayName sets the display name from the other fields of the
+// node. It has to be called again after changing them.
+func (n *Node) formatDisplayName() {
+	components := mapping.AddressComponents{
+		Name:        n.Name,
+		HouseNumber: n.HouseNumber,
+		Street:      n.Street,
+		Postcode:    n.Postcode,
+		City:        n.City,
+		County:      n.SubRegion,
+		Region:      n.Region,
+		Country:     n.CountryName,
+		CountryCode: n.Country,
+	}
+
+	// The names of streets and addresses are part of the address itself
+	switch n.Layer {
+	case "street":
+		components.Street = n.Name
+		components.Name = ""
+	case "address":
+		components.Name = ""
+	}
+
+	n.DisplayName.Short, n.DisplayName.Long = mapping.FormatAddress(components, n.lang)
+}
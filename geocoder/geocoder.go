@@ -3,6 +3,9 @@ package geocoder
 import (
 	"encoding/binary"
 	"hstin/gocoder/config"
+	"hstin/gocoder/geo"
+	"hstin/gocoder/mapping"
+	"hstin/gocoder/parser"
 	"hstin/gocoder/structures"
 	"hstin/gocoder/utils"
 	"io"
@@ -22,6 +25,18 @@ type Geocoder struct {
 	Trie        *structures.Trie
 	Index       *structures.Index
 	KDTree      *structures.KDTree
+	Admin       *structures.AdminIndex
+	Wikidata    *structures.WikidataIndex
+	Metadata    *structures.Metadata
+	Streets     *structures.StreetIndex
+	Addresses   *structures.AddressIndex
+	// Interpolations holds the addr:interpolation lines between addresses.
+	Interpolations *structures.InterpolationIndex
+	Postcodes      *structures.PostcodeIndex
+	// Codes maps IATA, ICAO, UIC and similar codes to documents.
+	Codes *structures.CodeIndex
+	// Natural holds the polygons of seas, lakes and islands.
+	Natural *structures.NaturalIndex
 }
 
 func (g *Geocoder) Close() error {
@@ -38,9 +53,14 @@ func NewGeocoder(DatabaseFile string) (*Geocoder, error) {
 	}
 	defer f.Close()
 
+	// Refuse databases written in another format before reading offsets
+	if err := structures.ReadFormatHeader(f); err != nil {
+		return nil, err
+	}
+
 	// Read header to get offsets and sizes (10 uint64 values)
 	header := make([]byte, 8*10) // 10 uint64 values
-	if _, err := f.Read(header); err != nil {
+	if _, err := io.ReadFull(f, header); err != nil {
 		return nil, err
 	}
 
@@ -63,8 +83,14 @@ func NewGeocoder(DatabaseFile string) (*Geocoder, error) {
 
 	kdTreeOffset := indexOffset + indexSize
 
-	// Languages start right after the header + kdTreeSize field
-	languagesOffset := uint64(8*10 + 8)
+	// Sections follow the KD tree
+	sections, err := structures.ReadSections(f, int64(kdTreeOffset+kdTreeSize))
+	if err != nil {
+		return nil, err
+	}
+
+	// Languages start right after the format version, header + kdTreeSize field
+	languagesOffset := uint64(structures.FormatHeaderSize + 8*10 + 8)
 
 	// Read languages section
 	if _, err := f.Seek(int64(languagesOffset), io.SeekStart); err != nil {
@@ -95,11 +121,21 @@ func NewGeocoder(DatabaseFile string) (*Geocoder, error) {
 	}
 
 	var (
-		nSearch     NodesSearch
-		documentMap structures.DocumentMap
-		trie        structures.Trie
-		index       structures.Index
-		KDTree      structures.KDTree
+		nSearch        NodesSearch
+		documentMap    structures.DocumentMap
+		trie           structures.Trie
+		index          structures.Index
+		KDTree         structures.KDTree
+		adminIndex     = structures.NewAdminIndex()
+		countries      = structures.NewCountryTable()
+		wikidata       = structures.NewWikidataIndex()
+		metadata       = &structures.Metadata{}
+		streets        = structures.NewStreetIndex()
+		addresses      = structures.NewAddressIndex()
+		interpolations = structures.NewInterpolationIndex()
+		postcodes      = structures.NewPostcodeIndex()
+		codes          = structures.NewCodeIndex()
+		natural        = structures.NewNaturalIndex()
 	)
 
 	if config.EnableForward {
@@ -136,27 +172,132 @@ func NewGeocoder(DatabaseFile string) (*Geocoder, error) {
 		}
 		runtime.GC()
 
+		// 5. Load Admin Polygons
+		if section, ok := sections[structures.SectionAdmin]; ok {
+			log.Printf("Loading admin polygons (%d MB)...", section.Size/1024/1024)
+			if err := adminIndex.LoadFromFile(f, section.Offset, section.Size); err != nil {
+				return nil, err
+			}
+			runtime.GC()
+		}
+
+	}
+
+	// 6. Load Countries
+	if section, ok := sections[structures.SectionCountries]; ok {
+		log.Printf("Loading countries...")
+		if err := countries.LoadFromFile(f, section.Offset, section.Size); err != nil {
+			return nil, err
+		}
+	}
+
+	// 7. Load Wikidata Index
+	if section, ok := sections[structures.SectionWikidata]; ok {
+		log.Printf("Loading wikidata index (%d MB)...", section.Size/1024/1024)
+		if err := wikidata.LoadFromFile(f, section.Offset, section.Size); err != nil {
+			return nil, err
+		}
+	}
+
+	// 8. Load Metadata
+	if section, ok := sections[structures.SectionMetadata]; ok {
+		if err := metadata.LoadFromFile(f, section.Offset, section.Size); err != nil {
+			return nil, err
+		}
+	}
+
+	// 9. Load Street Geometries
+	if section, ok := sections[structures.SectionStreets]; ok && config.EnableReverse {
+		log.Printf("Loading streets (%d MB)...", section.Size/1024/1024)
+		if err := streets.LoadFromFile(f, section.Offset, section.Size); err != nil {
+			return nil, err
+		}
+	}
+
+	// 10. Load Addresses
+	if section, ok := sections[structures.SectionAddresses]; ok {
+		log.Printf("Loading addresses (%d MB)...", section.Size/1024/1024)
+		if err := addresses.LoadFromFile(f, section.Offset, section.Size); err != nil {
+			return nil, err
+		}
 	}
 
-	// 5. Load Nodes Search (this opens its own file handle)
+	// 11. Load Interpolations
+	if section, ok := sections[structures.SectionInterpolations]; ok {
+		log.Printf("Loading interpolations (%d MB)...", section.Size/1024/1024)
+		if err := interpolations.LoadFromFile(f, section.Offset, section.Size); err != nil {
+			return nil, err
+		}
+	}
+
+	// 12. Load Postcodes
+	if section, ok := sections[structures.SectionPostcodes]; ok {
+		log.Printf("Loading postcodes (%d MB)...", section.Size/1024/1024)
+		if err := postcodes.LoadFromFile(f, section.Offset, section.Size); err != nil {
+			return nil, err
+		}
+	}
+
+	// 13. Load Transport Codes
+	if section, ok := sections[structures.SectionCodes]; ok {
+		log.Printf("Loading transport codes (%d MB)...", section.Size/1024/1024)
+		if err := codes.LoadFromFile(f, section.Offset, section.Size); err != nil {
+			return nil, err
+		}
+	}
+
+	// 14. Load Natural Feature Polygons
+	if section, ok := sections[structures.SectionNatural]; ok && config.EnableReverse {
+		log.Printf("Loading natural features (%d MB)...", section.Size/1024/1024)
+		if err := natural.LoadFromFile(f, section.Offset, section.Size); err != nil {
+			return nil, err
+		}
+	}
+
+	// Layers and place types added by tag filters are only known from the
+	// metadata of the database.
+	if len(metadata.Layers) > 0 {
+		mapping.Layers = metadata.Layers
+	}
+	if len(metadata.PlaceTypes) > 0 {
+		mapping.PlaceTypes = metadata.PlaceTypes
+	}
+	if len(metadata.Categories) > 0 {
+		mapping.Categories = metadata.Categories
+	}
+	mapping.SetCategoryKeywords(metadata.Taxonomy)
+
+	// 15. Load Nodes Search (this opens its own file handle)
 	log.Printf("Loading nodes search...")
 	nodeFile, err := os.Open(DatabaseFile)
 	if err != nil {
 		return nil, err
 	}
 	nSearch.LoadSingleFile(nodeFile, int64(nodesOffset), nodesSize, int64(stringsOffset), stringsSize, languageMap)
+	nSearch.Countries = countries
+	nSearch.Postcodes = postcodes
+	nSearch.Codes = codes
 
 	// Final garbage collection
 	runtime.GC()
 	log.Printf("Geocoder initialization complete")
 
 	return &Geocoder{
-		DocumentMap: documentMap,
-		cache:       make(map[string]structures.CacheEntry),
-		nSearch:     &nSearch,
-		Trie:        &trie,
-		Index:       &index,
-		KDTree:      &KDTree,
+		DocumentMap:    documentMap,
+		cache:          make(map[string]structures.CacheEntry),
+		nSearch:        &nSearch,
+		Trie:           &trie,
+		Index:          &index,
+		KDTree:         &KDTree,
+		Admin:          adminIndex,
+		Wikidata:       wikidata,
+		Metadata:       metadata,
+		Streets:        streets,
+		Addresses:      addresses,
+		Interpolations: interpolations,
+		Postcodes:      postcodes,
+		Codes:          codes,
+		Natural:        natural,
 	}, nil
 }
 
@@ -179,26 +320,71 @@ func (g *Geocoder) Cache(query string, nodes []Node) {
 	g.cacheLock.Unlock()
 }
 
-func (g *Geocoder) Search(
-	query string,
-	maxResults int,
-	useCache bool,
-	lang string,
-) (map[string]interface{}, bool) {
-	if query == "" {
+// SearchOptions controls filtering and scoring of a forward search.
+type SearchOptions struct {
+	// MaxResults limits the number of results, 0 or less returns all.
+	MaxResults int
+	UseCache   bool
+	// Focus biases the results towards a location, nil disables it.
+	Focus     *Focus
+	Countries []string
+	Layers    []string
+	// Categories restricts the results to POIs of these categories or
+	// categories below them, e.g. "health" or "health.pharmacy".
+	Categories []string
+	// Explain adds the score breakdown to every result.
+	Explain bool
+	// Dedupe collapses duplicate places within DedupeRadius metres, 0 uses
+	// DefaultDedupeRadius.
+	Dedupe       bool
+	DedupeRadius float64
+	// Address is a structured query, it replaces the parsed query text.
+	Address *parser.Address
+}
+
+func (o SearchOptions) dedupe(nodes []Node) []Node {
+	if !o.Dedupe {
+		return nodes
+	}
+	radius := o.DedupeRadius
+	if radius <= 0 {
+		radius = DefaultDedupeRadius
+	}
+	return dedupe(nodes, radius)
+}
+
+func (o SearchOptions) cacheable() bool {
+	return o.Focus == nil && len(o.Countries) == 0 && len(o.Layers) == 0 && len(o.Categories) == 0 && !o.Explain &&
+		o.Address == nil
+}
+
+func (g *Geocoder) Search(query string, lang string, opts SearchOptions) (map[string]interface{}, bool) {
+	if query == "" && opts.Address == nil {
 		return map[string]interface{}{
 			"found":   0,
 			"results": []Node{},
 		}, false
 	}
 
-	normalizedQuery := strings.ToLower(strings.TrimSpace(query))
+	// "52.5163, 13.3777", "52°31'N 13°24'E", "9F4MGCC2+RX" or "33U UU 89434
+	// 19806" return the point and the place there. Coordinate results
+	// aren't cached.
+	if opts.Address == nil {
+		if coordinate, ok := parser.ParseCoordinate(query); ok {
+			if response, ok := g.coordinateResults(coordinate, lang, opts); ok {
+				return response, false
+			}
+		}
+	}
 
-	returnMap := make(map[int64]Node)
+	normalizedQuery := strings.ToLower(strings.TrimSpace(query))
+	cacheKey := normalizedQuery + "|" + lang
+	// Queries that are a code aren't cached, see codeResults
+	useCache := opts.cacheable() && !config.DisableCache && !g.isCode(normalizedQuery)
 
-	if useCache && !config.DisableCache {
+	if useCache && opts.UseCache {
 		g.cacheLock.RLock()
-		cached, ok := g.cache[normalizedQuery]
+		cached, ok := g.cache[cacheKey]
 		g.cacheLock.RUnlock()
 
 		if ok {
@@ -208,54 +394,115 @@ func (g *Geocoder) Search(
 				node := g.nSearch.GetNode(docID, lang)
 				returnDocs = append(returnDocs, node)
 			}
+			return limitResults(opts.dedupe(returnDocs), opts.MaxResults), true
+		}
+	}
 
-			if maxResults > 0 && len(returnDocs) > maxResults {
-				returnDocs = returnDocs[:maxResults]
-			}
+	countries := make(map[string]bool, len(opts.Countries))
+	for _, country := range opts.Countries {
+		countries[strings.ToUpper(country)] = true
+	}
+	layers := make(map[string]bool, len(opts.Layers))
+	for _, layer := range opts.Layers {
+		layers[layer] = true
+	}
 
-			return map[string]interface{}{
-				"found":   cached.Found,
-				"results": returnDocs,
-			}, true
+	langKey, ok := g.nSearch.LanguageMap[lang]
+	if !ok {
+		langKey = 0
+	}
+
+	// "Apt 4, 12 Baker Street, London NW1 6XE, UK" is split into its
+	// components, each searched in its own index. Queries the parser finds
+	// nothing for are answered as usual. Structured results aren't cached.
+	address := opts.Address
+	if address == nil {
+		if parsed := parser.Parse(query); isStructured(parsed) {
+			address = &parsed
+		}
+	}
+	if address != nil {
+		results := g.structuredResults(*address, lang, langKey, opts, countries, layers)
+		if len(results) > 0 || opts.Address != nil {
+			return limitResults(opts.dedupe(results), opts.MaxResults), false
 		}
 	}
 
-	// 1) TRIE SEARCH
-	trieResults := g.Trie.Search(normalizedQuery)
+	// "Hauptstraße, Mainz" searches for the first part and uses the rest as
+	// context that has to match the settlement or admin areas of a result.
+	searchQuery, context := splitContext(normalizedQuery)
+	if searchQuery == "" {
+		return map[string]interface{}{
+			"found":   0,
+			"results": []Node{},
+		}, false
+	}
 
-	for _, docID := range trieResults {
-		node := g.nSearch.GetNode(docID, lang)
-		node.Rank += 500
-		returnMap[node.ID] = node
+	// "pharmacy in bonn" returns the pharmacies around the best place named
+	// bonn. Category results aren't cached.
+	if categories, place, ok := splitCategoryQuery(searchQuery); ok && (len(layers) == 0 || layers["poi"]) {
+		if len(context) > 0 {
+			place += ", " + strings.Join(context, ", ")
+		}
+		if len(opts.Categories) > 0 {
+			categories = filterCategories(categories, opts.Categories)
+		}
+		// With none of the categories left the query is searched by name
+		if len(categories) > 0 {
+			if results, found := g.categoryResults(categories, place, lang, langKey, opts, countries); found {
+				return limitResults(results, opts.MaxResults), false
+			}
+		}
 	}
 
-	// 2) FUZZY SEARCH
-	if len(returnMap) < 10 && len(normalizedQuery) > 2 {
-		maxDistance := 1
-		if len(normalizedQuery) > 4 {
-			maxDistance = 2
+	// "10115" or "SW1A 1AA" returns the postcode next to the places found.
+	// Postcode results aren't cached.
+	if codes := g.postcodeResults(searchQuery, lang, countries, layers, opts.Explain); len(codes) > 0 {
+		returnDocs := append(filterContext(codes, context), g.candidates(searchQuery, context, lang, langKey, opts, countries, layers)...)
+		return limitResults(opts.dedupe(sortByScore(returnDocs)), opts.MaxResults), false
+	}
+
+	// "Unter den Linden 77" returns the house number if the street has it,
+	// and the usual results otherwise. Address results aren't cached.
+	if street, number, ok := splitHouseNumber(searchQuery); ok && len(g.Addresses.Addresses)+len(g.Interpolations.Interpolations) > 0 {
+		streets := g.candidates(street, context, lang, langKey, opts, countries, nil)
+		if addresses := g.addressResults(streets, number, opts.Explain, layers); len(addresses) > 0 {
+			return limitResults(opts.dedupe(addresses), opts.MaxResults), false
 		}
+	}
 
-		indexResults := g.Index.Search(normalizedQuery, maxDistance)
+	returnDocs := g.candidates(searchQuery, context, lang, langKey, opts, countries, layers)
 
-		for _, docID := range indexResults {
-			node := g.nSearch.GetNode(docID, lang)
-			node.Rank -= 100
-			if _, ok := returnMap[node.ID]; !ok {
-				returnMap[node.ID] = node
+	// "FRA" or "EDDF" returns the airport with that code first, followed by
+	// the places matching the query by name.
+	rawQuery, _ := splitContext(strings.TrimSpace(query))
+	if codes := g.codeResults(rawQuery, context, lang, langKey, opts, countries, layers); len(codes) > 0 {
+		found := make(map[int64]bool, len(codes))
+		for _, node := range codes {
+			found[node.DocumentID] = true
+		}
+		for _, node := range returnDocs {
+			if !found[node.DocumentID] {
+				codes = append(codes, node)
 			}
 		}
+		return limitResults(opts.dedupe(sortByScore(codes)), opts.MaxResults), false
 	}
 
-	// Collect and sort the nodes
-	returnDocs := sortNodes(utils.MapToSlice(returnMap))
-	foundElements := len(returnDocs)
-
-	// Cache with normalized query if cache isnt disabled
-	if !config.DisableCache {
-		g.Cache(normalizedQuery, returnDocs)
+	// Cache with normalized query if cache isnt disabled. The cache holds
+	// the results before deduplication so both paths can apply it.
+	if useCache {
+		g.Cache(cacheKey, returnDocs)
 	}
 
+	return limitResults(opts.dedupe(returnDocs), opts.MaxResults), false
+}
+
+// limitResults returns the search response with at most maxResults results,
+// 0 or less returns all.
+func limitResults(returnDocs []Node, maxResults int) map[string]interface{} {
+	foundElements := len(returnDocs)
+
 	// If maxResults > 0, limit the returned slice
 	if maxResults > 0 && len(returnDocs) > maxResults {
 		returnDocs = returnDocs[:maxResults]
@@ -264,27 +511,451 @@ func (g *Geocoder) Search(
 	return map[string]interface{}{
 		"found":   foundElements,
 		"results": returnDocs,
-	}, false
+	}
 }
 
-func (g *Geocoder) Reverse(lat float64, lng float64, lang string) map[string]interface{} {
-	results := g.KDTree.KNN(
+// candidates returns the places matching the query that pass the country
+// and layer filters, ordered by score.
+func (g *Geocoder) candidates(
+	searchQuery string,
+	context []string,
+	lang string,
+	langKey int,
+	opts SearchOptions,
+	countries map[string]bool,
+	layers map[string]bool,
+) []Node {
+	maxDistance := 1
+	if len(searchQuery) > 4 {
+		maxDistance = 2
+	}
+
+	returnMap := make(map[int64]Node)
+
+	add := func(docID int64, match string) {
+		node := g.nSearch.GetNode(docID, lang)
+		if _, ok := returnMap[node.ID]; ok {
+			return
+		}
+		if len(countries) > 0 && !countries[node.Country] {
+			return
+		}
+		if len(layers) > 0 && !layers[node.Layer] {
+			return
+		}
+		if len(opts.Categories) > 0 && !matchCategories(node.Category, opts.Categories) {
+			return
+		}
+
+		explain := g.scoreNode(&node, searchQuery, match, maxDistance, langKey, opts.Focus)
+		if len(context) > 0 && matchContext(node, context) {
+			explain.Context = scoreContext
+			explain.Total += scoreContext
+		}
+		node.Score = explain.Total
+		if opts.Explain {
+			node.Explain = &explain
+		}
+		returnMap[node.ID] = node
+	}
+
+	// 1) TRIE SEARCH
+	for _, docID := range g.Trie.Search(searchQuery) {
+		add(docID, MatchPrefix)
+	}
+
+	// 2) FUZZY SEARCH
+	if len(returnMap) < 10 && len(searchQuery) > 2 {
+		for _, docID := range g.Index.Search(searchQuery, maxDistance) {
+			add(docID, MatchFuzzy)
+		}
+	}
+
+	// Collect and sort the nodes
+	return sortByScore(filterContext(utils.MapToSlice(returnMap), context))
+}
+
+// ReverseOptions narrows down the places returned by Reverse.
+type ReverseOptions struct {
+	// K is the maximum number of results, at most MaxReverseResults. 0
+	// returns up to MaxReverseResults places within Radius.
+	K int
+	// Radius in metres, at most MaxReverseRadius. 0 means unlimited.
+	Radius        float64
+	MinPopulation uint32
+	Layers        []string
+	// StreetRadius is the distance in metres within which the nearest
+	// street is returned, 0 uses DefaultStreetRadius.
+	StreetRadius float64
+	// AddressRadius is the distance in metres within which the nearest
+	// address is returned, 0 uses DefaultAddressRadius.
+	AddressRadius float64
+	// PostcodeRadius is the distance in metres within which the nearest
+	// postcode is returned if no postcode area contains the coordinate, 0
+	// uses DefaultPostcodeRadius.
+	PostcodeRadius float64
+}
+
+// MaxReverseResults limits ReverseOptions.K, so a single request can't
+// walk and return the whole index.
+const MaxReverseResults = 100
+
+// MaxReverseRadius limits ReverseOptions.Radius in metres.
+const MaxReverseRadius = 100000.0
+
+// DefaultStreetRadius is the default of ReverseOptions.StreetRadius.
+const DefaultStreetRadius = 200.0
+
+// ReverseResult is a place found by Reverse together with its distance in
+// metres and the bearing in degrees from the query point.
+type ReverseResult struct {
+	Node
+	Distance float64 `json:"distance"`
+	Bearing  float64 `json:"bearing"`
+}
+
+func (g *Geocoder) Reverse(lat float64, lng float64, lang string, opts ReverseOptions) map[string]interface{} {
+	if opts.K <= 0 && opts.Radius <= 0 {
+		opts.K = 1
+	}
+	if opts.K <= 0 || opts.K > MaxReverseResults {
+		opts.K = MaxReverseResults
+	}
+	opts.Radius = min(opts.Radius, MaxReverseRadius)
+
+	// Unknown layer names match nothing, they must not select the nodes
+	// without layer
+	filterLayers := len(opts.Layers) > 0
+	layers := make(map[uint8]bool, len(opts.Layers))
+	for _, layer := range opts.Layers {
+		if number := mapping.GetLayerNumber(layer); number != 0 {
+			layers[uint8(number)] = true
+		}
+	}
+
+	// POIs and natural features are only returned if asked for, they would
+	// shadow the places
+	hidden := make(map[uint8]bool, 2)
+	for _, layer := range []string{"poi", "natural"} {
+		if number := mapping.GetLayerNumber(layer); number != 0 {
+			hidden[uint8(number)] = true
+		}
+	}
+
+	accept := func(p *structures.Point) bool {
+		node := &g.nSearch.Nodes[p.ID]
+		if node.Population < opts.MinPopulation {
+			return false
+		}
+		if filterLayers && !layers[node.Layer] {
+			return false
+		}
+		if !filterLayers && hidden[node.Layer] {
+			return false
+		}
+		return true
+	}
+
+	neighbors := g.KDTree.Nearest(
 		structures.NewPoint(0, [2]float32{float32(lat), float32(lng)}),
-		1,
+		opts.K,
+		opts.Radius,
+		accept,
 	)
-	if len(results) == 0 {
-		return map[string]interface{}{
-			"results": []Node{},
+
+	results := make([]ReverseResult, 0, len(neighbors))
+	for _, neighbor := range neighbors {
+		node := g.nSearch.GetNode(neighbor.ID, lang)
+		results = append(results, ReverseResult{
+			Node:     node,
+			Distance: neighbor.Distance,
+			Bearing: geo.Bearing(
+				lat,
+				lng,
+				float64(node.Coordinates[0]),
+				float64(node.Coordinates[1]),
+			),
+		})
+	}
+
+	streetRadius := opts.StreetRadius
+	if streetRadius <= 0 {
+		streetRadius = DefaultStreetRadius
+	}
+
+	var street *ReverseResult
+	if nearest, distance := g.Streets.Nearest(lat, lng, streetRadius); nearest != nil {
+		node := g.nSearch.GetNode(nearest.DocID, lang)
+		street = &ReverseResult{
+			Node:     node,
+			Distance: distance,
+			Bearing: geo.Bearing(
+				lat,
+				lng,
+				float64(node.Coordinates[0]),
+				float64(node.Coordinates[1]),
+			),
+		}
+	}
+
+	// Points at sea return the sea instead of a far away coastal place
+	admin := g.AdminAreas(lat, lng, lang)
+	if len(results) > 0 {
+		admin.addLocalLevels(results[0].Hierarchy)
+	}
+	natural := g.naturalAreas(lat, lng, lang)
+	if !filterLayers {
+		if offshore, ok := offshoreResults(natural, admin, results, opts); ok {
+			results = offshore
+		}
+	}
+
+	var naturalArea *ReverseResult
+	if len(natural) > 0 {
+		naturalArea = &natural[0]
+	}
+
+	return map[string]interface{}{
+		"admin":    admin,
+		"natural":  naturalArea,
+		"street":   street,
+		"address":  g.nearestAddress(lat, lng, lang, opts.AddressRadius),
+		"postcode": g.nearestPostcode(lat, lng, lang, opts.PostcodeRadius),
+		"results":  results,
+	}
+}
+
+// AdminAreas holds the admin areas containing a coordinate.
+type AdminAreas struct {
+	Country     string       `json:"country"`
+	CountryName string       `json:"countryName"`
+	Region      string       `json:"region"`
+	County      string       `json:"county"`
+	Hierarchy   []AdminLevel `json:"hierarchy"`
+}
+
+// AdminAreas returns the admin areas whose polygons contain the coordinate.
+func (g *Geocoder) AdminAreas(lat, lng float64, lang string) AdminAreas {
+	langKey := g.nSearch.LanguageMap[lang]
+
+	polygons := g.Admin.Contains(lat, lng)
+	// Who's On First boundaries win over OSM relations of the same level
+	sort.SliceStable(polygons, func(i, j int) bool {
+		li, lj := mapping.GetAdminLevel(polygons[i].Placetype), mapping.GetAdminLevel(polygons[j].Placetype)
+		if li != lj {
+			return li < lj
+		}
+		return polygons[i].Source != "osm" && polygons[j].Source == "osm"
+	})
+
+	areas := AdminAreas{
+		Hierarchy: make([]AdminLevel, 0, len(polygons)),
+	}
+	seen := make(map[string]bool, len(polygons))
+	for _, polygon := range polygons {
+		if seen[polygon.Placetype] {
+			continue
+		}
+		seen[polygon.Placetype] = true
+
+		if areas.Country == "" && polygon.Country != "" {
+			areas.Country = polygon.Country
+		}
+
+		name := ""
+		if langKey < len(polygon.Names) {
+			name = polygon.Names[langKey]
+		}
+
+		switch polygon.Placetype {
+		case "region":
+			areas.Region = name
+		case "county":
+			areas.County = name
+		}
+
+		areas.Hierarchy = append(areas.Hierarchy, AdminLevel{
+			Placetype: polygon.Placetype,
+			ID:        polygon.ID,
+			Name:      name,
+			Source:    polygon.Source,
+		})
+	}
+
+	areas.CountryName = g.nSearch.CountryName(areas.Country, langKey)
+
+	return areas
+}
+
+// addLocalLevels adds the levels below county from the hierarchy of a place
+// near the coordinate, only polygons down to county are stored. The place
+// has to lie in the smallest area found by polygon.
+func (a *AdminAreas) addLocalLevels(hierarchy []AdminLevel) {
+	if len(a.Hierarchy) == 0 {
+		return
+	}
+	smallest := a.Hierarchy[len(a.Hierarchy)-1]
+	if smallest.Source == "osm" {
+		return
+	}
+	county := mapping.GetAdminLevel("county")
+
+	for i, level := range hierarchy {
+		if level.Placetype != smallest.Placetype || level.ID != smallest.ID {
+			continue
+		}
+		for _, local := range hierarchy[i+1:] {
+			if mapping.GetAdminLevel(local.Placetype) > county {
+				a.Hierarchy = append(a.Hierarchy, local)
+			}
+		}
+		return
+	}
+}
+
+// CountryResult is a country as returned by Countries.
+type CountryResult struct {
+	Code        string     `json:"code"`
+	ISO3        string     `json:"iso3"`
+	Name        string     `json:"name"`
+	Continent   string     `json:"continent"`
+	CallingCode string     `json:"callingCode"`
+	Currency    string     `json:"currency"`
+	BoundingBox [4]float32 `json:"boundingBox"`
+}
+
+// Countries lists all countries with their names in the requested language.
+func (g *Geocoder) Countries(lang string) map[string]interface{} {
+	langKey := g.nSearch.LanguageMap[lang]
+
+	results := make([]CountryResult, 0, len(g.nSearch.Countries.Countries))
+	for _, country := range g.nSearch.Countries.Countries {
+		results = append(results, CountryResult{
+			Code:        country.Code,
+			ISO3:        country.ISO3,
+			Name:        g.nSearch.CountryName(country.Code, langKey),
+			Continent:   country.Continent,
+			CallingCode: country.CallingCode,
+			Currency:    country.Currency,
+			BoundingBox: country.BoundingBox,
+		})
+	}
+
+	return map[string]interface{}{
+		"results": results,
+	}
+}
+
+// BBoxOptions controls which places BBox returns.
+type BBoxOptions struct {
+	Limit   int
+	MinRank int
+	// Grid splits the viewport into Grid x Grid cells and keeps only the
+	// highest ranked place per cell, 0 disables thinning.
+	Grid int
+}
+
+// BBox returns the places inside the rectangle ordered by rank, for example to
+// render map labels for a viewport.
+func (g *Geocoder) BBox(minLat, minLng, maxLat, maxLng float64, lang string, opts BBoxOptions) map[string]interface{} {
+	points := g.KDTree.Range(minLat, minLng, maxLat, maxLng)
+
+	candidates := make([]*structures.Point, 0, len(points))
+	for _, p := range points {
+		if int(g.nSearch.Nodes[p.ID].Rank) >= opts.MinRank {
+			candidates = append(candidates, p)
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		ri := g.nSearch.Nodes[candidates[i].ID].Rank
+		rj := g.nSearch.Nodes[candidates[j].ID].Rank
+		if ri != rj {
+			return ri > rj
 		}
+		return candidates[i].ID < candidates[j].ID
+	})
+
+	if opts.Grid > 0 {
+		width := maxLng - minLng
+		if width < 0 {
+			width += 360
+		}
+		height := maxLat - minLat
+
+		occupied := make(map[[2]int]bool)
+		thinned := candidates[:0]
+		for _, p := range candidates {
+			lng := float64(p.Coordinates[1]) - minLng
+			if lng < 0 {
+				lng += 360
+			}
+			cell := [2]int{
+				gridCell(float64(p.Coordinates[0])-minLat, height, opts.Grid),
+				gridCell(lng, width, opts.Grid),
+			}
+			if occupied[cell] {
+				continue
+			}
+			occupied[cell] = true
+			thinned = append(thinned, p)
+		}
+		candidates = thinned
+	}
+
+	found := len(candidates)
+	if opts.Limit > 0 && len(candidates) > opts.Limit {
+		candidates = candidates[:opts.Limit]
+	}
+
+	results := make([]Node, 0, len(candidates))
+	for _, p := range candidates {
+		results = append(results, g.nSearch.GetNode(p.ID, lang))
 	}
 
 	return map[string]interface{}{
-		"results": []Node{g.nSearch.GetNode(results[0].ID, lang)},
+		"found":   found,
+		"results": results,
 	}
 }
 
-func (g *Geocoder) GetNode(docID int64, lang string) Node {
-	return g.nSearch.GetNode(int64(g.DocumentMap[docID]), lang)
+func gridCell(offset, extent float64, cells int) int {
+	if extent <= 0 {
+		return 0
+	}
+	cell := int(offset / extent * float64(cells))
+	if cell >= cells {
+		cell = cells - 1
+	}
+	return cell
+}
+
+// GetByWikidata returns the places tagged with a Wikidata item ID like "Q64".
+func (g *Geocoder) GetByWikidata(qid string, lang string) map[string]interface{} {
+	results := make([]Node, 0)
+	if number := structures.ParseWikidataID(qid); number != 0 {
+		for _, docID := range g.Wikidata.Lookup(number) {
+			results = append(results, g.nSearch.GetNode(docID, lang))
+		}
+	}
+
+	return map[string]interface{}{
+		"results": sortNodes(results),
+	}
+}
+
+func (g *Geocoder) GetNode(docID int64, lang string) (Node, bool) {
+	return g.GetOSMObject("node", docID, lang)
+}
+
+// GetOSMObject returns the place indexed for an OSM node, way or relation.
+// It returns false if the object isn't indexed.
+func (g *Geocoder) GetOSMObject(osmType string, id int64, lang string) (Node, bool) {
+	docID, ok := g.DocumentMap[structures.DocumentKey(osmType, id)]
+	if !ok {
+		return Node{}, false
+	}
+	return g.nSearch.GetNode(int64(docID), lang), true
 }
 
 func sortNodes(nodes []Node) []Node {
@@ -0,0 +1,939 @@
+package geocoder
+
+import (
+	"hstin/gocoder/mapping"
+	"hstin/gocoder/structures"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/orb"
+)
+
+// testPlace is a document of the test database.
+type testPlace struct {
+	osmType    string
+	id         int64
+	name       string
+	layer      string
+	placeType  string
+	category   string
+	country    string
+	lat, lng   float32
+	rank       uint16
+	population uint32
+	wikidata   string
+	importance float32
+	elevation  int16
+	// codes are transport codes by code type, e.g. {"iata": "BER"}
+	codes map[string]string
+	// hierarchy are the Who's On First areas of the place
+	hierarchy []AdminLevel
+	// parent is the document id + 1 of the settlement of a street
+	parent uint32
+	// street is the geometry of a street for the street index
+	street orb.LineString
+}
+
+// testPlaces are the documents of newTestGeocoder, the document id is the
+// position in this list.
+var testPlaces = []testPlace{
+	{osmType: "node", id: 240109189, name: "Berlin", layer: "locality", placeType: "city", country: "DE", lat: 52.5170, lng: 13.3889, rank: 900, population: 3700000, wikidata: "Q64", importance: 0.9, hierarchy: berlinHierarchy},
+	{osmType: "node", id: 2, name: "Bonn", layer: "locality", placeType: "city", country: "DE", lat: 50.7374, lng: 7.0982, rank: 700, population: 330000, wikidata: "Q586", importance: 0.6},
+	{osmType: "node", id: 3, name: "Ulm", layer: "locality", placeType: "city", country: "DE", lat: 48.3984, lng: 9.9916, rank: 650, population: 126000},
+	{osmType: "node", id: 4, name: "Ber", layer: "locality", placeType: "village", country: "ML", lat: 16.0469, lng: -3.5289, rank: 200},
+	{osmType: "way", id: 5, name: "Flughafen Berlin Brandenburg", layer: "poi", category: "transport.airport", country: "DE", lat: 52.3667, lng: 13.5033, rank: 600, codes: map[string]string{"iata": "BER", "icao": "EDDB"}},
+	{osmType: "way", id: 6, name: "New Ulm Municipal Airport", layer: "poi", category: "transport.airport", country: "US", lat: 44.3196, lng: -94.5023, rank: 150, codes: map[string]string{"iata": "ULM"}},
+	{osmType: "node", id: 7, name: "Adler Apotheke", layer: "poi", category: "health.pharmacy", country: "DE", lat: 50.7355, lng: 7.1010, rank: 100},
+	{osmType: "node", id: 8, name: "Apotheke", layer: "locality", placeType: "hamlet", country: "DE", lat: 50.7000, lng: 7.1500, rank: 100},
+	{osmType: "node", id: 9, name: "Löwen Apotheke", layer: "poi", category: "health.pharmacy", country: "DE", lat: 52.5200, lng: 13.3900, rank: 100},
+	{osmType: "node", id: 10, name: "Mitte", layer: "neighbourhood", placeType: "suburb", country: "DE", lat: 52.5200, lng: 13.4050, rank: 500, hierarchy: append(berlinHierarchy[:4:4], AdminLevel{Placetype: "borough", ID: 421205771, Name: "Mitte"})},
+	{osmType: "relation", id: 11, name: "Tiergarten", layer: "neighbourhood", placeType: "suburb", country: "DE", lat: 52.5145, lng: 13.3501, rank: 450},
+	{osmType: "way", id: 12, name: "Hauptstraße", layer: "street", placeType: "street", country: "DE", lat: 50.7360, lng: 7.1000, rank: 300, parent: 2, street: orb.LineString{{7.0990, 50.7355}, {7.1010, 50.7365}}},
+	{osmType: "way", id: 13, name: "Hauptstraße", layer: "street", placeType: "street", country: "DE", lat: 48.3990, lng: 9.9920, rank: 300, parent: 3, street: orb.LineString{{9.9910, 48.3985}, {9.9930, 48.3995}}},
+	{osmType: "relation", id: 14, name: "North Sea", layer: "natural", placeType: "sea", lat: 55.0000, lng: 3.0000, rank: 900},
+	{osmType: "node", id: 15, name: "Zugspitze", layer: "natural", placeType: "peak", country: "DE", lat: 47.4211, lng: 10.9853, rank: 500, elevation: 2962},
+}
+
+var berlinHierarchy = []AdminLevel{
+	{Placetype: "country", ID: 85633111, Name: "Germany"},
+	{Placetype: "region", ID: 85682523, Name: "Berlin"},
+	{Placetype: "county", ID: 102063261, Name: "Berlin"},
+	{Placetype: "locality", ID: 101748799, Name: "Berlin"},
+}
+
+// newTestGeocoder returns a geocoder with the test places in memory, like
+// NewGeocoder loads them from a database.
+func newTestGeocoder(t *testing.T) *Geocoder {
+	t.Helper()
+
+	mapping.SetCategoryKeywords(mapping.DefaultCategories)
+
+	names, err := structures.NewNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := func(strings ...string) uint64 {
+		offset, err := names.Store(strings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return uint64(offset)
+	}
+	// Hierarchy levels are stored as placetype, id, default name and the
+	// english name
+	storeHierarchy := func(levels []AdminLevel) uint64 {
+		hierarchy := make([]string, 0, len(levels)*4)
+		for _, level := range levels {
+			hierarchy = append(hierarchy, level.Placetype, strconv.FormatInt(level.ID, 10), level.Name, level.Name)
+		}
+		return store(hierarchy...)
+	}
+
+	nodes := make([]structures.Node, len(testPlaces))
+	points := make([]*structures.Point, len(testPlaces))
+	documentMap := make(structures.DocumentMap, len(testPlaces))
+	trie := structures.NewTrie()
+	index := structures.NewIndex()
+	codes := structures.NewCodeIndex()
+	wikidata := structures.NewWikidataIndex()
+	streets := structures.NewStreetIndex()
+	for i, place := range testPlaces {
+		docID := int64(i)
+		center := [2]float32{place.lat, place.lng}
+		nodes[i] = structures.Node{
+			ID:              place.id,
+			NameOffset:      store(place.name),
+			RegionOffset:    store("", ""),
+			HierarchyOffset: storeHierarchy(place.hierarchy),
+			Population:      place.population,
+			Rank:            place.rank,
+			Country:         uint8(mapping.GetCountryNumber(place.country)),
+			Layer:           uint8(mapping.AddLayer(place.layer)),
+			PlaceType:       uint8(mapping.AddPlaceType(place.placeType)),
+			OSMType:         uint8(mapping.GetOSMTypeNumber(place.osmType)),
+			Center:          center,
+			BoundingBox:     [4]float32{place.lat - 0.05, place.lng - 0.05, place.lat + 0.05, place.lng + 0.05},
+			Category:        uint16(mapping.AddCategory(place.category)),
+			Wikidata:        structures.ParseWikidataID(place.wikidata),
+			Importance:      place.importance,
+			Elevation:       place.elevation,
+			Parent:          place.parent,
+		}
+		if place.street != nil {
+			streets.Add(structures.StreetGeometry{DocID: docID, Geometry: orb.MultiLineString{place.street}})
+		}
+		points[i] = structures.NewPoint(docID, center)
+		documentMap[structures.DocumentKey(place.osmType, place.id)] = int32(i)
+		trie.Insert(docID, strings.ToLower(place.name))
+		index.AddDocument(docID, strings.ToLower(place.name))
+		wikidata.Add(nodes[i].Wikidata, docID)
+		for codeType, code := range place.codes {
+			codes.Add(structures.Code{Type: codeType, Code: mapping.NormalizeCode(code), DocID: docID})
+		}
+	}
+	index.Optimize()
+	codes.Optimize()
+	wikidata.Optimize()
+
+	postcodes := structures.NewPostcodeIndex()
+	return &Geocoder{
+		DocumentMap: documentMap,
+		cache:       make(map[string]structures.CacheEntry),
+		nSearch: &NodesSearch{
+			Nodes:       nodes,
+			Strings:     StringSearcher{stringData: names.Bytes()},
+			LanguageMap: map[string]int{"en": 0},
+			Postcodes:   postcodes,
+			Codes:       codes,
+		},
+		Trie:           trie,
+		Index:          index,
+		KDTree:         structures.New(points),
+		Admin:          structures.NewAdminIndex(),
+		Wikidata:       wikidata,
+		Metadata:       &structures.Metadata{},
+		Streets:        streets,
+		Addresses:      structures.NewAddressIndex(),
+		Interpolations: structures.NewInterpolationIndex(),
+		Postcodes:      postcodes,
+		Codes:          codes,
+		Natural:        structures.NewNaturalIndex(),
+	}
+}
+
+// searchNames returns the names of the results of a search response.
+func searchNames(response map[string]interface{}) []string {
+	results := response["results"].([]Node)
+	out := make([]string, len(results))
+	for i, node := range results {
+		out[i] = node.Name
+	}
+	return out
+}
+
+func TestGetOSMObject(t *testing.T) {
+	g := newTestGeocoder(t)
+
+	tests := []struct {
+		osmType string
+		id      int64
+		want    string
+		found   bool
+	}{
+		{"node", 240109189, "Berlin", true},
+		{"relation", 11, "Tiergarten", true},
+		{"way", 5, "Flughafen Berlin Brandenburg", true},
+		// Ids of other types and unknown ids must not return document 0
+		{"way", 240109189, "", false},
+		{"relation", 999, "", false},
+		{"node", 11, "", false},
+	}
+	for _, test := range tests {
+		node, ok := g.GetOSMObject(test.osmType, test.id, "en")
+		if ok != test.found || node.Name != test.want {
+			t.Errorf("%s %d: got %q, %v, want %q, %v", test.osmType, test.id, node.Name, ok, test.want, test.found)
+		}
+	}
+}
+
+func TestSearchCodes(t *testing.T) {
+	g := newTestGeocoder(t)
+
+	tests := []struct {
+		query string
+		first string
+	}{
+		{"BER", "Flughafen Berlin Brandenburg"},
+		{"ber", "Flughafen Berlin Brandenburg"},
+		{"EDDB", "Flughafen Berlin Brandenburg"},
+		// The facility with the code comes before the place of that name
+		{"ULM", "New Ulm Municipal Airport"},
+		{"ulm", "New Ulm Municipal Airport"},
+		{"Ulm", "New Ulm Municipal Airport"},
+		{"Bonn", "Bonn"},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			response, _ := g.Search(test.query, "en", SearchOptions{Explain: true})
+			names := searchNames(response)
+			if len(names) == 0 || names[0] != test.first {
+				t.Fatalf("got %v, want %q first", names, test.first)
+			}
+		})
+	}
+
+	// The place of the same name follows the facility
+	response, _ := g.Search("ULM", "en", SearchOptions{})
+	if names := searchNames(response); len(names) < 2 || names[1] != "Ulm" {
+		t.Errorf("got %v, want Ulm second", names)
+	}
+
+	// Results carry the codes of the facility
+	codes := []struct {
+		docID int64
+		want  map[string]string
+	}{
+		{4, map[string]string{"iata": "BER", "icao": "EDDB"}},
+		{5, map[string]string{"iata": "ULM"}},
+		{1, nil},
+	}
+	for _, test := range codes {
+		if node := g.nSearch.GetNode(test.docID, "en"); !reflect.DeepEqual(node.Codes, test.want) {
+			t.Errorf("%s: codes %v, want %v", node.Name, node.Codes, test.want)
+		}
+	}
+}
+
+func TestSearchCategories(t *testing.T) {
+	g := newTestGeocoder(t)
+
+	tests := []struct {
+		name       string
+		query      string
+		categories []string
+		want       []string
+		category   bool
+	}{
+		{"near word", "pharmacy in Bonn", nil, []string{"Adler Apotheke"}, true},
+		{"other place", "pharmacy near Berlin", nil, []string{"Löwen Apotheke"}, true},
+		{"matching filter", "pharmacy in Bonn", []string{"health"}, []string{"Adler Apotheke"}, true},
+		// Without a near word the query is a name
+		{"comma", "Apotheke, Bonn", nil, []string{"Apotheke"}, false},
+		// With no category left the query is searched by name
+		{"filtered out", "pharmacy in Bonn", []string{"food"}, []string{}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, _ := g.Search(test.query, "en", SearchOptions{Explain: true, Categories: test.categories})
+			results := response["results"].([]Node)
+			for _, node := range results {
+				if isCategory := node.Explain != nil && node.Explain.Match == MatchCategory; isCategory != test.category {
+					t.Fatalf("%s: category match %v, want %v", node.Name, isCategory, test.category)
+				}
+			}
+			if test.want != nil && !reflect.DeepEqual(searchNames(response), test.want) {
+				t.Errorf("got %v, want %v", searchNames(response), test.want)
+			}
+		})
+	}
+}
+
+// reverseNames returns the names of the results of a reverse response.
+func reverseNames(response map[string]interface{}) []string {
+	results := response["results"].([]ReverseResult)
+	out := make([]string, len(results))
+	for i, result := range results {
+		out[i] = result.Name
+	}
+	return out
+}
+
+func TestReverse(t *testing.T) {
+	g := newTestGeocoder(t)
+
+	tests := []struct {
+		name string
+		opts ReverseOptions
+		want []string
+	}{
+		{"nearest", ReverseOptions{}, []string{"Berlin"}},
+		{"k", ReverseOptions{K: 3}, []string{"Berlin", "Mitte", "Tiergarten"}},
+		{"radius", ReverseOptions{Radius: 2000}, []string{"Berlin", "Mitte"}},
+		{"k within radius", ReverseOptions{K: 1, Radius: 2000}, []string{"Berlin"}},
+		{"nothing within radius", ReverseOptions{Radius: 500, Layers: []string{"neighbourhood"}}, []string{}},
+		{"min population", ReverseOptions{K: 3, MinPopulation: 300000}, []string{"Berlin", "Bonn"}},
+		{"layers", ReverseOptions{K: 2, Layers: []string{"neighbourhood"}}, []string{"Mitte", "Tiergarten"}},
+		// POIs are only returned if asked for
+		{"poi layer", ReverseOptions{K: 1, Layers: []string{"poi"}}, []string{"Löwen Apotheke"}},
+		{"unknown layer", ReverseOptions{K: 3, Layers: []string{"planet"}}, []string{}},
+		{"huge k", ReverseOptions{K: 2000000000}, []string{"Berlin", "Mitte", "Tiergarten", "Apotheke", "Hauptstraße", "Bonn", "Hauptstraße", "Ulm", "Ber"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := g.Reverse(52.5170, 13.3889, "en", test.opts)
+			if got := reverseNames(response); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	// Results carry their distance and bearing from the query point
+	results := g.Reverse(52.5170, 13.3889, "en", ReverseOptions{K: 2})["results"].([]ReverseResult)
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if results[0].Distance > 1 {
+		t.Errorf("Berlin is %.0f m away, want 0", results[0].Distance)
+	}
+	if results[1].Distance < 1000 || results[1].Distance > 1200 {
+		t.Errorf("Mitte is %.0f m away, want about 1100", results[1].Distance)
+	}
+	if results[1].Bearing < 70 || results[1].Bearing > 90 {
+		t.Errorf("Mitte has bearing %.0f, want east-northeast", results[1].Bearing)
+	}
+}
+
+func TestBBox(t *testing.T) {
+	g := newTestGeocoder(t)
+
+	tests := []struct {
+		name  string
+		opts  BBoxOptions
+		want  []string
+		found int
+	}{
+		{"by rank", BBoxOptions{}, []string{"Berlin", "Flughafen Berlin Brandenburg", "Mitte", "Tiergarten", "Löwen Apotheke"}, 5},
+		{"min rank", BBoxOptions{MinRank: 500}, []string{"Berlin", "Flughafen Berlin Brandenburg", "Mitte"}, 3},
+		{"limit", BBoxOptions{Limit: 2}, []string{"Berlin", "Flughafen Berlin Brandenburg"}, 5},
+		// The places of the city center share the cell of Berlin
+		{"grid", BBoxOptions{Grid: 2}, []string{"Berlin", "Flughafen Berlin Brandenburg"}, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := g.BBox(52.3, 13.3, 52.6, 13.6, "en", test.opts)
+			if got := searchNames(response); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+			if found := response["found"].(int); found != test.found {
+				t.Errorf("found %d, want %d", found, test.found)
+			}
+		})
+	}
+}
+
+func TestAdminAreas(t *testing.T) {
+	g := newTestGeocoder(t)
+
+	rectangle := func(minLng, minLat, maxLng, maxLat float64) orb.MultiPolygon {
+		return orb.MultiPolygon{{{
+			{minLng, minLat}, {maxLng, minLat}, {maxLng, maxLat}, {minLng, maxLat}, {minLng, minLat},
+		}}}
+	}
+	g.Admin.Add(structures.AdminPolygon{ID: 85633111, Placetype: "country", Country: "DE", Source: "wof", Names: []string{"Germany"}, Geometry: rectangle(7, 47.5625, 8, 48)})
+	g.Admin.Add(structures.AdminPolygon{ID: 85633051, Placetype: "country", Country: "CH", Source: "wof", Names: []string{"Switzerland"}, Geometry: rectangle(7, 47, 8, 47.5625)})
+	// The OSM relation of a level with a Who's On First area comes second
+	g.Admin.Add(structures.AdminPolygon{ID: 1690227, Placetype: "region", Country: "CH", Source: "osm", Names: []string{"Basel-Stadt (OSM)"}, Geometry: rectangle(7.5, 47.5, 7.75, 47.5625)})
+	g.Admin.Add(structures.AdminPolygon{ID: 85682229, Placetype: "region", Country: "CH", Source: "wof", Names: []string{"Basel-Stadt"}, Geometry: rectangle(7.5, 47.5, 7.75, 47.5625)})
+	g.Admin.Add(structures.AdminPolygon{ID: 1686006, Placetype: "county", Country: "CH", Source: "osm", Names: []string{"Basel"}, Geometry: rectangle(7.5, 47.5, 7.75, 47.5625)})
+
+	tests := []struct {
+		name     string
+		lat, lng float64
+		country  string
+		region   string
+		county   string
+		levels   []AdminLevel
+	}{
+		{
+			// Basel is next to the German border
+			name: "basel", lat: 47.55, lng: 7.59,
+			country: "CH", region: "Basel-Stadt", county: "Basel",
+			levels: []AdminLevel{
+				{Placetype: "country", ID: 85633051, Name: "Switzerland", Source: "wof"},
+				{Placetype: "region", ID: 85682229, Name: "Basel-Stadt", Source: "wof"},
+				{Placetype: "county", ID: 1686006, Name: "Basel", Source: "osm"},
+			},
+		},
+		{
+			name: "lörrach", lat: 47.61, lng: 7.66,
+			country: "DE",
+			levels:  []AdminLevel{{Placetype: "country", ID: 85633111, Name: "Germany", Source: "wof"}},
+		},
+		{name: "outside", lat: 46, lng: 7.5, levels: []AdminLevel{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			areas := g.AdminAreas(test.lat, test.lng, "en")
+			if areas.Country != test.country || areas.Region != test.region || areas.County != test.county {
+				t.Errorf("got %q/%q/%q, want %q/%q/%q", areas.Country, areas.Region, areas.County, test.country, test.region, test.county)
+			}
+			if !reflect.DeepEqual(areas.Hierarchy, test.levels) {
+				t.Errorf("hierarchy %+v, want %+v", areas.Hierarchy, test.levels)
+			}
+		})
+	}
+}
+
+func TestHierarchy(t *testing.T) {
+	g := newTestGeocoder(t)
+
+	mitte := append(berlinHierarchy[:4:4], AdminLevel{Placetype: "borough", ID: 421205771, Name: "Mitte"})
+	if got := g.nSearch.GetNode(9, "en").Hierarchy; !reflect.DeepEqual(got, mitte) {
+		t.Errorf("Mitte: got %+v, want %+v", got, mitte)
+	}
+	if got := g.nSearch.GetNode(1, "en").Hierarchy; len(got) != 0 {
+		t.Errorf("Bonn: got %+v, want none", got)
+	}
+
+	wof := func(levels ...AdminLevel) []AdminLevel {
+		out := make([]AdminLevel, len(levels))
+		for i, level := range levels {
+			level.Source = "wof"
+			out[i] = level
+		}
+		return out
+	}
+
+	tests := []struct {
+		name  string
+		areas []AdminLevel
+		want  []AdminLevel
+	}{
+		{
+			// Only polygons down to county are stored, the levels below come
+			// from the place
+			name:  "below county",
+			areas: wof(berlinHierarchy[:3]...),
+			want:  append(wof(berlinHierarchy[:3]...), mitte[3:]...),
+		},
+		{
+			name:  "other county",
+			areas: wof(berlinHierarchy[0], AdminLevel{Placetype: "county", ID: 102063262, Name: "Potsdam"}),
+			want:  wof(berlinHierarchy[0], AdminLevel{Placetype: "county", ID: 102063262, Name: "Potsdam"}),
+		},
+		{
+			name:  "osm relation",
+			areas: []AdminLevel{{Placetype: "county", ID: 62422, Name: "Berlin", Source: "osm"}},
+			want:  []AdminLevel{{Placetype: "county", ID: 62422, Name: "Berlin", Source: "osm"}},
+		},
+		{name: "no areas", areas: []AdminLevel{}, want: []AdminLevel{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			areas := AdminAreas{Hierarchy: append([]AdminLevel{}, test.areas...)}
+			areas.addLocalLevels(mitte)
+			if !reflect.DeepEqual(areas.Hierarchy, test.want) {
+				t.Errorf("got %+v, want %+v", areas.Hierarchy, test.want)
+			}
+		})
+	}
+}
+
+func TestGetByWikidata(t *testing.T) {
+	g := newTestGeocoder(t)
+
+	tests := []struct {
+		qid  string
+		want []string
+	}{
+		{"Q64", []string{"Berlin"}},
+		{"q586", []string{"Bonn"}},
+		{"Q1", []string{}},
+		{"Berlin", []string{}},
+	}
+	for _, test := range tests {
+		if got := searchNames(g.GetByWikidata(test.qid, "en")); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.qid, got, test.want)
+		}
+	}
+}
+
+func TestGetNodeTypes(t *testing.T) {
+	g := newTestGeocoder(t)
+
+	tests := []struct {
+		docID     int64
+		placeType string
+		osmType   string
+		wikidata  string
+	}{
+		{0, "city", "node", "Q64"},
+		{4, "", "way", ""},
+		{10, "suburb", "relation", ""},
+	}
+	for _, test := range tests {
+		node := g.nSearch.GetNode(test.docID, "en")
+		if node.PlaceType != test.placeType || node.OSMType != test.osmType || node.Wikidata != test.wikidata {
+			t.Errorf("%s: got %q/%q/%q, want %q/%q/%q", node.Name, node.PlaceType, node.OSMType, node.Wikidata, test.placeType, test.osmType, test.wikidata)
+		}
+	}
+}
+
+func TestStreets(t *testing.T) {
+	g := newTestGeocoder(t)
+
+	searches := []struct {
+		query string
+		city  string
+	}{
+		{"Hauptstraße, Bonn", "Bonn"},
+		{"Hauptstraße, Ulm", "Ulm"},
+		{"hauptstrasse, ulm", "Ulm"},
+	}
+	for _, test := range searches {
+		response, _ := g.Search(test.query, "en", SearchOptions{})
+		results := response["results"].([]Node)
+		if len(results) == 0 || results[0].Name != "Hauptstraße" || results[0].City != test.city {
+			t.Errorf("%s: got %+v, want the street in %s first", test.query, results, test.city)
+		}
+	}
+
+	reverses := []struct {
+		name     string
+		lat, lng float64
+		opts     ReverseOptions
+		city     string
+	}{
+		{"on the street", 50.7360, 7.1000, ReverseOptions{}, "Bonn"},
+		{"next to the street", 48.3992, 9.9915, ReverseOptions{}, "Ulm"},
+		{"too far", 50.7400, 7.1000, ReverseOptions{}, ""},
+		{"larger radius", 50.7400, 7.1000, ReverseOptions{StreetRadius: 1000}, "Bonn"},
+	}
+	for _, test := range reverses {
+		street, _ := g.Reverse(test.lat, test.lng, "en", test.opts)["street"].(*ReverseResult)
+		if test.city == "" {
+			if street != nil {
+				t.Errorf("%s: got %s in %s, want no street", test.name, street.Name, street.City)
+			}
+			continue
+		}
+		if street == nil || street.Name != "Hauptstraße" || street.City != test.city {
+			t.Errorf("%s: got %+v, want the street in %s", test.name, street, test.city)
+		}
+	}
+}
+
+func TestAddresses(t *testing.T) {
+	g := newTestGeocoder(t)
+
+	way := uint8(mapping.GetOSMTypeNumber("way"))
+	g.Addresses.Add(structures.Address{Parent: 11, HouseNumber: "5", Postcode: "53111", OSMType: way, ID: 100, Lat: 50.7358, Lng: 7.0995})
+	g.Addresses.Add(structures.Address{Parent: 11, HouseNumber: "7 A", OSMType: way, ID: 101, Lat: 50.7362, Lng: 7.1004})
+	g.Addresses.Add(structures.Address{Parent: 12, HouseNumber: "5", OSMType: way, ID: 102, Lat: 48.3987, Lng: 9.9912})
+	// Addresses without addr:street belong to their place
+	g.Addresses.Add(structures.Address{Parent: 7, HouseNumber: "3", OSMType: way, ID: 103, Lat: 50.7001, Lng: 7.1501})
+	g.Addresses.Optimize()
+
+	searches := []struct {
+		query string
+		id    int64
+		name  string
+		city  string
+	}{
+		{"Hauptstraße 5, Bonn", 100, "Hauptstraße 5", "Bonn"},
+		{"Hauptstraße 5, Ulm", 102, "Hauptstraße 5", "Ulm"},
+		{"5 Hauptstraße, Ulm", 102, "Hauptstraße 5", "Ulm"},
+		{"Hauptstraße 7a, Bonn", 101, "Hauptstraße 7 A", "Bonn"},
+		{"Apotheke 3", 103, "Apotheke 3", "Apotheke"},
+		// Unknown house numbers return the street
+		{"Hauptstraße 99, Bonn", 12, "Hauptstraße", "Bonn"},
+	}
+	for _, test := range searches {
+		t.Run(test.query, func(t *testing.T) {
+			response, _ := g.Search(test.query, "en", SearchOptions{})
+			results := response["results"].([]Node)
+			if len(results) == 0 {
+				t.Fatal("no results")
+			}
+			if got := results[0]; got.ID != test.id || got.Name != test.name || got.City != test.city {
+				t.Errorf("got %d %q in %q, want %d %q in %q", got.ID, got.Name, got.City, test.id, test.name, test.city)
+			}
+		})
+	}
+
+	reverses := []struct {
+		name     string
+		lat, lng float64
+		id       int64
+	}{
+		{"next to the house", 50.7358, 7.0996, 100},
+		{"other house", 50.7362, 7.1003, 101},
+		{"too far", 50.7400, 7.1000, 0},
+	}
+	for _, test := range reverses {
+		address, _ := g.Reverse(test.lat, test.lng, "en", ReverseOptions{})["address"].(*ReverseResult)
+		if test.id == 0 {
+			if address != nil {
+				t.Errorf("%s: got %q, want no address", test.name, address.Name)
+			}
+			continue
+		}
+		if address == nil || address.ID != test.id || address.Layer != "address" {
+			t.Errorf("%s: got %+v, want address %d", test.name, address, test.id)
+		}
+	}
+}
+
+func TestInterpolations(t *testing.T) {
+	g := newTestGeocoder(t)
+
+	way := uint8(mapping.GetOSMTypeNumber("way"))
+	g.Addresses.Add(structures.Address{Parent: 11, HouseNumber: "5", OSMType: way, ID: 100, Lat: 50.7358, Lng: 7.0995})
+	g.Addresses.Add(structures.Address{Parent: 11, HouseNumber: "7 A", OSMType: way, ID: 101, Lat: 50.7362, Lng: 7.1004})
+	g.Addresses.Optimize()
+	g.Interpolations.Add(structures.Interpolation{Parent: 11, Start: 1, End: 21, Step: 2, Postcode: "53111", ID: 200, Line: orb.LineString{{7.0990, 50.7355}, {7.1010, 50.7365}}})
+	g.Interpolations.Optimize()
+
+	searches := []struct {
+		query        string
+		id           int64
+		houseNumber  string
+		interpolated bool
+	}{
+		{"Hauptstraße 9, Bonn", 200, "9", true},
+		{"Hauptstraße 21, Bonn", 200, "21", true},
+		// Mapped addresses are preferred over the interpolation
+		{"Hauptstraße 5, Bonn", 100, "5", false},
+		// Even numbers are not on the line
+		{"Hauptstraße 4, Bonn", 12, "", false},
+	}
+	for _, test := range searches {
+		t.Run(test.query, func(t *testing.T) {
+			response, _ := g.Search(test.query, "en", SearchOptions{})
+			results := response["results"].([]Node)
+			if len(results) == 0 {
+				t.Fatal("no results")
+			}
+			got := results[0]
+			if got.ID != test.id || got.HouseNumber != test.houseNumber || got.Interpolated != test.interpolated {
+				t.Errorf("got %d %q interpolated %v, want %d %q interpolated %v", got.ID, got.HouseNumber, got.Interpolated, test.id, test.houseNumber, test.interpolated)
+			}
+			if got.Interpolated && (got.Postcode != "53111" || got.Accuracy <= 0) {
+				t.Errorf("postcode %q accuracy %v", got.Postcode, got.Accuracy)
+			}
+		})
+	}
+
+	reverses := []struct {
+		name         string
+		lat, lng     float64
+		id           int64
+		houseNumber  string
+		interpolated bool
+	}{
+		{"on a mapped house", 50.7358, 7.0995, 100, "5", false},
+		{"on the line", 50.7364, 7.1008, 200, "19", true},
+	}
+	for _, test := range reverses {
+		address, _ := g.Reverse(test.lat, test.lng, "en", ReverseOptions{})["address"].(*ReverseResult)
+		if address == nil || address.ID != test.id || address.HouseNumber != test.houseNumber || address.Interpolated != test.interpolated {
+			t.Errorf("%s: got %+v, want %d %q interpolated %v", test.name, address, test.id, test.houseNumber, test.interpolated)
+		}
+	}
+}
+
+func TestPostcodes(t *testing.T) {
+	g := newTestGeocoder(t)
+
+	g.Postcodes.Add(structures.Postcode{Code: "53111", Country: "DE", Source: "osm", OSMType: uint8(mapping.GetOSMTypeNumber("relation")), ID: 300, Center: [2]float32{50.735, 7.1}, Geometry: orb.MultiPolygon{{{{7.08, 50.72}, {7.12, 50.72}, {7.12, 50.75}, {7.08, 50.75}, {7.08, 50.72}}}}})
+	g.Postcodes.Add(structures.Postcode{Code: "10115", Country: "DE", Source: "addresses", Center: [2]float32{52.532, 13.385}})
+	g.Postcodes.Add(structures.Postcode{Code: "10115", Country: "US", Source: "addresses", Center: [2]float32{40.75, -73.5}})
+	g.Postcodes.Add(structures.Postcode{Code: "SW1A 1AA", Country: "GB", Source: "addresses", Center: [2]float32{51.501, -0.141}})
+	g.Postcodes.Optimize()
+
+	searches := []struct {
+		name  string
+		query string
+		opts  SearchOptions
+		want  []string
+	}{
+		{"postcode", "53111", SearchOptions{}, []string{"53111 (DE)"}},
+		{"without space", "sw1a1aa", SearchOptions{}, []string{"SW1A 1AA (GB)"}},
+		{"several countries", "10115", SearchOptions{}, []string{"10115 (DE)", "10115 (US)"}},
+		{"countries", "10115", SearchOptions{Countries: []string{"US"}}, []string{"10115 (US)"}},
+		{"layers", "10115", SearchOptions{Layers: []string{"locality"}}, []string{}},
+		// Queries in the wrong format for the country don't return it
+		{"wrong format", "5311", SearchOptions{}, []string{}},
+	}
+	for _, test := range searches {
+		t.Run(test.name, func(t *testing.T) {
+			response, _ := g.Search(test.query, "en", test.opts)
+			got := make([]string, 0)
+			for _, node := range response["results"].([]Node) {
+				if node.Layer == "postcode" {
+					got = append(got, node.Name+" ("+node.Country+")")
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	reverses := []struct {
+		name     string
+		lat, lng float64
+		want     string
+		distance bool
+	}{
+		{"inside", 50.7374, 7.0982, "53111", false},
+		{"near the centre", 52.5300, 13.3850, "10115", true},
+		{"too far", 48.3984, 9.9916, "", false},
+	}
+	for _, test := range reverses {
+		postcode, _ := g.Reverse(test.lat, test.lng, "en", ReverseOptions{})["postcode"].(*ReverseResult)
+		if test.want == "" {
+			if postcode != nil {
+				t.Errorf("%s: got %q, want none", test.name, postcode.Name)
+			}
+			continue
+		}
+		if postcode == nil || postcode.Name != test.want || postcode.Layer != "postcode" || (postcode.Distance > 0) != test.distance {
+			t.Errorf("%s: got %+v, want %q", test.name, postcode, test.want)
+		}
+	}
+
+	// Places carry the postcode they were given during generation
+	positions := g.Postcodes.Lookup("53111")
+	g.nSearch.Nodes[1].Postcode = uint32(positions[0]) + 1
+	if node := g.nSearch.GetNode(1, "en"); node.Postcode != "53111" {
+		t.Errorf("Bonn has postcode %q, want %q", node.Postcode, "53111")
+	}
+	if node := g.nSearch.GetNode(2, "en"); node.Postcode != "" {
+		t.Errorf("Ulm has postcode %q, want none", node.Postcode)
+	}
+}
+
+func TestNatural(t *testing.T) {
+	g := newTestGeocoder(t)
+
+	rectangle := func(minLng, minLat, maxLng, maxLat float64) orb.MultiPolygon {
+		return orb.MultiPolygon{{{
+			{minLng, minLat}, {maxLng, minLat}, {maxLng, maxLat}, {minLng, maxLat}, {minLng, minLat},
+		}}}
+	}
+	g.Natural.Add(structures.NaturalArea{DocID: 13, Geometry: rectangle(-2, 51, 9, 58)})
+	g.Admin.Add(structures.AdminPolygon{ID: 85633111, Placetype: "country", Country: "DE", Source: "wof", Names: []string{"Germany"}, Geometry: rectangle(6, 47, 15, 54)})
+
+	reverses := []struct {
+		name    string
+		lat     float64
+		lng     float64
+		opts    ReverseOptions
+		want    []string
+		natural string
+	}{
+		// Offshore points return the sea instead of the nearest places
+		{"offshore", 55, 3, ReverseOptions{K: 3}, []string{"North Sea"}, "North Sea"},
+		{"offshore with radius", 51.05, 5.95, ReverseOptions{K: 2, Radius: 100000}, []string{"North Sea", "Bonn"}, "North Sea"},
+		// Inside the sea polygon but in a country
+		{"coast", 53.5, 7.5, ReverseOptions{K: 1}, []string{"Bonn"}, "North Sea"},
+		{"on land", 50.74, 7.1, ReverseOptions{K: 1}, []string{"Bonn"}, ""},
+		// Natural features are only returned if asked for
+		{"layer", 47.5, 11, ReverseOptions{K: 1, Layers: []string{"natural"}}, []string{"Zugspitze"}, ""},
+		{"layer offshore", 55, 3, ReverseOptions{K: 1, Layers: []string{"natural"}}, []string{"North Sea"}, "North Sea"},
+	}
+	for _, test := range reverses {
+		t.Run(test.name, func(t *testing.T) {
+			response := g.Reverse(test.lat, test.lng, "en", test.opts)
+			if got := reverseNames(response); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+			natural := ""
+			if area := response["natural"].(*ReverseResult); area != nil {
+				natural = area.Name
+			}
+			if natural != test.natural {
+				t.Errorf("natural %q, want %q", natural, test.natural)
+			}
+		})
+	}
+
+	searches := []struct {
+		query  string
+		layers []string
+		want   string
+	}{
+		{"Zugspitze", nil, "Zugspitze"},
+		{"Zugspitze", []string{"natural"}, "Zugspitze"},
+		{"North Sea", []string{"natural"}, "North Sea"},
+		{"Zugspitze", []string{"locality"}, ""},
+	}
+	for _, test := range searches {
+		response, _ := g.Search(test.query, "en", SearchOptions{Layers: test.layers})
+		names := searchNames(response)
+		if test.want == "" {
+			if len(names) > 0 {
+				t.Errorf("%s in %v: got %v, want none", test.query, test.layers, names)
+			}
+			continue
+		}
+		if len(names) == 0 || names[0] != test.want {
+			t.Errorf("%s in %v: got %v, want %q first", test.query, test.layers, names, test.want)
+		}
+	}
+
+	if node := g.nSearch.GetNode(14, "en"); node.Elevation != 2962 || node.Layer != "natural" || node.PlaceType != "peak" {
+		t.Errorf("got %+v, want the peak with its elevation", node)
+	}
+}
+
+func TestSubSettlements(t *testing.T) {
+	g := newTestGeocoder(t)
+
+	// Generation links Mitte to Berlin and indexes it with its parent
+	g.nSearch.Nodes[9].Parent = 1
+	g.Trie.Insert(9, "Mitte Berlin")
+
+	tests := []struct {
+		query string
+		name  string
+		city  string
+	}{
+		{"Mitte", "Mitte", "Berlin"},
+		{"Mitte Berlin", "Mitte", "Berlin"},
+		{"Tiergarten", "Tiergarten", ""},
+		{"Berlin", "Berlin", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			response, _ := g.Search(test.query, "en", SearchOptions{})
+			results := response["results"].([]Node)
+			if len(results) == 0 {
+				t.Fatal("no results")
+			}
+			if got := results[0]; got.Name != test.name || got.City != test.city {
+				t.Errorf("got %q in %q, want %q in %q", got.Name, got.City, test.name, test.city)
+			}
+		})
+	}
+
+	results := g.Reverse(52.5201, 13.4049, "en", ReverseOptions{K: 1, Layers: []string{"neighbourhood"}})["results"].([]ReverseResult)
+	if len(results) != 1 || results[0].Name != "Mitte" || results[0].City != "Berlin" {
+		t.Errorf("reverse: got %+v, want Mitte in Berlin", results)
+	}
+}
+
+func TestDisplayName(t *testing.T) {
+	g := newTestGeocoder(t)
+	g.nSearch.Nodes[9].Parent = 1
+	g.Addresses.Add(structures.Address{Parent: 11, HouseNumber: "5", Postcode: "53111", OSMType: uint8(mapping.GetOSMTypeNumber("way")), ID: 100, Lat: 50.7358, Lng: 7.0995})
+	g.Addresses.Optimize()
+
+	nodes := []struct {
+		docID int64
+		short string
+		long  string
+	}{
+		{0, "Berlin", "Berlin"},
+		{9, "Mitte, Berlin", "Mitte, Berlin"},
+		// Streets are formatted as the street of the address
+		{11, "Hauptstraße, Bonn", "Hauptstraße, Bonn"},
+	}
+	for _, test := range nodes {
+		node := g.nSearch.GetNode(test.docID, "en")
+		if node.DisplayName.Short != test.short || node.DisplayName.Long != test.long {
+			t.Errorf("%s: got %+v, want %q, %q", node.Name, node.DisplayName, test.short, test.long)
+		}
+	}
+
+	response, _ := g.Search("Hauptstraße 5, Bonn", "en", SearchOptions{})
+	results := response["results"].([]Node)
+	want := DisplayName{Short: "Hauptstraße 5, Bonn", Long: "Hauptstraße 5, 53111 Bonn"}
+	if len(results) == 0 || results[0].DisplayName != want {
+		t.Errorf("search: got %+v, want %+v", results, want)
+	}
+
+	address, _ := g.Reverse(50.7358, 7.0996, "en", ReverseOptions{})["address"].(*ReverseResult)
+	if address == nil || address.DisplayName != want {
+		t.Errorf("reverse: got %+v, want %+v", address, want)
+	}
+}
+
+func TestCountries(t *testing.T) {
+	g := newTestGeocoder(t)
+
+	// Country names are the default name followed by one name per language.
+	// The test database maps english to the default names, key 0.
+	countries := structures.NewCountryTable()
+	countries.Add(structures.Country{Code: "DE", ISO3: "DEU", Continent: "EU", CallingCode: "+49", Currency: "EUR", BoundingBox: [4]float32{47.27, 5.87, 55.06, 15.04}, Names: []string{"Germany", "Germany (en)"}})
+	countries.Add(structures.Country{Code: "ML", ISO3: "MLI", Continent: "AF", CallingCode: "+223", Currency: "XOF", Names: []string{"Mali", "Mali (en)"}})
+	g.nSearch.Countries = countries
+
+	names := []struct {
+		code    string
+		langKey int
+		want    string
+	}{
+		{"DE", 0, "Germany"},
+		{"DE", 1, "Germany (en)"},
+		{"ML", 1, "Mali (en)"},
+		{"DE", 2, ""},
+		{"US", 0, ""},
+	}
+	for _, test := range names {
+		if got := g.nSearch.CountryName(test.code, test.langKey); got != test.want {
+			t.Errorf("CountryName(%q, %d) = %q, want %q", test.code, test.langKey, got, test.want)
+		}
+	}
+
+	// Results carry the country name in the requested language
+	if node := g.nSearch.GetNode(1, "en"); node.Country != "DE" || node.CountryName != "Germany" || node.DisplayName.Short != "Bonn, Germany" {
+		t.Errorf("got %q %q %+v, want Bonn in Germany", node.Country, node.CountryName, node.DisplayName)
+	}
+	if node := g.nSearch.GetNode(5, "en"); node.Country != "US" || node.CountryName != "" {
+		t.Errorf("got %q %q, want no name for a country without an entry", node.Country, node.CountryName)
+	}
+
+	want := []CountryResult{
+		{Code: "DE", ISO3: "DEU", Name: "Germany", Continent: "EU", CallingCode: "+49", Currency: "EUR", BoundingBox: [4]float32{47.27, 5.87, 55.06, 15.04}},
+		{Code: "ML", ISO3: "MLI", Name: "Mali", Continent: "AF", CallingCode: "+223", Currency: "XOF"},
+	}
+	if got := g.Countries("en")["results"].([]CountryResult); !reflect.DeepEqual(got, want) {
+		t.Errorf("Countries: got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,49 @@
+package geocoder
+
+// seaPlaceTypes are the natural features that count as open water. Points
+// inside one of them and outside of every country are offshore.
+var seaPlaceTypes = map[string]bool{
+	"ocean":      true,
+	"sea":        true,
+	"marinearea": true,
+}
+
+// naturalAreas returns the natural features whose polygons contain the
+// coordinate, the smallest first. Their distance is 0.
+func (g *Geocoder) naturalAreas(lat, lng float64, lang string) []ReverseResult {
+	areas := g.Natural.Contains(lat, lng)
+	results := make([]ReverseResult, 0, len(areas))
+	for _, area := range areas {
+		results = append(results, ReverseResult{
+			Node: g.nSearch.GetNode(area.DocID, lang),
+		})
+	}
+	return results
+}
+
+// offshoreResults returns the sea containing an offshore coordinate as the
+// only reverse result, followed by the places within the radius if one is
+// given. Without a radius the nearest places could be hundreds of kilometres
+// away, so they are dropped. It returns false if the coordinate is on land
+// or not inside a sea.
+func offshoreResults(natural []ReverseResult, admin AdminAreas, places []ReverseResult, opts ReverseOptions) ([]ReverseResult, bool) {
+	if admin.Country != "" {
+		return nil, false
+	}
+
+	for _, area := range natural {
+		if !seaPlaceTypes[area.PlaceType] {
+			continue
+		}
+
+		results := []ReverseResult{area}
+		if opts.Radius > 0 {
+			results = append(results, places...)
+		}
+		if opts.K > 0 && len(results) > opts.K {
+			results = results[:opts.K]
+		}
+		return results, true
+	}
+	return nil, false
+}
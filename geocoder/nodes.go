@@ -7,22 +7,63 @@ import (
 	"hstin/gocoder/structures"
 	"hstin/gocoder/utils"
 	"os"
+	"strconv"
 	"syscall"
 	"unsafe"
 )
 
 type Node struct {
-	ID          int64      `json:"id"`
-	DocumentID  int64      `json:"-"`
-	Name        string     `json:"name"`
-	Country     string     `json:"country"`
-	Region      string     `json:"region"`
-	SubRegion   string     `json:"subregion"`
-	Coordinates [2]float32 `json:"coordinates"`
-	BoundingBox [4]float32 `json:"boundingBox"`
-	Population  uint32     `json:"population"`
-	Timezone    string     `json:"timezone"`
-	Rank        int        `json:"-"`
+	ID         int64  `json:"id"`
+	DocumentID int64  `json:"-"`
+	Name       string `json:"name"`
+	// DisplayName is the formatted label in the requested language.
+	DisplayName DisplayName `json:"displayName"`
+	Country     string      `json:"country"`
+	CountryName string      `json:"countryName"`
+	City        string      `json:"city"`
+	Street      string      `json:"street,omitempty"`
+	HouseNumber string      `json:"housenumber,omitempty"`
+	Postcode    string      `json:"postcode,omitempty"`
+	Category    string      `json:"category,omitempty"`
+	// Codes are the transport identifiers by type, e.g. "iata": "FRA".
+	Codes map[string]string `json:"codes,omitempty"`
+	// Elevation in metres of peaks and other features with an ele tag.
+	Elevation int `json:"elevation,omitempty"`
+	// Interpolated addresses are positioned on an addr:interpolation line,
+	// Accuracy is the distance in metres to the neighbouring numbers.
+	Interpolated bool          `json:"interpolated,omitempty"`
+	Accuracy     float64       `json:"accuracy,omitempty"`
+	Region       string        `json:"region"`
+	SubRegion    string        `json:"subregion"`
+	Hierarchy    []AdminLevel  `json:"hierarchy"`
+	Coordinates  [2]float32    `json:"coordinates"`
+	Centroid     *[2]float32   `json:"centroid,omitempty"`
+	BoundingBox  [4]float32    `json:"boundingBox"`
+	Population   uint32        `json:"population"`
+	Timezone     string        `json:"timezone"`
+	Layer        string        `json:"layer"`
+	PlaceType    string        `json:"type"`
+	OSMType      string        `json:"osmType"`
+	Wikidata     string        `json:"wikidata"`
+	Importance   float32       `json:"importance"`
+	Rank         int           `json:"-"`
+	Score        float64       `json:"-"`
+	Explain      *ScoreExplain `json:"score,omitempty"`
+	Merged       []int64       `json:"merged,omitempty"`
+
+	// lang is the requested language, used to format the display name
+	lang string
+}
+
+// AdminLevel is one entry of the admin hierarchy of a place, ordered from the
+// largest to the smallest area.
+type AdminLevel struct {
+	Placetype string `json:"placetype"`
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	// Source tells Who's On First areas ("wof") and OSM relations ("osm")
+	// apart in the admin hierarchy of reverse results.
+	Source string `json:"source,omitempty"`
 }
 
 type NodesSearch struct {
@@ -32,6 +73,9 @@ type NodesSearch struct {
 	stringsData []byte
 	file        *os.File
 	LanguageMap map[string]int
+	Countries   *structures.CountryTable
+	Postcodes   *structures.PostcodeIndex
+	Codes       *structures.CodeIndex
 }
 
 type StringSearcher struct {
@@ -149,6 +193,20 @@ func (g *NodesSearch) GetNode(id int64, lang string) Node {
 	region := regionStrings[startIndex]
 	subRegion := regionStrings[startIndex+1]
 
+	// Hierarchy levels are stored as placetype, id, default name and one
+	// name per language.
+	hierarchyStrings := g.Strings.Get(node.HierarchyOffset)
+	stride := len(g.LanguageMap) + 3
+	hierarchy := make([]AdminLevel, 0, len(hierarchyStrings)/stride)
+	for i := 0; i+stride <= len(hierarchyStrings); i += stride {
+		wofID, _ := strconv.ParseInt(hierarchyStrings[i+1], 10, 64)
+		hierarchy = append(hierarchy, AdminLevel{
+			Placetype: hierarchyStrings[i],
+			ID:        wofID,
+			Name:      hierarchyStrings[i+2+langKey],
+		})
+	}
+
 	var country string
 	if int(node.Country) < len(mapping.CountryCodes) {
 		country = mapping.CountryCodes[node.Country]
@@ -156,6 +214,8 @@ func (g *NodesSearch) GetNode(id int64, lang string) Node {
 		country = ""
 	}
 
+	countryName := g.CountryName(country, langKey)
+
 	var timezone string
 	if int(node.Timezone) < len(utils.TimezoneNames) {
 		timezone = utils.TimezoneNames[node.Timezone]
@@ -163,17 +223,68 @@ func (g *NodesSearch) GetNode(id int64, lang string) Node {
 		timezone = "Etc/UTC"
 	}
 
-	return Node{
+	// Streets and sub-settlements store the document id + 1 of the
+	// settlement they belong to.
+	var city string
+	if node.Parent > 0 && int(node.Parent) <= len(g.Nodes) {
+		cityNames := g.Strings.Get(g.Nodes[node.Parent-1].NameOffset)
+		if langKey < len(cityNames) {
+			city = cityNames[langKey]
+		}
+	}
+
+	var postcode string
+	if node.Postcode > 0 && g.Postcodes != nil {
+		if p := g.Postcodes.Get(int(node.Postcode) - 1); p != nil {
+			postcode = p.Code
+		}
+	}
+
+	var centroid *[2]float32
+	if node.Centroid != [2]float32{} {
+		centroid = &node.Centroid
+	}
+
+	result := Node{
 		ID:          node.ID,
 		DocumentID:  id,
 		Name:        name,
 		Country:     country,
+		CountryName: countryName,
+		City:        city,
+		Postcode:    postcode,
+		Category:    mapping.GetCategoryName(int(node.Category)),
+		Codes:       g.nodeCodes(id),
+		Elevation:   int(node.Elevation),
 		Region:      region,
 		SubRegion:   subRegion,
+		Hierarchy:   hierarchy,
 		Coordinates: g.Nodes[id].Center,
+		Centroid:    centroid,
 		BoundingBox: g.Nodes[id].BoundingBox,
 		Population:  g.Nodes[id].Population,
 		Timezone:    timezone,
+		Layer:       mapping.GetLayerName(int(node.Layer)),
+		PlaceType:   mapping.GetPlaceTypeName(int(node.PlaceType)),
+		OSMType:     mapping.GetOSMTypeName(int(node.OSMType)),
+		Wikidata:    structures.FormatWikidataID(node.Wikidata),
+		Importance:  node.Importance,
 		Rank:        int(g.Nodes[id].Rank),
+		lang:        lang,
+	}
+	result.formatDisplayName()
+
+	return result
+}
+
+// CountryName returns the localized name of a country for a language key.
+func (g *NodesSearch) CountryName(code string, langKey int) string {
+	if g.Countries == nil {
+		return ""
+	}
+	country, ok := g.Countries.Get(code)
+	if !ok || langKey >= len(country.Names) {
+		return ""
 	}
+	return country.Names[langKey]
 }
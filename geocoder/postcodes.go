@@ -0,0 +1,113 @@
+package geocoder
+
+import (
+	"hstin/gocoder/geo"
+	"hstin/gocoder/mapping"
+	"strings"
+)
+
+// scorePostcode is added to postcode results of a query in the postcode
+// format of their country, so they rank above places with the same name.
+const scorePostcode = 1000.0
+
+// DefaultPostcodeRadius is the default of ReverseOptions.PostcodeRadius.
+const DefaultPostcodeRadius = 2000.0
+
+// postcodeNode returns the result for the postcode at a position of the
+// postcode index. The admin areas are looked up from the admin polygons.
+func (g *Geocoder) postcodeNode(i int, lang string) Node {
+	postcode := g.Postcodes.Get(i)
+	langKey := g.nSearch.LanguageMap[lang]
+	lat, lng := float64(postcode.Center[0]), float64(postcode.Center[1])
+
+	areas := g.AdminAreas(lat, lng, lang)
+	country := postcode.Country
+	if country == "" {
+		country = areas.Country
+	}
+
+	node := Node{
+		ID:          postcode.ID,
+		DocumentID:  -1,
+		Name:        postcode.Code,
+		Country:     country,
+		CountryName: g.nSearch.CountryName(country, langKey),
+		Region:      areas.Region,
+		SubRegion:   areas.County,
+		Hierarchy:   areas.Hierarchy,
+		Postcode:    postcode.Code,
+		Coordinates: postcode.Center,
+		BoundingBox: postcode.BoundingBox,
+		Layer:       "postcode",
+		PlaceType:   "postcode",
+		OSMType:     mapping.GetOSMTypeName(int(postcode.OSMType)),
+		lang:        lang,
+	}
+	node.formatDisplayName()
+
+	return node
+}
+
+// postcodeResults returns the postcodes matching a query that has the
+// postcode format of their country.
+func (g *Geocoder) postcodeResults(query string, lang string, countries map[string]bool, layers map[string]bool, explain bool) []Node {
+	if len(g.Postcodes.Postcodes) == 0 || (len(layers) > 0 && !layers["postcode"]) {
+		return nil
+	}
+
+	formats := make(map[string]bool)
+	for _, country := range mapping.PostcodeCountries(query) {
+		formats[country] = true
+	}
+
+	results := make([]Node, 0)
+	for _, i := range g.Postcodes.Lookup(query) {
+		postcode := g.Postcodes.Get(i)
+		if len(countries) > 0 && !countries[postcode.Country] {
+			continue
+		}
+		// Countries without a known format accept postcodes as stored
+		if _, known := mapping.PostcodePatterns[postcode.Country]; known && !formats[postcode.Country] {
+			continue
+		}
+
+		node := g.postcodeNode(i, lang)
+		node.Score = scoreExact + scorePostcode
+		if explain {
+			node.Explain = &ScoreExplain{
+				Total:       node.Score,
+				Match:       MatchExact,
+				MatchedName: strings.ToLower(postcode.Code),
+				Text:        scoreExact,
+				Postcode:    scorePostcode,
+			}
+		}
+		results = append(results, node)
+	}
+	return results
+}
+
+// nearestPostcode returns the postcode containing the coordinate, or the one
+// with the nearest centre within radius metres, or nil if there is none.
+func (g *Geocoder) nearestPostcode(lat, lng float64, lang string, radius float64) *ReverseResult {
+	if radius <= 0 {
+		radius = DefaultPostcodeRadius
+	}
+
+	i, distance := g.Postcodes.Find(lat, lng, radius)
+	if i < 0 {
+		return nil
+	}
+
+	node := g.postcodeNode(i, lang)
+	return &ReverseResult{
+		Node:     node,
+		Distance: distance,
+		Bearing: geo.Bearing(
+			lat,
+			lng,
+			float64(node.Coordinates[0]),
+			float64(node.Coordinates[1]),
+		),
+	}
+}
@@ -0,0 +1,293 @@
+package geocoder
+
+import (
+	"hstin/gocoder/geo"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Weights of the query-time score components. The static rank of a place is
+// added unweighted, so these are in the same unit as Node.Rank.
+const (
+	scoreExact       = 1000.0
+	scorePrefix      = 500.0
+	scorePrefixFull  = 300.0 // added on top of scorePrefix, scaled by how much of the name the query covers
+	scoreFuzzy       = 400.0
+	scoreFuzzyEdit   = 150.0 // subtracted per edit
+	scoreLanguage    = 100.0
+	scoreFocus       = 1500.0
+	scoreContext     = 800.0
+	defaultFocusSize = 50.0 // km
+)
+
+// Match types, from best to worst.
+const (
+	MatchExact  = "exact"
+	MatchPrefix = "prefix"
+	MatchFuzzy  = "fuzzy"
+	// MatchCategory is a POI found around the place of a category query,
+	// MatchedName is the name of the place.
+	MatchCategory = "category"
+	// MatchCode is a document whose transport code is the query,
+	// MatchedName is the code and MatchedLang the code type.
+	MatchCode = "code"
+)
+
+// Focus biases the search towards a location. Places within roughly Scale
+// kilometres of the point get the largest boost.
+type Focus struct {
+	Lat   float64
+	Lng   float64
+	Scale float64
+}
+
+// ScoreExplain is the breakdown of the score of a search result. Total is the
+// sum of all other components.
+type ScoreExplain struct {
+	Total       float64  `json:"total"`
+	Match       string   `json:"match"`
+	MatchedName string   `json:"matchedName"`
+	MatchedLang string   `json:"matchedLanguage"`
+	EditDist    int      `json:"editDistance"`
+	Text        float64  `json:"text"`
+	Language    float64  `json:"language"`
+	Rank        float64  `json:"rank"`
+	Distance    *float64 `json:"distance,omitempty"`
+	Focus       float64  `json:"focus"`
+	Context     float64  `json:"context"`
+	HouseNumber float64  `json:"houseNumber,omitempty"`
+	Postcode    float64  `json:"postcode,omitempty"`
+	Code        float64  `json:"code,omitempty"`
+}
+
+// textMatch is the best match of the query against the names of a place.
+type textMatch struct {
+	match    string
+	name     string
+	langKey  int
+	editDist int
+	coverage float64
+}
+
+// matchNames compares the normalized query with all stored names of a place
+// and returns the best match, preferring the requested language on ties.
+// Places found via an alternate name that isn't stored fall back to the match
+// type of the index that found them.
+func matchNames(query string, names []string, fallback string, maxDistance int, langKey int) textMatch {
+	best := textMatch{match: fallback, langKey: -1}
+	if fallback == MatchFuzzy {
+		best.editDist = maxDistance
+	}
+
+	queryLen := utf8.RuneCountInString(query)
+
+	for key, name := range names {
+		normalized := strings.ToLower(strings.TrimSpace(name))
+		if normalized == "" {
+			continue
+		}
+
+		var candidate textMatch
+		switch {
+		case normalized == query:
+			candidate = textMatch{match: MatchExact, coverage: 1}
+		case strings.HasPrefix(normalized, query):
+			candidate = textMatch{
+				match:    MatchPrefix,
+				coverage: float64(queryLen) / float64(utf8.RuneCountInString(normalized)),
+			}
+		default:
+			dist := editDistance(query, normalized)
+			if dist > maxDistance {
+				continue
+			}
+			candidate = textMatch{match: MatchFuzzy, editDist: dist}
+		}
+		candidate.name = name
+		candidate.langKey = key
+
+		if best.langKey < 0 || betterMatch(candidate, best) ||
+			(key == langKey && !betterMatch(best, candidate)) {
+			best = candidate
+		}
+	}
+
+	return best
+}
+
+func betterMatch(a, b textMatch) bool {
+	if matchOrder(a.match) != matchOrder(b.match) {
+		return matchOrder(a.match) < matchOrder(b.match)
+	}
+	if a.match == MatchFuzzy {
+		return a.editDist < b.editDist
+	}
+	return a.coverage > b.coverage
+}
+
+func matchOrder(match string) int {
+	switch match {
+	case MatchExact:
+		return 0
+	case MatchPrefix:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (m textMatch) score() float64 {
+	switch m.match {
+	case MatchExact:
+		return scoreExact
+	case MatchPrefix:
+		return scorePrefix + scorePrefixFull*m.coverage
+	default:
+		return math.Max(0, scoreFuzzy-scoreFuzzyEdit*float64(m.editDist))
+	}
+}
+
+// focusScore decays with the distance to the focus point, halving every
+// Scale kilometres.
+func focusScore(focus *Focus, node Node) (float64, float64) {
+	distance := geo.Haversine(focus.Lat, focus.Lng, float64(node.Coordinates[0]), float64(node.Coordinates[1]))
+	scale := focus.Scale
+	if scale <= 0 {
+		scale = defaultFocusSize
+	}
+	return scoreFocus * math.Exp2(-distance/1000/scale), distance
+}
+
+// scoreNode computes the final score of a search result.
+func (g *Geocoder) scoreNode(node *Node, query string, fallback string, maxDistance int, langKey int, focus *Focus) ScoreExplain {
+	names := g.nSearch.Strings.Get(g.nSearch.Nodes[node.DocumentID].NameOffset)
+	m := matchNames(query, names, fallback, maxDistance, langKey)
+
+	explain := ScoreExplain{
+		Match:       m.match,
+		MatchedName: m.name,
+		EditDist:    m.editDist,
+		Text:        m.score(),
+		Rank:        float64(node.Rank),
+	}
+
+	if m.langKey >= 0 {
+		explain.MatchedLang = g.languageName(m.langKey)
+		if m.langKey == langKey {
+			explain.Language = scoreLanguage
+		}
+	}
+
+	if focus != nil {
+		var distance float64
+		explain.Focus, distance = focusScore(focus, *node)
+		explain.Distance = &distance
+	}
+
+	explain.Total = explain.Text + explain.Language + explain.Rank + explain.Focus
+	return explain
+}
+
+func (g *Geocoder) languageName(langKey int) string {
+	for lang, key := range g.nSearch.LanguageMap {
+		if key == langKey {
+			return lang
+		}
+	}
+	return "name"
+}
+
+func sortByScore(nodes []Node) []Node {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Score != nodes[j].Score {
+			return nodes[i].Score > nodes[j].Score
+		}
+		if nodes[i].Name != nodes[j].Name {
+			return nodes[i].Name < nodes[j].Name
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+
+	return nodes
+}
+
+// editDistance returns the Levenshtein distance between two strings, compared
+// rune by rune.
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	cur := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		cur[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(br)]
+}
+
+// splitContext splits a query at the first comma into the search query and
+// the context parts, e.g. "hauptstraße, mainz" into "hauptstraße" and
+// ["mainz"].
+func splitContext(query string) (string, []string) {
+	parts := strings.Split(query, ",")
+	context := make([]string, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		if part = strings.TrimSpace(part); part != "" {
+			context = append(context, part)
+		}
+	}
+	return strings.TrimSpace(parts[0]), context
+}
+
+// matchContext reports whether every context part is a prefix of the city,
+// an admin area or the country of the node.
+func matchContext(node Node, context []string) bool {
+	names := []string{node.City, node.Region, node.SubRegion, node.CountryName, node.Country}
+	for _, level := range node.Hierarchy {
+		names = append(names, level.Name)
+	}
+
+	for _, part := range context {
+		matched := false
+		for _, name := range names {
+			if name != "" && strings.HasPrefix(strings.ToLower(name), part) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// filterContext drops the results that don't match the context, unless none
+// of them does.
+func filterContext(nodes []Node, context []string) []Node {
+	if len(context) == 0 {
+		return nodes
+	}
+
+	matching := make([]Node, 0, len(nodes))
+	for _, node := range nodes {
+		if matchContext(node, context) {
+			matching = append(matching, node)
+		}
+	}
+	if len(matching) == 0 {
+		return nodes
+	}
+	return matching
+}
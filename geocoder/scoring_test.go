@@ -0,0 +1,144 @@
+package geocoder
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestMatchNames(t *testing.T) {
+	// The names of a place: default name, then one per language
+	names := []string{"München", "Munich", "München", "Munich"}
+
+	tests := []struct {
+		name     string
+		query    string
+		fallback string
+		langKey  int
+		want     textMatch
+	}{
+		{"exact", "münchen", MatchExact, 0, textMatch{match: MatchExact, name: "München", langKey: 0, coverage: 1}},
+		// Ties go to the requested language
+		{"exact in language", "münchen", MatchExact, 2, textMatch{match: MatchExact, name: "München", langKey: 2, coverage: 1}},
+		{"other language", "munich", MatchExact, 0, textMatch{match: MatchExact, name: "Munich", langKey: 1, coverage: 1}},
+		{"prefix", "mün", MatchPrefix, 0, textMatch{match: MatchPrefix, name: "München", langKey: 0, coverage: 3.0 / 7}},
+		{"fuzzy", "munchen", MatchFuzzy, 0, textMatch{match: MatchFuzzy, name: "München", langKey: 0, editDist: 1}},
+		// Names found through an alternate name keep the match of the index
+		{"alternate name", "monaco di baviera", MatchFuzzy, 0, textMatch{match: MatchFuzzy, langKey: -1, editDist: 2}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := matchNames(test.query, names, test.fallback, 2, test.langKey); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTextScore(t *testing.T) {
+	exact := textMatch{match: MatchExact, coverage: 1}.score()
+	fullPrefix := textMatch{match: MatchPrefix, coverage: 0.9}.score()
+	shortPrefix := textMatch{match: MatchPrefix, coverage: 0.1}.score()
+	fuzzy := textMatch{match: MatchFuzzy, editDist: 1}.score()
+	fuzzier := textMatch{match: MatchFuzzy, editDist: 2}.score()
+
+	order := []float64{exact, fullPrefix, shortPrefix, fuzzy, fuzzier}
+	for i := 1; i < len(order); i++ {
+		if order[i] >= order[i-1] {
+			t.Errorf("score %d is %v, want less than %v", i, order[i], order[i-1])
+		}
+	}
+	if score := (textMatch{match: MatchFuzzy, editDist: 10}).score(); score != 0 {
+		t.Errorf("score of 10 edits %v, want 0", score)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"berlin", "berlin", 0},
+		{"berlin", "berln", 1},
+		{"munchen", "münchen", 1},
+		{"köln", "koeln", 2},
+		{"", "ulm", 3},
+	}
+	for _, test := range tests {
+		if got := editDistance(test.a, test.b); got != test.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestFocusScore(t *testing.T) {
+	focus := &Focus{Lat: 52.5170, Lng: 13.3889, Scale: 10}
+
+	tests := []struct {
+		name     string
+		lat, lng float32
+		want     float64
+	}{
+		{"at the focus", 52.5170, 13.3889, scoreFocus},
+		// Halves every Scale kilometres, about 10 km north
+		{"one scale", 52.6069, 13.3889, scoreFocus / 2},
+		{"two scales", 52.6969, 13.3889, scoreFocus / 4},
+	}
+	for _, test := range tests {
+		score, _ := focusScore(focus, Node{Coordinates: [2]float32{test.lat, test.lng}})
+		if math.Abs(score-test.want) > 5 {
+			t.Errorf("%s: score %.0f, want %.0f", test.name, score, test.want)
+		}
+	}
+}
+
+func TestSearchExplain(t *testing.T) {
+	g := newTestGeocoder(t)
+
+	tests := []struct {
+		name  string
+		query string
+		focus *Focus
+		first string
+		match string
+	}{
+		{"exact", "Bonn", nil, "Bonn", MatchExact},
+		{"prefix", "Tierg", nil, "Tiergarten", MatchPrefix},
+		{"fuzzy", "Tiergartne", nil, "Tiergarten", MatchFuzzy},
+		{"focus", "Bonn", &Focus{Lat: 50.7374, Lng: 7.0982}, "Bonn", MatchExact},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, _ := g.Search(test.query, "en", SearchOptions{Explain: true, Focus: test.focus})
+			results := response["results"].([]Node)
+			if len(results) == 0 || results[0].Name != test.first {
+				t.Fatalf("got %v, want %q first", searchNames(response), test.first)
+			}
+			for _, node := range results {
+				explain := node.Explain
+				if explain == nil {
+					t.Fatalf("%s: no explain", node.Name)
+				}
+				sum := explain.Text + explain.Language + explain.Rank + explain.Focus + explain.Context + explain.HouseNumber + explain.Postcode + explain.Code
+				if math.Abs(sum-explain.Total) > 1e-6 || explain.Total != node.Score {
+					t.Errorf("%s: components sum to %v, total %v, score %v", node.Name, sum, explain.Total, node.Score)
+				}
+			}
+			if results[0].Explain.Match != test.match {
+				t.Errorf("match %q, want %q", results[0].Explain.Match, test.match)
+			}
+			if (results[0].Explain.Distance != nil) != (test.focus != nil) {
+				t.Errorf("distance %v with focus %v", results[0].Explain.Distance, test.focus)
+			}
+			if test.focus != nil && math.Abs(results[0].Explain.Focus-scoreFocus) > 1 {
+				t.Errorf("focus score %v at the focus, want %v", results[0].Explain.Focus, scoreFocus)
+			}
+		})
+	}
+
+	// Without explain the breakdown is left out
+	response, _ := g.Search("Bonn", "en", SearchOptions{})
+	if results := response["results"].([]Node); len(results) == 0 || results[0].Explain != nil {
+		t.Errorf("got explain without asking for it")
+	}
+}
@@ -0,0 +1,123 @@
+package geocoder
+
+import (
+	"hstin/gocoder/mapping"
+	"hstin/gocoder/parser"
+	"strings"
+)
+
+// isStructured reports whether a parsed query has components the plain name
+// search can't use, so it is answered by structuredResults.
+func isStructured(address parser.Address) bool {
+	other := address.Street != "" || address.Name != "" || address.City != ""
+	switch {
+	case address.Street != "" && address.HouseNumber != "":
+		return true
+	case address.Unit != "":
+		return other
+	case address.Postcode != "" || address.Country != "":
+		return other
+	}
+	return false
+}
+
+// structuredResults searches every component of an address in its own
+// index: the street and house number in the address index, the postcode in
+// the postcode index and the name or city by name. The city and state are
+// used as context, the country as country filter unless one is given.
+func (g *Geocoder) structuredResults(
+	address parser.Address,
+	lang string,
+	langKey int,
+	opts SearchOptions,
+	countries map[string]bool,
+	layers map[string]bool,
+) []Node {
+	if len(countries) == 0 && address.CountryCode != "" {
+		countries = map[string]bool{strings.ToUpper(address.CountryCode): true}
+	}
+
+	name, nameContext, context := structuredContext(address)
+
+	if address.Street != "" {
+		street := strings.ToLower(address.Street)
+		streets := g.candidates(street, context, lang, langKey, opts, countries, nil)
+
+		if address.HouseNumber != "" && len(g.Addresses.Addresses)+len(g.Interpolations.Interpolations) > 0 {
+			addresses := g.addressResults(streets, address.HouseNumber, opts.Explain, layers)
+			if len(addresses) > 0 {
+				addresses = filterPostcode(filterContext(addresses, context), address.Postcode)
+				return sortByScore(addresses)
+			}
+		}
+
+		if len(layers) == 0 || layers["street"] {
+			matching := make([]Node, 0, len(streets))
+			for _, node := range streets {
+				if node.Layer == "street" {
+					matching = append(matching, node)
+				}
+			}
+			if len(matching) > 0 {
+				return sortByScore(filterPostcode(filterContext(matching, context), address.Postcode))
+			}
+		}
+	}
+
+	results := make([]Node, 0)
+	if address.Postcode != "" {
+		codes := g.postcodeResults(address.Postcode, lang, countries, layers, opts.Explain)
+		results = append(results, filterContext(codes, context)...)
+	}
+
+	if name != "" {
+		places := g.candidates(strings.ToLower(name), nameContext, lang, langKey, opts, countries, layers)
+		results = append(results, filterPostcode(places, address.Postcode)...)
+	}
+
+	return sortByScore(results)
+}
+
+// structuredContext returns the name to search for an address, the context
+// of that name and the context of the other components. The city and region
+// are the context of streets and postcodes. "Eiffel Tower, Paris" searches
+// the name in the city, "Paris, France" the city itself in its region.
+func structuredContext(address parser.Address) (string, []string, []string) {
+	city := strings.ToLower(strings.TrimSpace(address.City))
+	region := strings.ToLower(strings.TrimSpace(address.Region()))
+
+	regionContext := make([]string, 0, 1)
+	if region != "" {
+		regionContext = append(regionContext, region)
+	}
+	context := regionContext
+	if city != "" {
+		context = append([]string{city}, regionContext...)
+	}
+
+	name := strings.TrimSpace(address.Name)
+	if name == "" {
+		return city, regionContext, context
+	}
+	return name, context, context
+}
+
+// filterPostcode drops the results with another postcode, unless none of
+// them has the postcode.
+func filterPostcode(nodes []Node, postcode string) []Node {
+	if postcode == "" {
+		return nodes
+	}
+
+	key := mapping.PostcodeKey(postcode)
+	matching := make([]Node, 0, len(nodes))
+	for _, node := range nodes {
+		if mapping.PostcodeKey(node.Postcode) == key {
+			matching = append(matching, node)
+		}
+	}
+	if len(matching) == 0 {
+		return nodes
+	}
+	return matching
+}
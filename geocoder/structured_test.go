@@ -0,0 +1,76 @@
+package geocoder
+
+import (
+	"hstin/gocoder/parser"
+	"reflect"
+	"testing"
+)
+
+func TestStructuredContext(t *testing.T) {
+	tests := []struct {
+		name        string
+		address     parser.Address
+		want        string
+		nameContext []string
+		context     []string
+	}{
+		{
+			name:        "name in city",
+			address:     parser.Address{Name: "Eiffel Tower", City: "Paris"},
+			want:        "Eiffel Tower",
+			nameContext: []string{"paris"},
+			context:     []string{"paris"},
+		},
+		{
+			name:        "city in region",
+			address:     parser.Address{City: "Springfield", State: "IL", CountryCode: "US"},
+			want:        "springfield",
+			nameContext: []string{"illinois"},
+			context:     []string{"springfield", "illinois"},
+		},
+		{
+			name:        "city only",
+			address:     parser.Address{City: " Paris ", Country: "France"},
+			want:        "paris",
+			nameContext: []string{},
+			context:     []string{"paris"},
+		},
+		{
+			name:        "blank city",
+			address:     parser.Address{City: " ", Postcode: "10115"},
+			want:        "",
+			nameContext: []string{},
+			context:     []string{},
+		},
+		{
+			name:        "blank city with region",
+			address:     parser.Address{City: "\t", State: "Bavaria"},
+			want:        "",
+			nameContext: []string{"bavaria"},
+			context:     []string{"bavaria"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name, nameContext, context := structuredContext(test.address)
+			if name != test.want {
+				t.Errorf("name %q, want %q", name, test.want)
+			}
+			if !reflect.DeepEqual(nameContext, test.nameContext) {
+				t.Errorf("name context %q, want %q", nameContext, test.nameContext)
+			}
+			if !reflect.DeepEqual(context, test.context) {
+				t.Errorf("context %q, want %q", context, test.context)
+			}
+		})
+	}
+}
+
+func TestStructuredResultsBlankCity(t *testing.T) {
+	g := &Geocoder{}
+	// A blank city used to be taken as the name with the rest of an empty
+	// context
+	if results := g.structuredResults(parser.Address{City: " "}, "en", 0, SearchOptions{}, nil, nil); len(results) != 0 {
+		t.Errorf("got %d results for a blank city", len(results))
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"hstin/gocoder/config"
 	"hstin/gocoder/generate"
 	"hstin/gocoder/geocoder"
+	"hstin/gocoder/parser"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -46,13 +48,77 @@ func StartServer() {
 		complete := c.QueryBool("complete", false)
 		useCache := c.QueryBool("cache", true)
 		lang := c.Query("lang", "name")
+		explain := c.QueryBool("explain", false)
+		dedupe := c.QueryBool("dedupe", true)
+		dedupeRadius := c.QueryFloat("dedupe_radius", 0)
 
 		if complete {
 			maxResults = -1
 			useCache = false
 		}
 
-		result, cacheHit := gCoder.Search(q, maxResults, useCache, lang)
+		var countries []string
+		if val := c.Query("countries"); val != "" {
+			countries = strings.Split(val, ",")
+		}
+
+		var layers []string
+		if val := c.Query("layers"); val != "" {
+			layers = strings.Split(val, ",")
+		}
+
+		var categories []string
+		if val := c.Query("category"); val != "" {
+			categories = strings.Split(val, ",")
+		}
+
+		var focus *geocoder.Focus
+		if c.Query("focus.lat") != "" && c.Query("focus.lng") != "" {
+			focusLat, err := strconv.ParseFloat(c.Query("focus.lat"), 64)
+			if err != nil {
+				return err
+			}
+
+			focusLng, err := strconv.ParseFloat(c.Query("focus.lng"), 64)
+			if err != nil {
+				return err
+			}
+
+			focus = &geocoder.Focus{
+				Lat:   focusLat,
+				Lng:   focusLng,
+				Scale: c.QueryFloat("focus.scale", 0),
+			}
+		}
+
+		// Structured queries name the address components themselves. Blank
+		// components count as not given.
+		var address *parser.Address
+		components := parser.Address{
+			HouseNumber: strings.TrimSpace(c.Query("housenumber")),
+			Street:      strings.TrimSpace(c.Query("street")),
+			Postcode:    strings.TrimSpace(c.Query("postcode")),
+			City:        strings.TrimSpace(c.Query("city")),
+			State:       strings.TrimSpace(c.Query("state")),
+			Country:     strings.TrimSpace(c.Query("country")),
+		}
+		if components.Street != "" || components.Postcode != "" || components.City != "" || components.Country != "" {
+			components.CountryCode, _ = parser.ParseCountry(components.Country)
+			address = &components
+		}
+
+		result, cacheHit := gCoder.Search(q, lang, geocoder.SearchOptions{
+			MaxResults:   maxResults,
+			UseCache:     useCache,
+			Focus:        focus,
+			Countries:    countries,
+			Layers:       layers,
+			Categories:   categories,
+			Explain:      explain,
+			Dedupe:       dedupe,
+			DedupeRadius: dedupeRadius,
+			Address:      address,
+		})
 		if cacheHit {
 			c.Set("X-Geocache", "HIT")
 		} else {
@@ -61,6 +127,10 @@ func StartServer() {
 		return c.JSON(result)
 	})
 
+	app.Get("/parse", func(c *fiber.Ctx) error {
+		return c.JSON(parser.Parse(c.Query("q")))
+	})
+
 	app.Get("/reverse", func(c *fiber.Ctx) error {
 
 		if !config.EnableReverse {
@@ -72,6 +142,17 @@ func StartServer() {
 		lat := c.Query("lat")
 		lng := c.Query("lng")
 		lang := c.Query("lang")
+		k := c.QueryInt("k", 1)
+		radius := c.QueryFloat("radius", 0)
+		minPopulation := c.QueryInt("min_population", 0)
+		streetRadius := c.QueryFloat("street_radius", 0)
+		addressRadius := c.QueryFloat("address_radius", 0)
+		postcodeRadius := c.QueryFloat("postcode_radius", 0)
+
+		var layers []string
+		if val := c.Query("layers"); val != "" {
+			layers = strings.Split(val, ",")
+		}
 
 		latFloat, err := strconv.ParseFloat(lat, 64)
 		if err != nil {
@@ -83,7 +164,75 @@ func StartServer() {
 			return err
 		}
 
-		return c.JSON(gCoder.Reverse(latFloat, lngFloat, lang))
+		// k and radius are limited, a single request must not return the
+		// whole index
+		if k <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "k has to be positive",
+			})
+		}
+		if radius < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "radius must not be negative",
+			})
+		}
+		k = min(k, geocoder.MaxReverseResults)
+		radius = min(radius, geocoder.MaxReverseRadius)
+
+		return c.JSON(gCoder.Reverse(latFloat, lngFloat, lang, geocoder.ReverseOptions{
+			K:              k,
+			Radius:         radius,
+			MinPopulation:  uint32(max(minPopulation, 0)),
+			Layers:         layers,
+			StreetRadius:   streetRadius,
+			AddressRadius:  addressRadius,
+			PostcodeRadius: postcodeRadius,
+		}))
+	})
+
+	app.Get("/bbox", func(c *fiber.Ctx) error {
+
+		if !config.EnableReverse {
+			return c.JSON(fiber.Map{
+				"error": "Reverse search is disabled",
+			})
+		}
+
+		var bounds [4]float64
+		for i, key := range []string{"minlat", "minlng", "maxlat", "maxlng"} {
+			val, err := strconv.ParseFloat(c.Query(key), 64)
+			if err != nil {
+				return err
+			}
+			bounds[i] = val
+		}
+
+		lang := c.Query("lang")
+		limit := c.QueryInt("limit", 100)
+		minRank := c.QueryInt("min_rank", 0)
+		grid := c.QueryInt("grid", 0)
+
+		return c.JSON(gCoder.BBox(bounds[0], bounds[1], bounds[2], bounds[3], lang, geocoder.BBoxOptions{
+			Limit:   limit,
+			MinRank: minRank,
+			Grid:    grid,
+		}))
+	})
+
+	app.Get("/wikidata/:qid", func(c *fiber.Ctx) error {
+		lang := c.Query("lang")
+
+		return c.JSON(gCoder.GetByWikidata(c.Params("qid"), lang))
+	})
+
+	app.Get("/metadata", func(c *fiber.Ctx) error {
+		return c.JSON(gCoder.Metadata)
+	})
+
+	app.Get("/countries", func(c *fiber.Ctx) error {
+		lang := c.Query("lang")
+
+		return c.JSON(gCoder.Countries(lang))
 	})
 
 	app.Get("/node/:id", func(c *fiber.Ctx) error {
@@ -94,7 +243,50 @@ func StartServer() {
 
 		lang := c.Query("lang")
 
-		return c.JSON(gCoder.GetNode(id, lang))
+		node, ok := gCoder.GetNode(id, lang)
+		if !ok {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Node not found",
+			})
+		}
+
+		return c.JSON(node)
+	})
+
+	app.Get("/way/:id", func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return err
+		}
+
+		lang := c.Query("lang")
+
+		node, ok := gCoder.GetOSMObject("way", id, lang)
+		if !ok {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Way not found",
+			})
+		}
+
+		return c.JSON(node)
+	})
+
+	app.Get("/relation/:id", func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return err
+		}
+
+		lang := c.Query("lang")
+
+		node, ok := gCoder.GetOSMObject("relation", id, lang)
+		if !ok {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Relation not found",
+			})
+		}
+
+		return c.JSON(node)
 	})
 
 	_ = app.Listen(":3000")
@@ -0,0 +1,253 @@
+package mapping
+
+import (
+	"regexp"
+	"strings"
+)
+
+// AddressComponents are the parts of a result a display name is built from.
+// All names are in the requested language.
+type AddressComponents struct {
+	Name        string
+	HouseNumber string
+	Street      string
+	Postcode    string
+	City        string
+	County      string
+	Region      string
+	Country     string
+	CountryCode string
+}
+
+// AddressFormat is the display name template of a country. Every part is a
+// text with placeholders like "{postcode} {city}". "{city|county}" uses the
+// first non-empty component. Parts whose placeholders are all empty and
+// parts repeating an earlier one are dropped, the rest is joined with the
+// separator, ", " if empty.
+//
+// Placeholders: name, housenumber, street, postcode, city, county, region,
+// state_code (the abbreviation of the region, see StateCodes), country.
+type AddressFormat struct {
+	Short     []string
+	Long      []string
+	Separator string
+}
+
+// defaultAddressFormat is used for countries without their own format.
+var defaultAddressFormat = AddressFormat{
+	Short: []string{"{name}", "{street} {housenumber}", "{city}", "{country}"},
+	Long:  []string{"{name}", "{street} {housenumber}", "{postcode} {city}", "{region}", "{country}"},
+}
+
+// numberFirstAddressFormat puts the house number before the street, e.g.
+// "10 Downing Street".
+var numberFirstAddressFormat = AddressFormat{
+	Short: []string{"{name}", "{housenumber} {street}", "{city}", "{country}"},
+	Long:  []string{"{name}", "{housenumber} {street}", "{postcode} {city}", "{region}", "{country}"},
+}
+
+// AddressFormats are the display name templates by country code. Formats
+// for the native language of a country are stored as "JP:ja" and win over
+// the country format if that language is requested.
+var AddressFormats = map[string]AddressFormat{
+	"AT": {
+		Short: []string{"{name}", "{street} {housenumber}", "{city}", "{country}"},
+		Long:  []string{"{name}", "{street} {housenumber}", "{postcode} {city}", "{country}"},
+	},
+	"AU": {
+		Short: []string{"{name}", "{housenumber} {street}", "{city}", "{state_code|region}"},
+		Long:  []string{"{name}", "{housenumber} {street}", "{city} {state_code|region} {postcode}", "{country}"},
+	},
+	"BE": {
+		Short: []string{"{name}", "{street} {housenumber}", "{city}", "{country}"},
+		Long:  []string{"{name}", "{street} {housenumber}", "{postcode} {city}", "{country}"},
+	},
+	"CA": {
+		Short: []string{"{name}", "{housenumber} {street}", "{city}", "{state_code|region}"},
+		Long:  []string{"{name}", "{housenumber} {street}", "{city} {state_code|region} {postcode}", "{country}"},
+	},
+	"CH": {
+		Short: []string{"{name}", "{street} {housenumber}", "{city}", "{country}"},
+		Long:  []string{"{name}", "{street} {housenumber}", "{postcode} {city}", "{country}"},
+	},
+	"CN": numberFirstAddressFormat,
+	"CN:zh": {
+		Short:     []string{"{region}", "{city}", "{name}"},
+		Long:      []string{"{country}", "{region}", "{city}", "{street}", "{housenumber}", "{name}"},
+		Separator: " ",
+	},
+	"DE": {
+		Short: []string{"{name}", "{street} {housenumber}", "{city}", "{country}"},
+		Long:  []string{"{name}", "{street} {housenumber}", "{postcode} {city}", "{country}"},
+	},
+	"DK": {
+		Short: []string{"{name}", "{street} {housenumber}", "{city}", "{country}"},
+		Long:  []string{"{name}", "{street} {housenumber}", "{postcode} {city}", "{country}"},
+	},
+	"ES": {
+		Short: []string{"{name}", "{street} {housenumber}", "{city}", "{country}"},
+		Long:  []string{"{name}", "{street} {housenumber}", "{postcode} {city}", "{region}", "{country}"},
+	},
+	"FR": {
+		Short: []string{"{name}", "{housenumber} {street}", "{city}", "{country}"},
+		Long:  []string{"{name}", "{housenumber} {street}", "{postcode} {city}", "{country}"},
+	},
+	"GB": {
+		Short: []string{"{name}", "{housenumber} {street}", "{city}", "{country}"},
+		Long:  []string{"{name}", "{housenumber} {street}", "{city}", "{postcode}", "{country}"},
+	},
+	"HU": {
+		Short: []string{"{name}", "{city}", "{street} {housenumber}", "{country}"},
+		Long:  []string{"{name}", "{postcode} {city}", "{street} {housenumber}", "{country}"},
+	},
+	"IE": {
+		Short: []string{"{name}", "{housenumber} {street}", "{city}", "{country}"},
+		Long:  []string{"{name}", "{housenumber} {street}", "{city}", "{county}", "{postcode}", "{country}"},
+	},
+	"IT": {
+		Short: []string{"{name}", "{street} {housenumber}", "{city}", "{country}"},
+		Long:  []string{"{name}", "{street} {housenumber}", "{postcode} {city}", "{region}", "{country}"},
+	},
+	"JP": {
+		Short: []string{"{name}", "{housenumber} {street}", "{city}", "{region}"},
+		Long:  []string{"{name}", "{housenumber} {street}", "{city}", "{region} {postcode}", "{country}"},
+	},
+	"JP:ja": {
+		Short:     []string{"{region}{city}", "{name}"},
+		Long:      []string{"〒{postcode}", "{region}{county}{city}", "{street}{housenumber}", "{name}"},
+		Separator: " ",
+	},
+	"KR": numberFirstAddressFormat,
+	"KR:ko": {
+		Short:     []string{"{region}", "{city}", "{name}"},
+		Long:      []string{"{region}", "{city}", "{street} {housenumber}", "{name}", "{postcode}"},
+		Separator: " ",
+	},
+	"NL": {
+		Short: []string{"{name}", "{street} {housenumber}", "{city}", "{country}"},
+		Long:  []string{"{name}", "{street} {housenumber}", "{postcode} {city}", "{country}"},
+	},
+	"NZ": numberFirstAddressFormat,
+	"PL": {
+		Short: []string{"{name}", "{street} {housenumber}", "{city}", "{country}"},
+		Long:  []string{"{name}", "{street} {housenumber}", "{postcode} {city}", "{country}"},
+	},
+	"RU": {
+		Short: []string{"{name}", "{street}, {housenumber}", "{city}", "{country}"},
+		Long:  []string{"{name}", "{street}, {housenumber}", "{city}", "{region}", "{postcode}", "{country}"},
+	},
+	"US": {
+		Short: []string{"{name}", "{housenumber} {street}", "{city|county}", "{state_code|region}"},
+		Long:  []string{"{name}", "{housenumber} {street}", "{city|county}", "{state_code|region} {postcode}", "{country}"},
+	},
+}
+
+// StateCodes are the abbreviations of the regions of a country by their
+// English name, used for the state_code placeholder.
+var StateCodes = map[string]map[string]string{
+	"US": {
+		"Alabama": "AL", "Alaska": "AK", "Arizona": "AZ", "Arkansas": "AR", "California": "CA",
+		"Colorado": "CO", "Connecticut": "CT", "Delaware": "DE", "District of Columbia": "DC",
+		"Florida": "FL", "Georgia": "GA", "Hawaii": "HI", "Idaho": "ID", "Illinois": "IL",
+		"Indiana": "IN", "Iowa": "IA", "Kansas": "KS", "Kentucky": "KY", "Louisiana": "LA",
+		"Maine": "ME", "Maryland": "MD", "Massachusetts": "MA", "Michigan": "MI", "Minnesota": "MN",
+		"Mississippi": "MS", "Missouri": "MO", "Montana": "MT", "Nebraska": "NE", "Nevada": "NV",
+		"New Hampshire": "NH", "New Jersey": "NJ", "New Mexico": "NM", "New York": "NY",
+		"North Carolina": "NC", "North Dakota": "ND", "Ohio": "OH", "Oklahoma": "OK", "Oregon": "OR",
+		"Pennsylvania": "PA", "Rhode Island": "RI", "South Carolina": "SC", "South Dakota": "SD",
+		"Tennessee": "TN", "Texas": "TX", "Utah": "UT", "Vermont": "VT", "Virginia": "VA",
+		"Washington": "WA", "West Virginia": "WV", "Wisconsin": "WI", "Wyoming": "WY",
+		"Puerto Rico": "PR",
+	},
+	"CA": {
+		"Alberta": "AB", "British Columbia": "BC", "Manitoba": "MB", "New Brunswick": "NB",
+		"Newfoundland and Labrador": "NL", "Northwest Territories": "NT", "Nova Scotia": "NS",
+		"Nunavut": "NU", "Ontario": "ON", "Prince Edward Island": "PE", "Quebec": "QC",
+		"Québec": "QC", "Saskatchewan": "SK", "Yukon": "YT",
+	},
+	"AU": {
+		"Australian Capital Territory": "ACT", "New South Wales": "NSW", "Northern Territory": "NT",
+		"Queensland": "QLD", "South Australia": "SA", "Tasmania": "TAS", "Victoria": "VIC",
+		"Western Australia": "WA",
+	},
+}
+
+var placeholderPattern = regexp.MustCompile(`\{([a-z_|]+)\}`)
+
+// GetAddressFormat returns the format for a country and language.
+func GetAddressFormat(country string, lang string) AddressFormat {
+	if format, ok := AddressFormats[country+":"+lang]; ok {
+		return format
+	}
+	if format, ok := AddressFormats[country]; ok {
+		return format
+	}
+	return defaultAddressFormat
+}
+
+// FormatAddress returns the short and long display name of the components
+// in the format of their country and the language.
+func FormatAddress(components AddressComponents, lang string) (string, string) {
+	format := GetAddressFormat(components.CountryCode, lang)
+	return format.render(format.Short, components), format.render(format.Long, components)
+}
+
+// FormatStreetAddress returns the street and house number of the components
+// in the order of their country and the language, e.g. "Hauptstraße 12" in
+// Germany and "12 Baker Street" in Great Britain.
+func FormatStreetAddress(components AddressComponents, lang string) string {
+	format := GetAddressFormat(components.CountryCode, lang)
+	parts := make([]string, 0, 2)
+	for _, part := range format.Long {
+		if strings.Contains(part, "{street}") || strings.Contains(part, "{housenumber}") {
+			parts = append(parts, part)
+		}
+	}
+	return format.render(parts, AddressComponents{
+		Street:      components.Street,
+		HouseNumber: components.HouseNumber,
+	})
+}
+
+func (f AddressFormat) render(parts []string, components AddressComponents) string {
+	values := map[string]string{
+		"name":        components.Name,
+		"housenumber": components.HouseNumber,
+		"street":      components.Street,
+		"postcode":    components.Postcode,
+		"city":        components.City,
+		"county":      components.County,
+		"region":      components.Region,
+		"state_code":  StateCodes[components.CountryCode][components.Region],
+		"country":     components.Country,
+	}
+
+	separator := f.Separator
+	if separator == "" {
+		separator = ", "
+	}
+
+	out := make([]string, 0, len(parts))
+	seen := make(map[string]bool, len(parts))
+	for _, part := range parts {
+		filled := false
+		text := placeholderPattern.ReplaceAllStringFunc(part, func(placeholder string) string {
+			for _, key := range strings.Split(placeholder[1:len(placeholder)-1], "|") {
+				if value := strings.TrimSpace(values[key]); value != "" {
+					filled = true
+					return value
+				}
+			}
+			return ""
+		})
+		text = strings.Join(strings.Fields(text), " ")
+		text = strings.Trim(text, ", ")
+		if !filled || text == "" || seen[text] {
+			continue
+		}
+		seen[text] = true
+		out = append(out, text)
+	}
+
+	return strings.Join(out, separator)
+}
@@ -0,0 +1,103 @@
+package mapping
+
+import "testing"
+
+func TestFormatStreetAddress(t *testing.T) {
+	tests := []struct {
+		country string
+		street  string
+		number  string
+		want    string
+	}{
+		{"DE", "Unter den Linden", "77", "Unter den Linden 77"},
+		{"AT", "Stephansplatz", "1", "Stephansplatz 1"},
+		{"GB", "Baker Street", "221B", "221B Baker Street"},
+		{"US", "Pennsylvania Ave", "1600", "1600 Pennsylvania Ave"},
+		{"FR", "rue de Rivoli", "5", "5 rue de Rivoli"},
+		{"DE", "Unter den Linden", "", "Unter den Linden"},
+	}
+	for _, test := range tests {
+		got := FormatStreetAddress(AddressComponents{Street: test.street, HouseNumber: test.number, CountryCode: test.country}, "en")
+		if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.country, got, test.want)
+		}
+	}
+}
+
+func TestFormatAddress(t *testing.T) {
+	tests := []struct {
+		name       string
+		components AddressComponents
+		lang       string
+		short      string
+		long       string
+	}{
+		{
+			"germany",
+			AddressComponents{Name: "Brandenburger Tor", Street: "Pariser Platz", HouseNumber: "1", Postcode: "10117", City: "Berlin", Region: "Berlin", Country: "Germany", CountryCode: "DE"},
+			"en",
+			"Brandenburger Tor, Pariser Platz 1, Berlin, Germany",
+			"Brandenburger Tor, Pariser Platz 1, 10117 Berlin, Germany",
+		},
+		{
+			// Repeated parts are dropped
+			"city",
+			AddressComponents{Name: "Berlin", City: "Berlin", Region: "Berlin", Country: "Germany", CountryCode: "DE"},
+			"en",
+			"Berlin, Germany",
+			"Berlin, Germany",
+		},
+		{
+			"state code",
+			AddressComponents{Name: "White House", Street: "Pennsylvania Avenue NW", HouseNumber: "1600", Postcode: "20500", City: "Washington", Region: "District of Columbia", Country: "United States", CountryCode: "US"},
+			"en",
+			"White House, 1600 Pennsylvania Avenue NW, Washington, DC",
+			"White House, 1600 Pennsylvania Avenue NW, Washington, DC 20500, United States",
+		},
+		{
+			"county instead of city",
+			AddressComponents{Name: "Yellowstone", County: "Park County", Region: "Wyoming", Country: "United States", CountryCode: "US"},
+			"en",
+			"Yellowstone, Park County, WY",
+			"Yellowstone, Park County, WY, United States",
+		},
+		{
+			"city first",
+			AddressComponents{Name: "Parlament", Street: "Kossuth Lajos tér", HouseNumber: "1-3", Postcode: "1055", City: "Budapest", Country: "Hungary", CountryCode: "HU"},
+			"en",
+			"Parlament, Budapest, Kossuth Lajos tér 1-3, Hungary",
+			"Parlament, 1055 Budapest, Kossuth Lajos tér 1-3, Hungary",
+		},
+		{
+			"japan in english",
+			AddressComponents{Name: "Tokyo Tower", Postcode: "105-0011", City: "Minato", Region: "Tokyo", Country: "Japan", CountryCode: "JP"},
+			"en",
+			"Tokyo Tower, Minato, Tokyo",
+			"Tokyo Tower, Minato, Tokyo 105-0011, Japan",
+		},
+		{
+			// The native format goes from the largest area to the name
+			"japan in japanese",
+			AddressComponents{Name: "東京タワー", Postcode: "105-0011", City: "港区", Region: "東京都", Country: "日本", CountryCode: "JP"},
+			"ja",
+			"東京都港区 東京タワー",
+			"〒105-0011 東京都港区 東京タワー",
+		},
+		{
+			"default format",
+			AddressComponents{Name: "Ber", Region: "Tombouctou", Country: "Mali", CountryCode: "ML"},
+			"en",
+			"Ber, Mali",
+			"Ber, Tombouctou, Mali",
+		},
+	}
+	for _, test := range tests {
+		short, long := FormatAddress(test.components, test.lang)
+		if short != test.short {
+			t.Errorf("%s: short %q, want %q", test.name, short, test.short)
+		}
+		if long != test.long {
+			t.Errorf("%s: long %q, want %q", test.name, long, test.long)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package mapping
+
+// AdminPlacetypes lists the Who's On First placetypes kept in the admin
+// hierarchy of a place, ordered from the largest to the smallest area.
+var AdminPlacetypes = []string{
+	"country",
+	"macroregion",
+	"region",
+	"macrocounty",
+	"county",
+	"localadmin",
+	"locality",
+	"borough",
+	"neighbourhood",
+}
+
+// GetAdminLevel returns the position of a placetype in AdminPlacetypes or -1
+// if the placetype is not part of the hierarchy.
+func GetAdminLevel(placetype string) int {
+	for i, p := range AdminPlacetypes {
+		if p == placetype {
+			return i
+		}
+	}
+	return -1
+}
+
+// OSMAdminPlacetypes maps the admin_level of OSM boundary=administrative
+// relations to the placetype they are indexed as. The meaning of the levels
+// differs between countries, this follows the most common use. Levels
+// without placetype are not indexed, the settlements below level 8 are
+// covered by place nodes.
+var OSMAdminPlacetypes = []string{
+	2: "country",
+	3: "macroregion",
+	4: "region",
+	5: "macrocounty",
+	6: "county",
+	7: "localadmin",
+	8: "localadmin",
+}
@@ -0,0 +1,137 @@
+package mapping
+
+import "strings"
+
+// Category is an entry of the POI taxonomy. Names are dot separated paths
+// from the general to the specific category, e.g. "health.pharmacy". An OSM
+// object belongs to the first category with a matching tag expression, see
+// the tag filters for the expression syntax.
+type Category struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+	// Keywords are the words that select the category in queries like
+	// "pharmacy in Bonn", next to the last part of the name.
+	Keywords []string `json:"keywords,omitempty"`
+	// Rank replaces the place type weight of the ranking model.
+	Rank *float64 `json:"rank,omitempty"`
+}
+
+func categoryRank(rank float64) *float64 {
+	return &rank
+}
+
+// DefaultCategories is the built-in POI taxonomy.
+var DefaultCategories = []Category{
+	{Name: "transport.airport", Tags: []string{"aeroway=aerodrome"}, Keywords: []string{"airport", "flughafen", "aéroport", "aeropuerto"}, Rank: categoryRank(700)},
+	{Name: "transport.station", Tags: []string{"railway=station|halt", "public_transport=station"}, Keywords: []string{"train station", "railway station", "bahnhof", "gare"}, Rank: categoryRank(500)},
+	{Name: "transport.ferry_terminal", Tags: []string{"amenity=ferry_terminal"}, Keywords: []string{"ferry", "fähre"}},
+	{Name: "health.hospital", Tags: []string{"amenity=hospital"}, Keywords: []string{"krankenhaus", "klinik", "hôpital"}, Rank: categoryRank(300)},
+	{Name: "health.pharmacy", Tags: []string{"amenity=pharmacy", "healthcare=pharmacy"}, Keywords: []string{"chemist", "apotheke", "pharmacie", "farmacia"}},
+	{Name: "health.doctor", Tags: []string{"amenity=doctors|clinic"}, Keywords: []string{"doctors", "arzt"}},
+	{Name: "food.restaurant", Tags: []string{"amenity=restaurant"}},
+	{Name: "food.cafe", Tags: []string{"amenity=cafe"}, Keywords: []string{"café", "coffee"}},
+	{Name: "food.fast_food", Tags: []string{"amenity=fast_food"}, Keywords: []string{"imbiss"}},
+	{Name: "food.bar", Tags: []string{"amenity=bar|pub|biergarten"}, Keywords: []string{"pub", "kneipe"}},
+	{Name: "education.university", Tags: []string{"amenity=university|college"}, Keywords: []string{"universität", "uni", "université"}, Rank: categoryRank(300)},
+	{Name: "education.school", Tags: []string{"amenity=school"}, Keywords: []string{"schule", "école"}},
+	{Name: "finance.bank", Tags: []string{"amenity=bank"}},
+	{Name: "finance.atm", Tags: []string{"amenity=atm"}, Keywords: []string{"geldautomat"}},
+	{Name: "transport.fuel", Tags: []string{"amenity=fuel"}, Keywords: []string{"gas station", "petrol station", "tankstelle"}},
+	{Name: "government.police", Tags: []string{"amenity=police"}, Keywords: []string{"polizei"}},
+	{Name: "government.post_office", Tags: []string{"amenity=post_office"}, Keywords: []string{"post", "postamt"}},
+	{Name: "government.townhall", Tags: []string{"amenity=townhall"}, Keywords: []string{"town hall", "rathaus"}},
+	{Name: "culture.museum", Tags: []string{"tourism=museum"}, Keywords: []string{"musée"}, Rank: categoryRank(300)},
+	{Name: "culture.theatre", Tags: []string{"amenity=theatre"}, Keywords: []string{"theater"}},
+	{Name: "culture.cinema", Tags: []string{"amenity=cinema"}, Keywords: []string{"kino"}},
+	{Name: "culture.library", Tags: []string{"amenity=library"}, Keywords: []string{"bibliothek", "bücherei"}},
+	{Name: "tourism.hotel", Tags: []string{"tourism=hotel|hostel|guest_house|motel"}, Keywords: []string{"hostel", "motel"}},
+	{Name: "tourism.attraction", Tags: []string{"tourism=attraction|viewpoint|zoo|theme_park"}, Keywords: []string{"sight", "sehenswürdigkeit", "zoo"}, Rank: categoryRank(300)},
+	{Name: "leisure.park", Tags: []string{"leisure=park"}},
+	{Name: "leisure.stadium", Tags: []string{"leisure=stadium"}, Keywords: []string{"stadion"}, Rank: categoryRank(300)},
+	{Name: "leisure.swimming_pool", Tags: []string{"leisure=swimming_pool|water_park"}, Keywords: []string{"schwimmbad", "pool"}},
+	{Name: "leisure.sports_centre", Tags: []string{"leisure=sports_centre"}, Keywords: []string{"sports centre", "gym"}},
+	{Name: "shop.supermarket", Tags: []string{"shop=supermarket"}, Keywords: []string{"supermarkt"}},
+	{Name: "shop.bakery", Tags: []string{"shop=bakery"}, Keywords: []string{"bäckerei", "boulangerie"}},
+	{Name: "shop.mall", Tags: []string{"shop=mall|department_store"}, Keywords: []string{"shopping centre", "shopping center", "einkaufszentrum"}},
+	{Name: "shop.convenience", Tags: []string{"shop=convenience|kiosk"}, Keywords: []string{"kiosk", "späti"}},
+	{Name: "shop", Tags: []string{"shop"}, Keywords: []string{"store", "geschäft", "laden"}},
+}
+
+// Categories lists the categories a node can belong to. The position in this
+// slice is what gets stored in the database.
+var Categories = []string{
+	"", // Index 0 reserved for nodes without category
+}
+
+// categoryKeywords maps the normalized keywords to the categories they select.
+var categoryKeywords = map[string][]string{}
+
+func GetCategoryNumber(category string) int {
+	for i := 1; i < len(Categories); i++ {
+		if Categories[i] == category {
+			return i
+		}
+	}
+	return 0
+}
+
+func GetCategoryName(number int) string {
+	if number < 0 || number >= len(Categories) {
+		return ""
+	}
+	return Categories[number]
+}
+
+// AddCategory registers a category and returns its number. The number is 0
+// if the list is full.
+func AddCategory(category string) int {
+	if number := GetCategoryNumber(category); number != 0 || category == "" {
+		return number
+	}
+	if len(Categories) > 65535 {
+		return 0
+	}
+	Categories = append(Categories, category)
+	return len(Categories) - 1
+}
+
+// SetCategoryKeywords registers the query keywords of a taxonomy: the last
+// part of every category name, e.g. "pharmacy" or "fast food", and the
+// configured keywords.
+func SetCategoryKeywords(categories []Category) {
+	categoryKeywords = make(map[string][]string)
+	for _, category := range categories {
+		parts := strings.Split(category.Name, ".")
+		keywords := append([]string{strings.ReplaceAll(parts[len(parts)-1], "_", " ")}, category.Keywords...)
+		for _, keyword := range keywords {
+			keyword = strings.ToLower(strings.TrimSpace(keyword))
+			categoryKeywords[keyword] = append(categoryKeywords[keyword], category.Name)
+		}
+	}
+}
+
+// CategoriesForKeyword returns the categories a query word selects. Plurals
+// like "museums" or "pharmacies" are reduced to the singular.
+func CategoriesForKeyword(word string) []string {
+	word = strings.ToLower(strings.TrimSpace(word))
+	candidates := []string{word}
+	if strings.HasSuffix(word, "ies") {
+		candidates = append(candidates, strings.TrimSuffix(word, "ies")+"y")
+	}
+	if strings.HasSuffix(word, "s") {
+		candidates = append(candidates, strings.TrimSuffix(word, "s"), strings.TrimSuffix(word, "es"))
+	}
+	for _, candidate := range candidates {
+		if categories, ok := categoryKeywords[candidate]; ok {
+			return categories
+		}
+	}
+	return nil
+}
+
+// MatchCategory reports whether a category is selected by a filter. A filter
+// selects the category itself and all categories below it, "health" selects
+// "health.pharmacy".
+func MatchCategory(category string, filter string) bool {
+	return category == filter || strings.HasPrefix(category, filter+".")
+}
@@ -0,0 +1,33 @@
+package mapping
+
+import "strings"
+
+// CodeTag is an OSM tag holding a transport identifier and the code type it
+// is stored as, e.g. "iata" for the tags iata and ref:IATA.
+type CodeTag struct {
+	Key  string
+	Type string
+}
+
+// CodeTags are the identifier tags that are indexed for the code lookup. A
+// tag value can hold several codes separated by ";".
+var CodeTags = []CodeTag{
+	{Key: "iata", Type: "iata"},
+	{Key: "ref:IATA", Type: "iata"},
+	{Key: "ref:iata", Type: "iata"},
+	{Key: "icao", Type: "icao"},
+	{Key: "ref:ICAO", Type: "icao"},
+	{Key: "ref:icao", Type: "icao"},
+	{Key: "faa", Type: "faa"},
+	{Key: "uic_ref", Type: "uic"},
+	{Key: "ref:uic", Type: "uic"},
+	{Key: "ref:IBNR", Type: "ibnr"},
+	{Key: "ref:crs", Type: "crs"},
+	{Key: "railway:ref", Type: "railway"},
+}
+
+// NormalizeCode upper cases a code and removes whitespace, so "eddf" and
+// "EDDF" are the same code.
+func NormalizeCode(code string) string {
+	return strings.Join(strings.Fields(strings.ToUpper(code)), "")
+}
@@ -0,0 +1,262 @@
+package mapping
+
+// CountryMetadata holds static information about a country that is stored
+// in the database alongside the localized country names.
+type CountryMetadata struct {
+	ISO3        string
+	Continent   string
+	CallingCode string
+	Currency    string
+}
+
+// CountryInfo maps ISO-3166 alpha-2 codes to their metadata. Continents use
+// the two letter codes AF, AN, AS, EU, NA, OC and SA, currencies ISO 4217.
+var CountryInfo = map[string]CountryMetadata{
+	"AF": {"AFG", "AS", "+93", "AFN"},
+	"AL": {"ALB", "EU", "+355", "ALL"},
+	"DZ": {"DZA", "AF", "+213", "DZD"},
+	"AS": {"ASM", "OC", "+1-684", "USD"},
+	"AD": {"AND", "EU", "+376", "EUR"},
+	"AO": {"AGO", "AF", "+244", "AOA"},
+	"AI": {"AIA", "NA", "+1-264", "XCD"},
+	"AQ": {"ATA", "AN", "+672", ""},
+	"AG": {"ATG", "NA", "+1-268", "XCD"},
+	"AR": {"ARG", "SA", "+54", "ARS"},
+	"AM": {"ARM", "AS", "+374", "AMD"},
+	"AW": {"ABW", "NA", "+297", "AWG"},
+	"AU": {"AUS", "OC", "+61", "AUD"},
+	"AT": {"AUT", "EU", "+43", "EUR"},
+	"AZ": {"AZE", "AS", "+994", "AZN"},
+	"BS": {"BHS", "NA", "+1-242", "BSD"},
+	"BH": {"BHR", "AS", "+973", "BHD"},
+	"BD": {"BGD", "AS", "+880", "BDT"},
+	"BB": {"BRB", "NA", "+1-246", "BBD"},
+	"BY": {"BLR", "EU", "+375", "BYN"},
+	"BE": {"BEL", "EU", "+32", "EUR"},
+	"BZ": {"BLZ", "NA", "+501", "BZD"},
+	"BJ": {"BEN", "AF", "+229", "XOF"},
+	"BM": {"BMU", "NA", "+1-441", "BMD"},
+	"BT": {"BTN", "AS", "+975", "BTN"},
+	"BO": {"BOL", "SA", "+591", "BOB"},
+	"BQ": {"BES", "NA", "+599", "USD"},
+	"BA": {"BIH", "EU", "+387", "BAM"},
+	"BW": {"BWA", "AF", "+267", "BWP"},
+	"BV": {"BVT", "AN", "+47", "NOK"},
+	"BR": {"BRA", "SA", "+55", "BRL"},
+	"IO": {"IOT", "AS", "+246", "USD"},
+	"BN": {"BRN", "AS", "+673", "BND"},
+	"BG": {"BGR", "EU", "+359", "BGN"},
+	"BF": {"BFA", "AF", "+226", "XOF"},
+	"BI": {"BDI", "AF", "+257", "BIF"},
+	"CV": {"CPV", "AF", "+238", "CVE"},
+	"KH": {"KHM", "AS", "+855", "KHR"},
+	"CM": {"CMR", "AF", "+237", "XAF"},
+	"CA": {"CAN", "NA", "+1", "CAD"},
+	"KY": {"CYM", "NA", "+1-345", "KYD"},
+	"CF": {"CAF", "AF", "+236", "XAF"},
+	"TD": {"TCD", "AF", "+235", "XAF"},
+	"CL": {"CHL", "SA", "+56", "CLP"},
+	"CN": {"CHN", "AS", "+86", "CNY"},
+	"CX": {"CXR", "AS", "+61", "AUD"},
+	"CC": {"CCK", "AS", "+61", "AUD"},
+	"CO": {"COL", "SA", "+57", "COP"},
+	"KM": {"COM", "AF", "+269", "KMF"},
+	"CG": {"COG", "AF", "+242", "XAF"},
+	"CD": {"COD", "AF", "+243", "CDF"},
+	"CK": {"COK", "OC", "+682", "NZD"},
+	"CR": {"CRI", "NA", "+506", "CRC"},
+	"HR": {"HRV", "EU", "+385", "EUR"},
+	"CU": {"CUB", "NA", "+53", "CUP"},
+	"CW": {"CUW", "NA", "+599", "ANG"},
+	"CY": {"CYP", "EU", "+357", "EUR"},
+	"CZ": {"CZE", "EU", "+420", "CZK"},
+	"DK": {"DNK", "EU", "+45", "DKK"},
+	"DJ": {"DJI", "AF", "+253", "DJF"},
+	"DM": {"DMA", "NA", "+1-767", "XCD"},
+	"DO": {"DOM", "NA", "+1-809", "DOP"},
+	"EC": {"ECU", "SA", "+593", "USD"},
+	"EG": {"EGY", "AF", "+20", "EGP"},
+	"SV": {"SLV", "NA", "+503", "USD"},
+	"GQ": {"GNQ", "AF", "+240", "XAF"},
+	"ER": {"ERI", "AF", "+291", "ERN"},
+	"EE": {"EST", "EU", "+372", "EUR"},
+	"SZ": {"SWZ", "AF", "+268", "SZL"},
+	"ET": {"ETH", "AF", "+251", "ETB"},
+	"FK": {"FLK", "SA", "+500", "FKP"},
+	"FO": {"FRO", "EU", "+298", "DKK"},
+	"FJ": {"FJI", "OC", "+679", "FJD"},
+	"FI": {"FIN", "EU", "+358", "EUR"},
+	"FR": {"FRA", "EU", "+33", "EUR"},
+	"GF": {"GUF", "SA", "+594", "EUR"},
+	"PF": {"PYF", "OC", "+689", "XPF"},
+	"TF": {"ATF", "AN", "+262", "EUR"},
+	"GA": {"GAB", "AF", "+241", "XAF"},
+	"GM": {"GMB", "AF", "+220", "GMD"},
+	"GE": {"GEO", "AS", "+995", "GEL"},
+	"DE": {"DEU", "EU", "+49", "EUR"},
+	"GH": {"GHA", "AF", "+233", "GHS"},
+	"GI": {"GIB", "EU", "+350", "GIP"},
+	"GR": {"GRC", "EU", "+30", "EUR"},
+	"GL": {"GRL", "NA", "+299", "DKK"},
+	"GD": {"GRD", "NA", "+1-473", "XCD"},
+	"GP": {"GLP", "NA", "+590", "EUR"},
+	"GU": {"GUM", "OC", "+1-671", "USD"},
+	"GT": {"GTM", "NA", "+502", "GTQ"},
+	"GG": {"GGY", "EU", "+44", "GBP"},
+	"GN": {"GIN", "AF", "+224", "GNF"},
+	"GW": {"GNB", "AF", "+245", "XOF"},
+	"GY": {"GUY", "SA", "+592", "GYD"},
+	"HT": {"HTI", "NA", "+509", "HTG"},
+	"HM": {"HMD", "AN", "+672", "AUD"},
+	"VA": {"VAT", "EU", "+379", "EUR"},
+	"HN": {"HND", "NA", "+504", "HNL"},
+	"HK": {"HKG", "AS", "+852", "HKD"},
+	"HU": {"HUN", "EU", "+36", "HUF"},
+	"IS": {"ISL", "EU", "+354", "ISK"},
+	"IN": {"IND", "AS", "+91", "INR"},
+	"ID": {"IDN", "AS", "+62", "IDR"},
+	"IR": {"IRN", "AS", "+98", "IRR"},
+	"IQ": {"IRQ", "AS", "+964", "IQD"},
+	"IE": {"IRL", "EU", "+353", "EUR"},
+	"IM": {"IMN", "EU", "+44", "GBP"},
+	"IL": {"ISR", "AS", "+972", "ILS"},
+	"IT": {"ITA", "EU", "+39", "EUR"},
+	"JM": {"JAM", "NA", "+1-876", "JMD"},
+	"JP": {"JPN", "AS", "+81", "JPY"},
+	"JE": {"JEY", "EU", "+44", "GBP"},
+	"JO": {"JOR", "AS", "+962", "JOD"},
+	"KZ": {"KAZ", "AS", "+7", "KZT"},
+	"KE": {"KEN", "AF", "+254", "KES"},
+	"KI": {"KIR", "OC", "+686", "AUD"},
+	"KP": {"PRK", "AS", "+850", "KPW"},
+	"KR": {"KOR", "AS", "+82", "KRW"},
+	"KW": {"KWT", "AS", "+965", "KWD"},
+	"KG": {"KGZ", "AS", "+996", "KGS"},
+	"LA": {"LAO", "AS", "+856", "LAK"},
+	"LV": {"LVA", "EU", "+371", "EUR"},
+	"LB": {"LBN", "AS", "+961", "LBP"},
+	"LS": {"LSO", "AF", "+266", "LSL"},
+	"LR": {"LBR", "AF", "+231", "LRD"},
+	"LY": {"LBY", "AF", "+218", "LYD"},
+	"LI": {"LIE", "EU", "+423", "CHF"},
+	"LT": {"LTU", "EU", "+370", "EUR"},
+	"LU": {"LUX", "EU", "+352", "EUR"},
+	"MO": {"MAC", "AS", "+853", "MOP"},
+	"MG": {"MDG", "AF", "+261", "MGA"},
+	"MW": {"MWI", "AF", "+265", "MWK"},
+	"MY": {"MYS", "AS", "+60", "MYR"},
+	"MV": {"MDV", "AS", "+960", "MVR"},
+	"ML": {"MLI", "AF", "+223", "XOF"},
+	"MT": {"MLT", "EU", "+356", "EUR"},
+	"MH": {"MHL", "OC", "+692", "USD"},
+	"MQ": {"MTQ", "NA", "+596", "EUR"},
+	"MR": {"MRT", "AF", "+222", "MRU"},
+	"MU": {"MUS", "AF", "+230", "MUR"},
+	"YT": {"MYT", "AF", "+262", "EUR"},
+	"MX": {"MEX", "NA", "+52", "MXN"},
+	"FM": {"FSM", "OC", "+691", "USD"},
+	"MD": {"MDA", "EU", "+373", "MDL"},
+	"MC": {"MCO", "EU", "+377", "EUR"},
+	"MN": {"MNG", "AS", "+976", "MNT"},
+	"ME": {"MNE", "EU", "+382", "EUR"},
+	"MS": {"MSR", "NA", "+1-664", "XCD"},
+	"MA": {"MAR", "AF", "+212", "MAD"},
+	"MZ": {"MOZ", "AF", "+258", "MZN"},
+	"MM": {"MMR", "AS", "+95", "MMK"},
+	"NA": {"NAM", "AF", "+264", "NAD"},
+	"NR": {"NRU", "OC", "+674", "AUD"},
+	"NP": {"NPL", "AS", "+977", "NPR"},
+	"NL": {"NLD", "EU", "+31", "EUR"},
+	"NC": {"NCL", "OC", "+687", "XPF"},
+	"NZ": {"NZL", "OC", "+64", "NZD"},
+	"NI": {"NIC", "NA", "+505", "NIO"},
+	"NE": {"NER", "AF", "+227", "XOF"},
+	"NG": {"NGA", "AF", "+234", "NGN"},
+	"NU": {"NIU", "OC", "+683", "NZD"},
+	"NF": {"NFK", "OC", "+672", "AUD"},
+	"MK": {"MKD", "EU", "+389", "MKD"},
+	"MP": {"MNP", "OC", "+1-670", "USD"},
+	"NO": {"NOR", "EU", "+47", "NOK"},
+	"OM": {"OMN", "AS", "+968", "OMR"},
+	"PK": {"PAK", "AS", "+92", "PKR"},
+	"PW": {"PLW", "OC", "+680", "USD"},
+	"PS": {"PSE", "AS", "+970", "ILS"},
+	"PA": {"PAN", "NA", "+507", "PAB"},
+	"PG": {"PNG", "OC", "+675", "PGK"},
+	"PY": {"PRY", "SA", "+595", "PYG"},
+	"PE": {"PER", "SA", "+51", "PEN"},
+	"PH": {"PHL", "AS", "+63", "PHP"},
+	"PN": {"PCN", "OC", "+64", "NZD"},
+	"PL": {"POL", "EU", "+48", "PLN"},
+	"PT": {"PRT", "EU", "+351", "EUR"},
+	"PR": {"PRI", "NA", "+1-787", "USD"},
+	"QA": {"QAT", "AS", "+974", "QAR"},
+	"RE": {"REU", "AF", "+262", "EUR"},
+	"RO": {"ROU", "EU", "+40", "RON"},
+	"RU": {"RUS", "EU", "+7", "RUB"},
+	"RW": {"RWA", "AF", "+250", "RWF"},
+	"BL": {"BLM", "NA", "+590", "EUR"},
+	"SH": {"SHN", "AF", "+290", "SHP"},
+	"KN": {"KNA", "NA", "+1-869", "XCD"},
+	"LC": {"LCA", "NA", "+1-758", "XCD"},
+	"MF": {"MAF", "NA", "+590", "EUR"},
+	"PM": {"SPM", "NA", "+508", "EUR"},
+	"VC": {"VCT", "NA", "+1-784", "XCD"},
+	"WS": {"WSM", "OC", "+685", "WST"},
+	"SM": {"SMR", "EU", "+378", "EUR"},
+	"ST": {"STP", "AF", "+239", "STN"},
+	"SA": {"SAU", "AS", "+966", "SAR"},
+	"SN": {"SEN", "AF", "+221", "XOF"},
+	"RS": {"SRB", "EU", "+381", "RSD"},
+	"SC": {"SYC", "AF", "+248", "SCR"},
+	"SL": {"SLE", "AF", "+232", "SLE"},
+	"SG": {"SGP", "AS", "+65", "SGD"},
+	"SX": {"SXM", "NA", "+1-721", "ANG"},
+	"SK": {"SVK", "EU", "+421", "EUR"},
+	"SI": {"SVN", "EU", "+386", "EUR"},
+	"SB": {"SLB", "OC", "+677", "SBD"},
+	"SO": {"SOM", "AF", "+252", "SOS"},
+	"ZA": {"ZAF", "AF", "+27", "ZAR"},
+	"GS": {"SGS", "AN", "+500", "GBP"},
+	"SS": {"SSD", "AF", "+211", "SSP"},
+	"ES": {"ESP", "EU", "+34", "EUR"},
+	"LK": {"LKA", "AS", "+94", "LKR"},
+	"SD": {"SDN", "AF", "+249", "SDG"},
+	"SR": {"SUR", "SA", "+597", "SRD"},
+	"SJ": {"SJM", "EU", "+47", "NOK"},
+	"SE": {"SWE", "EU", "+46", "SEK"},
+	"CH": {"CHE", "EU", "+41", "CHF"},
+	"SY": {"SYR", "AS", "+963", "SYP"},
+	"TW": {"TWN", "AS", "+886", "TWD"},
+	"TJ": {"TJK", "AS", "+992", "TJS"},
+	"TZ": {"TZA", "AF", "+255", "TZS"},
+	"TH": {"THA", "AS", "+66", "THB"},
+	"TL": {"TLS", "AS", "+670", "USD"},
+	"TG": {"TGO", "AF", "+228", "XOF"},
+	"TK": {"TKL", "OC", "+690", "NZD"},
+	"TO": {"TON", "OC", "+676", "TOP"},
+	"TT": {"TTO", "NA", "+1-868", "TTD"},
+	"TN": {"TUN", "AF", "+216", "TND"},
+	"TR": {"TUR", "AS", "+90", "TRY"},
+	"TM": {"TKM", "AS", "+993", "TMT"},
+	"TC": {"TCA", "NA", "+1-649", "USD"},
+	"TV": {"TUV", "OC", "+688", "AUD"},
+	"UG": {"UGA", "AF", "+256", "UGX"},
+	"UA": {"UKR", "EU", "+380", "UAH"},
+	"AE": {"ARE", "AS", "+971", "AED"},
+	"GB": {"GBR", "EU", "+44", "GBP"},
+	"UM": {"UMI", "OC", "+1", "USD"},
+	"US": {"USA", "NA", "+1", "USD"},
+	"UY": {"URY", "SA", "+598", "UYU"},
+	"UZ": {"UZB", "AS", "+998", "UZS"},
+	"VU": {"VUT", "OC", "+678", "VUV"},
+	"VE": {"VEN", "SA", "+58", "VES"},
+	"VN": {"VNM", "AS", "+84", "VND"},
+	"VG": {"VGB", "NA", "+1-284", "USD"},
+	"VI": {"VIR", "NA", "+1-340", "USD"},
+	"WF": {"WLF", "OC", "+681", "XPF"},
+	"EH": {"ESH", "AF", "+212", "MAD"},
+	"YE": {"YEM", "AS", "+967", "YER"},
+	"ZM": {"ZMB", "AF", "+260", "ZMW"},
+	"ZW": {"ZWE", "AF", "+263", "ZWL"},
+}
@@ -0,0 +1,58 @@
+package mapping
+
+// Layers lists the result layers a node can belong to. The position in this
+// slice is what gets stored in the database.
+var Layers = []string{
+	"", // Index 0 reserved for unknown layers
+	"locality",
+	"neighbourhood",
+	"admin",
+	"street",
+}
+
+// PlaceLayer maps OSM place values to the layer they are indexed under.
+var PlaceLayer = map[string]string{
+	"city":              "locality",
+	"town":              "locality",
+	"village":           "locality",
+	"hamlet":            "locality",
+	"isolated_dwelling": "locality",
+	"farm":              "locality",
+	"borough":           "neighbourhood",
+	"suburb":            "neighbourhood",
+	"quarter":           "neighbourhood",
+	"neighbourhood":     "neighbourhood",
+	"city_block":        "neighbourhood",
+	"plot":              "neighbourhood",
+	"allotments":        "neighbourhood",
+}
+
+func GetLayerNumber(layer string) int {
+	for i := 1; i < len(Layers); i++ {
+		if Layers[i] == layer {
+			return i
+		}
+	}
+	return 0
+}
+
+func GetLayerName(number int) string {
+	if number < 0 || number >= len(Layers) {
+		return ""
+	}
+	return Layers[number]
+}
+
+// AddLayer registers a layer that is not part of the built-in list, e.g. one
+// defined by a tag filter, and returns its number. Only 255 layers fit into
+// a node, the number is 0 if the list is full.
+func AddLayer(layer string) int {
+	if number := GetLayerNumber(layer); number != 0 || layer == "" {
+		return number
+	}
+	if len(Layers) > 255 {
+		return 0
+	}
+	Layers = append(Layers, layer)
+	return len(Layers) - 1
+}
@@ -480,6 +480,30 @@ var PlaceRank = map[string]int{
 	"isolated_dwelling": 300,
 	"farm":              300,
 	"allotments":        250,
+
+	// Who's On First admin placetypes
+	"country":     950,
+	"macroregion": 880,
+	"region":      870,
+	"macrocounty": 780,
+	"county":      760,
+	"localadmin":  700,
+
+	// Streets
+	"street": 300,
+
+	// Natural features
+	"ocean":       940,
+	"sea":         900,
+	"marinearea":  880,
+	"archipelago": 750,
+	"island":      700,
+	"islet":       400,
+	"lake":        600,
+	"reservoir":   500,
+	"lagoon":      500,
+	"peak":        500,
+	"volcano":     550,
 }
 
 var Language3ToLanguage2 = map[string]string{
@@ -0,0 +1,104 @@
+package mapping
+
+// PlaceTypes lists the place types a node can have, OSM place values as well
+// as Who's On First placetypes. The position in this slice is what gets
+// stored in the database.
+var PlaceTypes = []string{
+	"", // Index 0 reserved for unknown place types
+	"city",
+	"borough",
+	"suburb",
+	"quarter",
+	"neighbourhood",
+	"city_block",
+	"plot",
+	"town",
+	"village",
+	"hamlet",
+	"isolated_dwelling",
+	"farm",
+	"allotments",
+	"country",
+	"macroregion",
+	"region",
+	"macrocounty",
+	"county",
+	"localadmin",
+	"street",
+	"ocean",
+	"marinearea",
+}
+
+// SearchablePlacetypes are the Who's On First placetypes imported as search
+// documents. Localities and neighbourhoods are left out, OSM already provides
+// them as place nodes.
+var SearchablePlacetypes = map[string]bool{
+	"country":     true,
+	"macroregion": true,
+	"region":      true,
+	"macrocounty": true,
+	"county":      true,
+	"localadmin":  true,
+}
+
+// NaturalPlacetypes are the Who's On First placetypes imported into the
+// natural features layer together with their polygons.
+var NaturalPlacetypes = map[string]bool{
+	"ocean":      true,
+	"marinearea": true,
+}
+
+func GetPlaceTypeNumber(placeType string) int {
+	for i := 1; i < len(PlaceTypes); i++ {
+		if PlaceTypes[i] == placeType {
+			return i
+		}
+	}
+	return 0
+}
+
+func GetPlaceTypeName(number int) string {
+	if number < 0 || number >= len(PlaceTypes) {
+		return ""
+	}
+	return PlaceTypes[number]
+}
+
+// AddPlaceType registers a place type that is not part of the built-in list,
+// e.g. a tag value matched by a tag filter, and returns its number. Only 255
+// place types fit into a node, the number is 0 if the list is full.
+func AddPlaceType(placeType string) int {
+	if number := GetPlaceTypeNumber(placeType); number != 0 || placeType == "" {
+		return number
+	}
+	if len(PlaceTypes) > 255 {
+		return 0
+	}
+	PlaceTypes = append(PlaceTypes, placeType)
+	return len(PlaceTypes) - 1
+}
+
+// OSMTypes lists the OSM object types a node can originate from. Index 0 is
+// used for documents that don't come from OSM.
+var OSMTypes = []string{
+	"",
+	"node",
+	"way",
+	"relation",
+}
+
+func GetOSMTypeNumber(osmType string) int {
+	for i := 1; i < len(OSMTypes); i++ {
+		if OSMTypes[i] == osmType {
+			return i
+		}
+	}
+	return 0
+}
+
+func GetOSMTypeName(number int) string {
+	if number < 0 || number >= len(OSMTypes) {
+		return ""
+	}
+	return OSMTypes[number]
+}
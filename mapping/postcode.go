@@ -0,0 +1,80 @@
+package mapping
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PostcodePatterns are the formats of the postcodes of a country, matched
+// against the normalized postcode. Countries without a pattern accept any
+// postcode.
+var PostcodePatterns = map[string]*regexp.Regexp{
+	"AT": regexp.MustCompile(`^\d{4}$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"BE": regexp.MustCompile(`^\d{4}$`),
+	"BR": regexp.MustCompile(`^\d{5}-?\d{3}$`),
+	"CA": regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`),
+	"CH": regexp.MustCompile(`^\d{4}$`),
+	"CN": regexp.MustCompile(`^\d{6}$`),
+	"CZ": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"DK": regexp.MustCompile(`^\d{4}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"FI": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`^(GIR ?0AA|[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2})$`),
+	"GR": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"HU": regexp.MustCompile(`^\d{4}$`),
+	"IE": regexp.MustCompile(`^[A-Z]\d[\dW] ?[A-Z\d]{4}$`),
+	"IN": regexp.MustCompile(`^\d{6}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+	"LU": regexp.MustCompile(`^(L-)?\d{4}$`),
+	"MX": regexp.MustCompile(`^\d{5}$`),
+	"NL": regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`),
+	"NO": regexp.MustCompile(`^\d{4}$`),
+	"NZ": regexp.MustCompile(`^\d{4}$`),
+	"PL": regexp.MustCompile(`^\d{2}-\d{3}$`),
+	"PT": regexp.MustCompile(`^\d{4}-\d{3}$`),
+	"RU": regexp.MustCompile(`^\d{6}$`),
+	"SE": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"SK": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+}
+
+// NormalizePostcode upper cases a postcode and collapses whitespace, e.g.
+// "sw1a  1aa" becomes "SW1A 1AA".
+func NormalizePostcode(code string) string {
+	return strings.Join(strings.Fields(strings.ToUpper(code)), " ")
+}
+
+// PostcodeKey is the lookup key of a postcode, the normalized postcode
+// without spaces, so "SW1A 1AA" and "SW1A1AA" are the same.
+func PostcodeKey(code string) string {
+	return strings.ReplaceAll(NormalizePostcode(code), " ", "")
+}
+
+// ValidPostcode reports whether a postcode matches the format of a country.
+// Postcodes of countries without a pattern only have to contain a digit.
+func ValidPostcode(country string, code string) bool {
+	code = NormalizePostcode(code)
+	if pattern, ok := PostcodePatterns[country]; ok {
+		return pattern.MatchString(code)
+	}
+	return len(code) <= 10 && strings.ContainsAny(code, "0123456789")
+}
+
+// PostcodeCountries returns the countries whose postcode format matches the
+// query, sorted by country code.
+func PostcodeCountries(query string) []string {
+	code := NormalizePostcode(query)
+	countries := make([]string, 0)
+	for country, pattern := range PostcodePatterns {
+		if pattern.MatchString(code) {
+			countries = append(countries, country)
+		}
+	}
+	sort.Strings(countries)
+	return countries
+}
@@ -0,0 +1,60 @@
+package mapping
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidPostcode(t *testing.T) {
+	tests := []struct {
+		country string
+		code    string
+		want    bool
+	}{
+		{"DE", "10115", true},
+		{"DE", "1011", false},
+		{"GB", "SW1A 1AA", true},
+		{"GB", "sw1a  1aa", true},
+		{"GB", "SW1A1AA", true},
+		{"GB", "10115", false},
+		{"NL", "1012 AB", true},
+		{"PL", "00-950", true},
+		{"US", "20500-0003", true},
+		// Countries without a pattern only need a digit
+		{"AE", "12345", true},
+		{"AE", "Dubai", false},
+	}
+	for _, test := range tests {
+		if got := ValidPostcode(test.country, test.code); got != test.want {
+			t.Errorf("ValidPostcode(%q, %q) = %v, want %v", test.country, test.code, got, test.want)
+		}
+	}
+}
+
+func TestPostcodeKey(t *testing.T) {
+	for _, code := range []string{"SW1A 1AA", "sw1a1aa", " SW1A  1aa "} {
+		if got := PostcodeKey(code); got != "SW1A1AA" {
+			t.Errorf("PostcodeKey(%q) = %q, want %q", code, got, "SW1A1AA")
+		}
+	}
+	if got := NormalizePostcode("sw1a  1aa"); got != "SW1A 1AA" {
+		t.Errorf("NormalizePostcode = %q, want %q", got, "SW1A 1AA")
+	}
+}
+
+func TestPostcodeCountries(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"sw1a 1aa", []string{"GB"}},
+		{"1012 AB", []string{"NL"}},
+		{"10115", []string{"CZ", "DE", "ES", "FI", "FR", "GR", "IT", "MX", "SE", "SK", "US"}},
+		{"Berlin", []string{}},
+	}
+	for _, test := range tests {
+		if got := PostcodeCountries(test.query); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("PostcodeCountries(%q) = %v, want %v", test.query, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package mapping
+
+// StreetHighways are the highway values indexed as streets. Footways, paths
+// and similar ways are left out, they are rarely used as addresses.
+var StreetHighways = map[string]bool{
+	"motorway":       true,
+	"trunk":          true,
+	"primary":        true,
+	"secondary":      true,
+	"tertiary":       true,
+	"unclassified":   true,
+	"residential":    true,
+	"living_street":  true,
+	"pedestrian":     true,
+	"service":        true,
+	"road":           true,
+	"motorway_link":  true,
+	"trunk_link":     true,
+	"primary_link":   true,
+	"secondary_link": true,
+	"tertiary_link":  true,
+}
@@ -0,0 +1,278 @@
+package parser
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Coordinate formats, see Coordinate.Format.
+const (
+	FormatDecimal = "decimal"
+	FormatDMS     = "dms"
+	FormatDDM     = "ddm"
+	FormatOLC     = "olc"
+	FormatGeohash = "geohash"
+	FormatUTM     = "utm"
+	FormatMGRS    = "mgrs"
+)
+
+// Coordinate is a point written in one of the coordinate formats, e.g.
+// "52.5163, 13.3777", "52°31'N 13°24'E", "9F4MGCC2+RX", "u33db2m" or
+// "33U UU 89434 19806".
+type Coordinate struct {
+	Lat    float64 `json:"lat"`
+	Lng    float64 `json:"lng"`
+	Format string  `json:"format"`
+	// Accuracy is the size in metres of the cell a plus code, geohash or
+	// MGRS reference stands for, 0 for exact coordinates. The point is the
+	// centre of the cell.
+	Accuracy float64 `json:"accuracy,omitempty"`
+	// Reference is the place a short plus code is relative to, e.g.
+	// "Berlin" for "GCC2+RX Berlin". The point is unknown until Recover is
+	// called with its position.
+	Reference string `json:"reference,omitempty"`
+
+	shortCode string
+}
+
+// IsShort reports whether the coordinate is a short plus code that needs a
+// reference position, see Recover.
+func (c *Coordinate) IsShort() bool {
+	return c.shortCode != ""
+}
+
+// Recover sets the point of a short plus code to the nearest matching cell
+// of the reference position.
+func (c *Coordinate) Recover(lat, lng float64) bool {
+	if c.shortCode == "" {
+		return false
+	}
+	point, accuracy, ok := recoverOLC(c.shortCode, lat, lng)
+	if !ok {
+		return false
+	}
+	c.Lat, c.Lng, c.Accuracy = point[0], point[1], accuracy
+	c.shortCode = ""
+	return true
+}
+
+// ParseCoordinate recognizes a query that is a coordinate: decimal degrees
+// in either order, with hemispheres or "lat"/"lng" hints, degrees with
+// minutes and seconds, Open Location Codes, geohashes, UTM and MGRS.
+func ParseCoordinate(query string) (Coordinate, bool) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return Coordinate{}, false
+	}
+
+	for _, parse := range []func(string) (Coordinate, bool){
+		parseOLC,
+		parseMGRS,
+		parseUTM,
+		parseGeohash,
+		parseDegrees,
+	} {
+		if c, ok := parse(query); ok {
+			return c, true
+		}
+	}
+	return Coordinate{}, false
+}
+
+// validLatLng reports whether a point is on the globe.
+func validLatLng(lat, lng float64) bool {
+	return !math.IsNaN(lat) && !math.IsNaN(lng) && math.Abs(lat) <= 90 && math.Abs(lng) <= 180
+}
+
+var (
+	// decimalCommaPattern matches "52,5163 13,3777", written with decimal
+	// commas
+	decimalCommaPattern = regexp.MustCompile(`^([-+]?\d{1,3},\d+)[\s;]+([-+]?\d{1,3},\d+)$`)
+	// degreeSymbols are replaced by the markers the tokenizer knows
+	degreeSymbols = strings.NewReplacer(
+		"°", " ° ", "º", " ° ", "˚", " ° ",
+		"″", ` " `, "”", ` " `, "“", ` " `, "''", ` " `, `"`, ` " `,
+		"′", " ' ", "’", " ' ", "‘", " ' ", "'", " ' ",
+		",", " , ", ";", " , ", "=", " ", ":", " ",
+	)
+	degreeTokenPattern = regexp.MustCompile(`[-+]?\d+(?:\.\d+)?|[A-Z]+|[°'",]`)
+)
+
+// degreeComponent is one half of a coordinate in degrees, with the
+// hemisphere or hint that tells which half it is.
+type degreeComponent struct {
+	values     []float64
+	decimals   []bool
+	negative   bool
+	axis       byte // 'N' for latitude, 'E' for longitude, 0 if unknown
+	markers    int
+	hemisphere bool
+}
+
+func (d *degreeComponent) degrees() (float64, bool) {
+	if len(d.values) == 0 || len(d.values) > 3 {
+		return 0, false
+	}
+
+	value := d.values[0]
+	for i, v := range d.values[1:] {
+		// Only the last value may have decimals, minutes and seconds are
+		// below 60
+		if d.decimals[i] || v >= 60 {
+			return 0, false
+		}
+		value += v / math.Pow(60, float64(i+1))
+	}
+	if d.negative {
+		value = -value
+	}
+	return value, true
+}
+
+// parseDegrees recognizes decimal degrees and degrees with minutes and
+// seconds, e.g. "52.5163, 13.3777", "13.3777E 52.5163N", "lat 52.5 lng
+// 13.4", "geo:52.5,13.4" or "52°31'12\"N 13°24'36\"E".
+func parseDegrees(query string) (Coordinate, bool) {
+	// "geo:52.5163,13.3777;u=35" URIs
+	if len(query) > 4 && strings.EqualFold(query[:4], "geo:") {
+		query, _, _ = strings.Cut(query[4:], ";")
+	}
+	if m := decimalCommaPattern.FindStringSubmatch(query); m != nil {
+		query = strings.ReplaceAll(m[1], ",", ".") + " " + strings.ReplaceAll(m[2], ",", ".")
+	}
+
+	text := degreeSymbols.Replace(strings.ToUpper(query))
+	tokens := degreeTokenPattern.FindAllString(text, -1)
+	if len(strings.Join(tokens, "")) != len(strings.Join(strings.Fields(text), "")) {
+		// Anything but numbers, markers and hints is a name
+		return Coordinate{}, false
+	}
+
+	components := make([]*degreeComponent, 0, 2)
+	current := &degreeComponent{}
+	next := func() {
+		if len(current.values) > 0 || current.axis != 0 {
+			components = append(components, current)
+		}
+		current = &degreeComponent{}
+	}
+	for i, token := range tokens {
+		switch token {
+		case ",":
+			next()
+			continue
+		case "°", "'", `"`:
+			current.markers++
+			continue
+		case "N", "S", "E", "W":
+			axis := byte('N')
+			if token == "E" || token == "W" {
+				axis = 'E'
+			}
+			// A hemisphere after the numbers ends the component, before
+			// them it starts one
+			if len(current.values) > 0 && !current.hemisphere {
+				current.axis, current.hemisphere = axis, true
+				current.negative = current.negative != (token == "S" || token == "W")
+				next()
+			} else {
+				next()
+				current.axis, current.hemisphere = axis, true
+				current.negative = token == "S" || token == "W"
+			}
+			continue
+		case "LAT", "LATITUDE":
+			next()
+			current.axis = 'N'
+			continue
+		case "LNG", "LON", "LONG", "LONGITUDE":
+			next()
+			current.axis = 'E'
+			continue
+		}
+
+		value, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return Coordinate{}, false
+		}
+		// A degree value or a sign starts the next component, e.g. the 13
+		// of "52°31' 13°24'" or "52.5 -13.4"
+		signed := strings.HasPrefix(token, "-") || strings.HasPrefix(token, "+")
+		if len(current.values) > 0 && (signed || (i+1 < len(tokens) && tokens[i+1] == "°")) {
+			next()
+		}
+		if value < 0 {
+			current.negative = !current.negative
+			value = -value
+		}
+		current.values = append(current.values, value)
+		current.decimals = append(current.decimals, strings.Contains(token, "."))
+	}
+	next()
+
+	// Plain numbers "52.5 13.4" without separator are two components
+	if len(components) == 1 && len(components[0].values) == 2 && components[0].markers == 0 && components[0].axis == 0 {
+		first := components[0]
+		components = []*degreeComponent{
+			{values: first.values[:1], decimals: first.decimals[:1], negative: first.negative},
+			{values: first.values[1:], decimals: first.decimals[1:]},
+		}
+	}
+	if len(components) != 2 {
+		return Coordinate{}, false
+	}
+
+	a, b := components[0], components[1]
+	// Minutes and seconds need degree markers or a hemisphere of their own,
+	// the "0 0" of "31N 0 0" is the rest of a UTM reference
+	for _, component := range components {
+		if len(component.values) > 1 && component.markers == 0 && !component.hemisphere {
+			return Coordinate{}, false
+		}
+	}
+	hinted := a.axis != 0 || b.axis != 0 || a.markers > 0 || b.markers > 0
+	decimal := false
+	for _, component := range components {
+		for _, d := range component.decimals {
+			decimal = decimal || d
+		}
+	}
+	// "52, 13" could be anything, a coordinate has decimals or hints
+	if !hinted && !decimal {
+		return Coordinate{}, false
+	}
+
+	first, ok1 := a.degrees()
+	second, ok2 := b.degrees()
+	if !ok1 || !ok2 {
+		return Coordinate{}, false
+	}
+
+	lat, lng := first, second
+	switch {
+	case a.axis == 'E' || b.axis == 'N':
+		lat, lng = second, first
+	case a.axis == 0 && b.axis == 0 && math.Abs(first) > 90 && math.Abs(second) <= 90:
+		// "13.3777, 52.5163" can only be longitude first if it is
+		// outside the latitude range
+		lat, lng = second, first
+	}
+	if a.axis != 0 && a.axis == b.axis {
+		return Coordinate{}, false
+	}
+	if !validLatLng(lat, lng) {
+		return Coordinate{}, false
+	}
+
+	format := FormatDecimal
+	if len(a.values) > 1 || len(b.values) > 1 {
+		format = FormatDDM
+		if len(a.values) > 2 || len(b.values) > 2 {
+			format = FormatDMS
+		}
+	}
+
+	return Coordinate{Lat: lat, Lng: lng, Format: format}, true
+}
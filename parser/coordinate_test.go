@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"math"
+	"testing"
+)
+
+// coordinateTest is a query with the point, format and cell size it has to
+// be parsed to.
+type coordinateTest struct {
+	query    string
+	lat      float64
+	lng      float64
+	format   string
+	accuracy float64
+}
+
+// testCoordinates parses the queries and compares the points to 1e-5
+// degrees, about a metre, and the cell sizes to a metre.
+func testCoordinates(t *testing.T, tests []coordinateTest) {
+	t.Helper()
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			c, ok := ParseCoordinate(test.query)
+			if !ok {
+				t.Fatal("not recognized")
+			}
+			if c.Format != test.format {
+				t.Errorf("format %q, want %q", c.Format, test.format)
+			}
+			if math.Abs(c.Lat-test.lat) > 1e-5 || math.Abs(c.Lng-test.lng) > 1e-5 {
+				t.Errorf("got %.6f, %.6f, want %.6f, %.6f", c.Lat, c.Lng, test.lat, test.lng)
+			}
+			if math.Abs(c.Accuracy-test.accuracy) > 1 {
+				t.Errorf("accuracy %.1f, want %.1f", c.Accuracy, test.accuracy)
+			}
+		})
+	}
+}
+
+// testNotCoordinates checks that the queries aren't taken for coordinates.
+func testNotCoordinates(t *testing.T, queries []string) {
+	t.Helper()
+	for _, query := range queries {
+		if c, ok := ParseCoordinate(query); ok {
+			t.Errorf("%q parsed as %s %.6f, %.6f", query, c.Format, c.Lat, c.Lng)
+		}
+	}
+}
+
+func TestParseDegrees(t *testing.T) {
+	testCoordinates(t, []coordinateTest{
+		{"52.5163, 13.3777", 52.5163, 13.3777, FormatDecimal, 0},
+		{"52.5163 13.3777", 52.5163, 13.3777, FormatDecimal, 0},
+		{"52,5163 13,3777", 52.5163, 13.3777, FormatDecimal, 0},
+		{"13.3777E 52.5163N", 52.5163, 13.3777, FormatDecimal, 0},
+		{"lat=52.5163 lng=13.3777", 52.5163, 13.3777, FormatDecimal, 0},
+		{"geo:52.5163,13.3777;u=35", 52.5163, 13.3777, FormatDecimal, 0},
+		{"-33.8568, 151.2153", -33.8568, 151.2153, FormatDecimal, 0},
+		// Longitude first if the first value can't be a latitude
+		{"151.2153 -33.8568", -33.8568, 151.2153, FormatDecimal, 0},
+		{"52°31'N 13°24'E", 52.516667, 13.4, FormatDDM, 0},
+		{"N 52° 31.0' E 13° 22.6'", 52.516667, 13.376667, FormatDDM, 0},
+		{"52 31 N 13 24 E", 52.516667, 13.4, FormatDDM, 0},
+		{`52°31'12.5"N 13°24'36"E`, 52.520139, 13.41, FormatDMS, 0},
+		{`33°51'24"S 151°12'55"E`, -33.856667, 151.215278, FormatDMS, 0},
+	})
+}
+
+func TestParseNotCoordinate(t *testing.T) {
+	testNotCoordinates(t, []string{
+		"",
+		"52, 13",
+		"10115",
+		"bremen",
+		"Berlin",
+		"Route 66",
+		"52.5N 13.4N",
+		"91.5, 190.5",
+		"52°61'N 13°24'E",
+		// Minutes without degree markers or hemisphere
+		"31N 0 0",
+		"52.5 31 13",
+	})
+}
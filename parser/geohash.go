@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+)
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// geohashPrefixes mark a query as geohash, e.g. "geohash:u33db2m".
+var geohashPrefixes = []string{"geohash:", "gh:"}
+
+// parseGeohash recognizes a geohash like "u33db2m". Without a prefix it
+// needs 6 to 12 characters with at least two digits and one letter, so
+// names like "bremen" aren't taken for one.
+func parseGeohash(query string) (Coordinate, bool) {
+	hash := strings.ToLower(strings.TrimSpace(query))
+	prefixed := false
+	for _, prefix := range geohashPrefixes {
+		if strings.HasPrefix(hash, prefix) {
+			hash, prefixed = strings.TrimSpace(hash[len(prefix):]), true
+			break
+		}
+	}
+
+	if len(hash) == 0 || len(hash) > 12 {
+		return Coordinate{}, false
+	}
+	digits, letters := 0, 0
+	for _, r := range hash {
+		if !strings.ContainsRune(geohashAlphabet, r) {
+			return Coordinate{}, false
+		}
+		if unicode.IsDigit(r) {
+			digits++
+		} else {
+			letters++
+		}
+	}
+	if !prefixed && (len(hash) < 6 || digits < 2 || letters == 0) {
+		return Coordinate{}, false
+	}
+
+	minLat, maxLat, minLng, maxLng := -90.0, 90.0, -180.0, 180.0
+	even := true
+	for _, r := range hash {
+		bits := strings.IndexRune(geohashAlphabet, r)
+		for mask := 16; mask > 0; mask >>= 1 {
+			if even {
+				mid := (minLng + maxLng) / 2
+				if bits&mask != 0 {
+					minLng = mid
+				} else {
+					maxLng = mid
+				}
+			} else {
+				mid := (minLat + maxLat) / 2
+				if bits&mask != 0 {
+					minLat = mid
+				} else {
+					maxLat = mid
+				}
+			}
+			even = !even
+		}
+	}
+
+	lat, lng := (minLat+maxLat)/2, (minLng+maxLng)/2
+	return Coordinate{
+		Lat:      lat,
+		Lng:      lng,
+		Format:   FormatGeohash,
+		Accuracy: cellSize(lat, maxLat-minLat, maxLng-minLng),
+	}, true
+}
@@ -0,0 +1,21 @@
+package parser
+
+import "testing"
+
+func TestParseGeohash(t *testing.T) {
+	testCoordinates(t, []coordinateTest{
+		{"geohash:ezs42", 42.60498046875, -5.60302734375, FormatGeohash, 4892},
+		{"gh:ezs42", 42.60498046875, -5.60302734375, FormatGeohash, 4892},
+		{"u33db2m", 52.51670837, 13.37791443, FormatGeohash, 152.9},
+		{"U33DB2M", 52.51670837, 13.37791443, FormatGeohash, 152.9},
+	})
+	testNotCoordinates(t, []string{
+		// Short or without digits only with a prefix
+		"ezs42",
+		"dr5ru",
+		"bremen",
+		"geohash:",
+		"geohash:u33dba",
+		"u33db2mu33db2m",
+	})
+}
@@ -0,0 +1,114 @@
+package parser
+
+// streetTypes are the words naming the kind of a street, lower cased. A
+// street part contains one of them as a separate word, e.g. "Baker Street"
+// or "Rue de Rivoli". Suffix types end the street name, so text after them
+// is not part of the street.
+var streetTypes = map[string]bool{
+	// English
+	"street": true, "st": true, "st.": true, "road": true, "rd": true, "rd.": true,
+	"avenue": true, "ave": true, "ave.": true, "lane": true, "ln": true, "drive": true,
+	"dr": true, "dr.": true, "boulevard": true, "blvd": true, "blvd.": true, "court": true,
+	"ct": true, "place": true, "pl": true, "square": true, "sq": true, "terrace": true,
+	"way": true, "close": true, "crescent": true, "highway": true, "hwy": true,
+	"parkway": true, "pkwy": true, "circle": true, "gardens": true, "row": true, "mews": true,
+	"grove": true, "walk": true, "parade": true,
+	// German and Dutch
+	"straße": true, "strasse": true, "str.": true, "weg": true, "gasse": true, "platz": true,
+	"allee": true, "damm": true, "ufer": true, "chaussee": true, "straat": true, "laan": true,
+	"gracht": true, "plein": true, "kade": true,
+	// French
+	"rue": true, "bd": true, "chemin": true, "impasse": true, "allée": true,
+	"quai": true, "route": true, "cours": true,
+	// Spanish, Catalan and Portuguese
+	"calle": true, "c/": true, "avenida": true, "avda": true, "avda.": true, "paseo": true,
+	"plaza": true, "camino": true, "carrer": true, "rua": true, "travessa": true, "praça": true,
+	// Italian
+	"via": true, "viale": true, "piazza": true, "corso": true, "largo": true, "vicolo": true,
+	// Polish, Czech and Russian
+	"ulica": true, "ul.": true, "aleja": true, "al.": true, "ulice": true, "náměstí": true,
+	"улица": true, "ул.": true, "проспект": true, "пр.": true, "переулок": true,
+	// Nordic
+	"gatan": true, "vägen": true, "vej": true, "gade": true, "veien": true,
+}
+
+// streetSuffixes end the compound street names of languages that join the
+// street type to the name, e.g. "Hauptstraße" or "Kalverstraat".
+var streetSuffixes = []string{
+	"straße", "strasse", "str.", "weg", "gasse", "platz", "allee", "damm", "ufer",
+	"straat", "laan", "gracht", "plein", "kade", "vej", "gade", "gatan", "vägen",
+	"veien", "utca", "katu",
+}
+
+// prefixStreetTypes start the street name, e.g. "Rue de Rivoli". The other
+// street types end it, e.g. "Baker Street", and only count after a word of
+// the name, so "St. Gallen" is no street.
+var prefixStreetTypes = map[string]bool{
+	"avenue": true, "boulevard": true, "place": true, "rue": true, "bd": true, "chemin": true, "impasse": true, "allée": true, "quai": true,
+	"route": true, "cours": true, "calle": true, "c/": true, "avenida": true, "avda": true,
+	"avda.": true, "paseo": true, "plaza": true, "camino": true, "carrer": true, "rua": true,
+	"travessa": true, "praça": true, "via": true, "viale": true, "piazza": true, "corso": true,
+	"largo": true, "vicolo": true, "ulica": true, "ul.": true, "aleja": true, "al.": true,
+	"ulice": true, "náměstí": true, "улица": true, "ул.": true, "проспект": true, "пр.": true,
+	"переулок": true,
+}
+
+// unitTypes introduce the flat or unit within a building, e.g. "Apt 4" or
+// "Whg. 3".
+var unitTypes = map[string]bool{
+	"apt": true, "apt.": true, "apartment": true, "flat": true, "unit": true, "suite": true,
+	"ste": true, "ste.": true, "room": true, "rm": true, "floor": true, "fl": true,
+	"wohnung": true, "whg": true, "whg.": true, "app": true, "app.": true,
+	"appartement": true, "piso": true, "interno": true, "int.": true,
+}
+
+// numberPrefixes introduce a house number, e.g. "No. 5" or "Nr. 12".
+var numberPrefixes = map[string]bool{
+	"no": true, "no.": true, "nº": true, "n°": true, "nr": true, "nr.": true, "#": true,
+}
+
+// numberSuffixes follow a house number as a separate word, e.g. "5 bis".
+var numberSuffixes = map[string]bool{
+	"bis": true, "ter": true, "quater": true,
+}
+
+// countryNames are the English and native names and common abbreviations
+// of countries, lower cased. ISO 3166 alpha-2 and alpha-3 codes are
+// recognized as well, see countryCode.
+var countryNames = map[string]string{
+	"afghanistan": "AF", "albania": "AL", "algeria": "DZ", "andorra": "AD", "angola": "AO",
+	"argentina": "AR", "armenia": "AM", "australia": "AU", "austria": "AT", "österreich": "AT",
+	"azerbaijan": "AZ", "bahamas": "BS", "bangladesh": "BD", "belarus": "BY", "belgium": "BE",
+	"belgië": "BE", "belgique": "BE", "belgien": "BE", "bolivia": "BO", "bosnia and herzegovina": "BA",
+	"brazil": "BR", "brasil": "BR", "bulgaria": "BG", "cambodia": "KH", "cameroon": "CM",
+	"canada": "CA", "chile": "CL", "china": "CN", "中国": "CN", "colombia": "CO",
+	"costa rica": "CR", "croatia": "HR", "hrvatska": "HR", "cuba": "CU", "cyprus": "CY",
+	"czech republic": "CZ", "czechia": "CZ", "česko": "CZ", "denmark": "DK", "danmark": "DK",
+	"dominican republic": "DO", "ecuador": "EC", "egypt": "EG", "estonia": "EE", "eesti": "EE",
+	"ethiopia": "ET", "finland": "FI", "suomi": "FI", "france": "FR", "frankreich": "FR",
+	"germany": "DE", "deutschland": "DE", "allemagne": "DE", "greece": "GR", "ελλάδα": "GR",
+	"guatemala": "GT", "hungary": "HU", "magyarország": "HU", "iceland": "IS", "ísland": "IS",
+	"india": "IN", "indonesia": "ID", "iran": "IR", "iraq": "IQ", "ireland": "IE", "éire": "IE",
+	"israel": "IL", "italy": "IT", "italia": "IT", "italien": "IT", "japan": "JP", "日本": "JP",
+	"jordan": "JO", "kazakhstan": "KZ", "kenya": "KE", "kosovo": "XK", "latvia": "LV",
+	"latvija": "LV", "lebanon": "LB", "liechtenstein": "LI", "lithuania": "LT", "lietuva": "LT",
+	"luxembourg": "LU", "luxemburg": "LU", "malaysia": "MY", "malta": "MT", "mexico": "MX",
+	"méxico": "MX", "moldova": "MD", "monaco": "MC", "montenegro": "ME", "morocco": "MA",
+	"nepal": "NP", "netherlands": "NL", "the netherlands": "NL", "nederland": "NL",
+	"holland": "NL", "niederlande": "NL", "new zealand": "NZ", "nigeria": "NG",
+	"north macedonia": "MK", "norway": "NO", "norge": "NO", "pakistan": "PK", "panama": "PA",
+	"paraguay": "PY", "peru": "PE", "perú": "PE", "philippines": "PH", "poland": "PL",
+	"polska": "PL", "polen": "PL", "portugal": "PT", "qatar": "QA", "romania": "RO",
+	"românia": "RO", "russia": "RU", "russian federation": "RU", "россия": "RU",
+	"saudi arabia": "SA", "serbia": "RS", "srbija": "RS", "singapore": "SG", "slovakia": "SK",
+	"slovensko": "SK", "slovenia": "SI", "slovenija": "SI", "south africa": "ZA",
+	"south korea": "KR", "korea": "KR", "대한민국": "KR", "spain": "ES", "españa": "ES",
+	"spanien": "ES", "sri lanka": "LK", "sweden": "SE", "sverige": "SE", "schweden": "SE",
+	"switzerland": "CH", "schweiz": "CH", "suisse": "CH", "svizzera": "CH", "taiwan": "TW",
+	"thailand": "TH", "tunisia": "TN", "turkey": "TR", "türkiye": "TR", "türkei": "TR",
+	"ukraine": "UA", "україна": "UA", "united arab emirates": "AE", "uae": "AE",
+	"united kingdom": "GB", "uk": "GB", "u.k.": "GB", "great britain": "GB", "england": "GB",
+	"scotland": "GB", "wales": "GB", "northern ireland": "GB", "united states": "US",
+	"united states of america": "US", "usa": "US", "u.s.a.": "US", "u.s.": "US",
+	"america": "US", "uruguay": "UY", "venezuela": "VE", "vietnam": "VN", "viet nam": "VN",
+}
@@ -0,0 +1,198 @@
+package parser
+
+import (
+	"math"
+	"strings"
+)
+
+// Open Location Code ("plus code") constants, see
+// https://github.com/google/open-location-code/blob/main/docs/specification.md
+const (
+	olcAlphabet      = "23456789CFGHJMPQRVWX"
+	olcSeparator     = '+'
+	olcSeparatorPos  = 8
+	olcPadding       = '0'
+	olcPairLength    = 10
+	olcGridRows      = 5
+	olcGridColumns   = 4
+	olcMaxCodeLength = 15
+)
+
+// parseOLC recognizes a full plus code, e.g. "9F4MGCC2+RX", or a short one
+// followed or preceded by its reference place, e.g. "GCC2+RX Berlin".
+func parseOLC(query string) (Coordinate, bool) {
+	tokens := strings.Fields(strings.ReplaceAll(query, ",", " "))
+	for i, token := range tokens {
+		code := strings.ToUpper(token)
+		if !strings.ContainsRune(code, olcSeparator) || !validOLC(code) {
+			continue
+		}
+		reference := strings.TrimSpace(strings.Join(append(append([]string{}, tokens[:i]...), tokens[i+1:]...), " "))
+
+		if strings.IndexRune(code, olcSeparator) == olcSeparatorPos {
+			// A full code needs no reference
+			if reference != "" {
+				return Coordinate{}, false
+			}
+			point, accuracy, ok := decodeOLC(code)
+			if !ok {
+				return Coordinate{}, false
+			}
+			return Coordinate{Lat: point[0], Lng: point[1], Format: FormatOLC, Accuracy: accuracy}, true
+		}
+
+		return Coordinate{Format: FormatOLC, Reference: strings.Trim(reference, ", "), shortCode: code}, true
+	}
+	return Coordinate{}, false
+}
+
+// validOLC checks the syntax of a full or short plus code.
+func validOLC(code string) bool {
+	separator := strings.IndexRune(code, olcSeparator)
+	if separator < 0 || separator != strings.LastIndexByte(code, olcSeparator) ||
+		separator > olcSeparatorPos || separator%2 == 1 || separator < 2 {
+		return false
+	}
+
+	padding := strings.IndexRune(code, olcPadding)
+	if padding >= 0 {
+		// Padded codes are full codes like "8FVC0000+" and end after the
+		// separator
+		if separator < olcSeparatorPos || padding == 0 || padding%2 == 1 || separator != len(code)-1 {
+			return false
+		}
+		for _, r := range code[padding:separator] {
+			if r != olcPadding {
+				return false
+			}
+		}
+	}
+
+	if separator < olcSeparatorPos && len(code) == separator+1 {
+		// Short codes need the digits after the separator
+		return false
+	}
+	if len(code) > separator+1 && len(code)-separator-1 < 2 {
+		return false
+	}
+	if len(code) > olcMaxCodeLength+1 {
+		return false
+	}
+
+	for i, r := range code {
+		if i == separator || (padding >= 0 && i >= padding && i < separator) {
+			continue
+		}
+		if !strings.ContainsRune(olcAlphabet, r) {
+			return false
+		}
+	}
+
+	if separator == olcSeparatorPos {
+		// The first pair has to be on the globe
+		if strings.IndexByte(olcAlphabet, code[0])*20 >= 180 || strings.IndexByte(olcAlphabet, code[1])*20 >= 360 {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeOLC returns the centre of the cell of a full plus code and the size
+// of the cell in metres.
+func decodeOLC(code string) ([2]float64, float64, bool) {
+	digits := strings.NewReplacer(string(olcSeparator), "", string(olcPadding), "").Replace(code)
+	if len(digits) > olcMaxCodeLength {
+		digits = digits[:olcMaxCodeLength]
+	}
+
+	lat, lng := -90.0, -180.0
+	latSize, lngSize := 400.0, 400.0
+	for i := 0; i < len(digits) && i < olcPairLength; i += 2 {
+		latSize, lngSize = latSize/20, lngSize/20
+		lat += float64(strings.IndexByte(olcAlphabet, digits[i])) * latSize
+		if i+1 < len(digits) {
+			lng += float64(strings.IndexByte(olcAlphabet, digits[i+1])) * lngSize
+		}
+	}
+	for i := olcPairLength; i < len(digits); i++ {
+		latSize, lngSize = latSize/olcGridRows, lngSize/olcGridColumns
+		digit := strings.IndexByte(olcAlphabet, digits[i])
+		lat += float64(digit/olcGridColumns) * latSize
+		lng += float64(digit%olcGridColumns) * lngSize
+	}
+
+	centre := [2]float64{math.Min(lat+latSize/2, 90), lng + lngSize/2}
+	if !validLatLng(centre[0], centre[1]) {
+		return centre, 0, false
+	}
+	return centre, cellSize(centre[0], latSize, lngSize), true
+}
+
+// encodeOLCPrefix returns the first length digits of the plus code of a
+// point, length has to be even and at most 8.
+func encodeOLCPrefix(lat, lng float64, length int) string {
+	lat = math.Min(math.Max(lat, -90), 90) + 90
+	lng = normalizeLng(lng) + 180
+	if lat >= 180 {
+		lat = 180 - 1e-9
+	}
+
+	var code strings.Builder
+	size := 20.0
+	for code.Len() < length {
+		latDigit, lngDigit := int(lat/size), int(lng/size)
+		lat -= float64(latDigit) * size
+		lng -= float64(lngDigit) * size
+		code.WriteByte(olcAlphabet[latDigit])
+		code.WriteByte(olcAlphabet[lngDigit])
+		size /= 20
+	}
+	return code.String()
+}
+
+// recoverOLC returns the centre of the cell of a short plus code nearest to
+// the reference position and the size of the cell in metres.
+func recoverOLC(code string, refLat, refLng float64) ([2]float64, float64, bool) {
+	padding := olcSeparatorPos - strings.IndexRune(code, olcSeparator)
+	point, accuracy, ok := decodeOLC(encodeOLCPrefix(refLat, refLng, padding) + code)
+	if !ok {
+		return point, 0, false
+	}
+
+	// The prefix of the reference can be off by one cell if the reference
+	// is close to the edge of its cell
+	resolution := math.Pow(20, 2-float64(padding)/2)
+	half := resolution / 2
+	switch {
+	case refLat+half < point[0] && point[0]-resolution >= -90:
+		point[0] -= resolution
+	case refLat-half > point[0] && point[0]+resolution <= 90:
+		point[0] += resolution
+	}
+	switch {
+	case refLng+half < point[1]:
+		point[1] -= resolution
+	case refLng-half > point[1]:
+		point[1] += resolution
+	}
+	point[1] = normalizeLng(point[1])
+
+	return point, accuracy, true
+}
+
+func normalizeLng(lng float64) float64 {
+	for lng < -180 {
+		lng += 360
+	}
+	for lng >= 180 {
+		lng -= 360
+	}
+	return lng
+}
+
+// cellSize returns the larger side in metres of a cell of the given size in
+// degrees at a latitude.
+func cellSize(lat, latSize, lngSize float64) float64 {
+	const metresPerDegree = 111320.0
+	return math.Max(latSize*metresPerDegree, lngSize*metresPerDegree*math.Cos(lat*math.Pi/180))
+}
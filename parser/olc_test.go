@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseOLC(t *testing.T) {
+	testCoordinates(t, []coordinateTest{
+		{"7FG49QCJ+2V", 20.3700625, 2.7821875, FormatOLC, 13.9},
+		{"7fg49qcj+2v", 20.3700625, 2.7821875, FormatOLC, 13.9},
+		{"7FG49Q00+", 20.375, 2.775, FormatOLC, 5566},
+		{"9F4MGCC2+RX", 52.5220625, 13.4024375, FormatOLC, 13.9},
+	})
+	testNotCoordinates(t, []string{
+		"0000+",
+		"9F4MGCC2+R",
+		"9F4M00C2+",
+		"ZZZZZZZZ+ZZ",
+	})
+}
+
+func TestRecoverOLC(t *testing.T) {
+	tests := []struct {
+		query     string
+		reference string
+		refLat    float64
+		refLng    float64
+		lat       float64
+		lng       float64
+	}{
+		{"9G8F+6X", "", 47.4, 8.6, 47.3655625, 8.5249375},
+		{"9G8F+6X Zurich", "Zurich", 47.4, 8.6, 47.3655625, 8.5249375},
+		{"GCC2+RX Berlin", "Berlin", 52.5, 13.4, 52.5220625, 13.4024375},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			c, ok := ParseCoordinate(test.query)
+			if !ok || !c.IsShort() {
+				t.Fatalf("not recognized as short code: %+v", c)
+			}
+			if c.Reference != test.reference {
+				t.Errorf("reference %q, want %q", c.Reference, test.reference)
+			}
+			if !c.Recover(test.refLat, test.refLng) {
+				t.Fatal("not recovered")
+			}
+			if math.Abs(c.Lat-test.lat) > 1e-6 || math.Abs(c.Lng-test.lng) > 1e-6 {
+				t.Errorf("got %.7f, %.7f, want %.7f, %.7f", c.Lat, c.Lng, test.lat, test.lng)
+			}
+			if c.IsShort() || c.Recover(test.refLat, test.refLng) {
+				t.Error("recovered code is still short")
+			}
+		})
+	}
+}
+
+func TestEncodeOLCPrefix(t *testing.T) {
+	if got := encodeOLCPrefix(47.365562, 8.524875, 8); got != "8FVC9G8F" {
+		t.Errorf("got %q, want 8FVC9G8F", got)
+	}
+}
@@ -0,0 +1,458 @@
+// Package parser splits a free text address like "Apt 4, 12 Baker Street,
+// London NW1 6XE, UK" into its components. It is rule based: the parts
+// between commas are classified by the postcode formats of
+// mapping.PostcodePatterns and the street types, house number patterns and
+// country names of the lexicon.
+package parser
+
+import (
+	"hstin/gocoder/mapping"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Address holds the components of a parsed query, in the spelling of the
+// query. Components that weren't found are empty.
+type Address struct {
+	// Name is a place or building named before the street, e.g. "British
+	// Museum, Great Russell Street".
+	Name        string `json:"name,omitempty"`
+	Unit        string `json:"unit,omitempty"`
+	HouseNumber string `json:"housenumber,omitempty"`
+	Street      string `json:"street,omitempty"`
+	Postcode    string `json:"postcode,omitempty"`
+	City        string `json:"city,omitempty"`
+	// State is a state, province or its abbreviation, e.g. "IL".
+	State   string `json:"state,omitempty"`
+	Country string `json:"country,omitempty"`
+	// CountryCode is the ISO 3166 alpha-2 code of the country, or of the
+	// only country whose postcode format matches the postcode.
+	CountryCode string `json:"countryCode,omitempty"`
+}
+
+// houseNumberPattern matches house numbers like "12", "221B", "12-14" or
+// "4/2".
+var houseNumberPattern = regexp.MustCompile(`^\d{1,5}[a-zA-Z]?([-/]\d{1,5}[a-zA-Z]?)?$`)
+
+// unitPattern matches the number of a flat or unit, e.g. "4", "12b" or "C".
+var unitPattern = regexp.MustCompile(`^(\d[0-9a-zA-Z\-/]*|[a-zA-Z])$`)
+
+// stateCountries are searched in this order for a state without a country.
+var stateCountries = []string{"US", "CA", "AU"}
+
+// Parse splits a query into its address components. The country is the
+// last part, the postcode the last match of a postcode format, the street
+// the first part with a street type or a house number. Of the remaining
+// parts the one before the street is the name, the last one after it the
+// city.
+func Parse(query string) Address {
+	parts := make([][]string, 0)
+	for _, part := range strings.FieldsFunc(query, func(r rune) bool { return r == ',' || r == ';' }) {
+		if tokens := strings.Fields(part); len(tokens) > 0 {
+			parts = append(parts, tokens)
+		}
+	}
+
+	var address Address
+	used := make([]bool, len(parts))
+
+	// "..., UK" or "..., Deutschland"
+	if n := len(parts); n > 0 {
+		text := strings.Join(parts[n-1], " ")
+		if code, ok := countryNames[strings.ToLower(text)]; ok {
+			address.Country, address.CountryCode = text, code
+			used[n-1] = true
+		} else if code, ok := ParseCountry(text); ok && n > 1 && text == strings.ToUpper(text) && !isStateCode(text) {
+			// Codes only count in capitals and if no state has them, so
+			// "Springfield, IL" stays in the US
+			address.Country, address.CountryCode = text, code
+			used[n-1] = true
+		}
+	}
+
+	// "London NW1 6XE" or "Springfield, IL 62701"
+	city := ""
+	for i := len(parts) - 1; i >= 0 && address.Postcode == ""; i-- {
+		if used[i] || hasStreetType(parts[i]) {
+			continue
+		}
+		start, end, ok := findPostcode(parts[i], address.CountryCode, i == 0)
+		if !ok {
+			continue
+		}
+
+		address.Postcode = mapping.NormalizePostcode(strings.Join(parts[i][start:end], " "))
+		if address.CountryCode == "" {
+			if countries := mapping.PostcodeCountries(address.Postcode); len(countries) == 1 {
+				address.CountryCode = countries[0]
+			}
+		}
+		used[i] = true
+
+		rest := append(append([]string{}, parts[i][:start]...), parts[i][end:]...)
+		if n := len(rest); n > 0 && address.State == "" && isState(rest[n-1]) {
+			address.State = rest[n-1]
+			rest = rest[:n-1]
+		}
+		city = strings.Join(rest, " ")
+	}
+
+	// "..., IL" or "..., Illinois, USA". State names need a part after
+	// them, "12 Main St, Washington" is the city
+	for i := 1; i < len(parts) && address.State == ""; i++ {
+		text := strings.Join(parts[i], " ")
+		if !used[i] && (isStateCode(text) || (i+1 < len(parts) && isState(text))) {
+			address.State = text
+			used[i] = true
+		}
+	}
+
+	// "Apt 4" as its own part or at the start or end of the street
+	for i := range parts {
+		if used[i] || address.Unit != "" {
+			continue
+		}
+		if unit, rest, ok := splitUnit(parts[i]); ok {
+			address.Unit = unit
+			parts[i] = rest
+			used[i] = len(rest) == 0
+		}
+	}
+
+	streetPart := -1
+	for i := range parts {
+		if !used[i] && hasStreetType(parts[i]) {
+			streetPart = i
+			break
+		}
+	}
+	if streetPart < 0 {
+		for i := range parts {
+			if !used[i] && hasHouseNumber(parts[i]) {
+				streetPart = i
+				break
+			}
+		}
+	}
+
+	if streetPart >= 0 {
+		street, number, rest := splitStreet(parts[streetPart])
+		address.Street, address.HouseNumber = street, number
+		used[streetPart] = true
+		if city == "" {
+			city = rest
+		}
+
+		// "Calle Mayor, 5" or "12, Baker Street"
+		if number == "" {
+			for _, i := range []int{streetPart + 1, streetPart - 1} {
+				if i < 0 || i >= len(parts) || used[i] {
+					continue
+				}
+				if number, ok := bareHouseNumber(parts[i]); ok {
+					address.HouseNumber = number
+					used[i] = true
+					break
+				}
+			}
+		}
+	}
+
+	before, after := make([]string, 0), make([]string, 0)
+	for i := range parts {
+		if used[i] {
+			continue
+		}
+		if streetPart >= 0 && i > streetPart {
+			after = append(after, strings.Join(parts[i], " "))
+		} else {
+			before = append(before, strings.Join(parts[i], " "))
+		}
+	}
+
+	if len(after) > 0 {
+		city = after[len(after)-1]
+	}
+	switch {
+	case len(before) == 0:
+	case streetPart >= 0 || city != "":
+		address.Name = before[0]
+	case len(before) > 1:
+		// "Eiffel Tower, Paris"
+		address.Name, city = before[0], before[len(before)-1]
+	default:
+		city = before[0]
+	}
+	address.City = city
+
+	return address
+}
+
+// ParseCountry returns the country code of a country name, e.g. "Germany",
+// "Deutschland" or "UK", or of an ISO 3166 alpha-2 or alpha-3 code.
+func ParseCountry(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if code, ok := countryNames[strings.ToLower(text)]; ok {
+		return code, true
+	}
+
+	upper := strings.ToUpper(text)
+	if len(upper) == 2 && mapping.GetCountryNumber(upper) > 0 {
+		return upper, true
+	}
+	if len(upper) == 3 {
+		for code, info := range mapping.CountryInfo {
+			if info.ISO3 == upper {
+				return code, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Region returns the name of the state, e.g. "Illinois" for "IL". States
+// that aren't an abbreviation are returned as they are.
+func (a Address) Region() string {
+	countries := stateCountries
+	if a.CountryCode != "" {
+		countries = []string{a.CountryCode}
+	}
+
+	state := strings.ToUpper(a.State)
+	for _, country := range countries {
+		names := make([]string, 0)
+		for name, code := range mapping.StateCodes[country] {
+			if code == state {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			sort.Strings(names)
+			return names[0]
+		}
+	}
+	return a.State
+}
+
+// IsEmpty reports whether no component was found.
+func (a Address) IsEmpty() bool {
+	return a == Address{}
+}
+
+func isStateCode(text string) bool {
+	for _, codes := range mapping.StateCodes {
+		for _, code := range codes {
+			if code == text {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isState reports whether the text is a state abbreviation in capitals or
+// the name of a state.
+func isState(text string) bool {
+	if isStateCode(text) {
+		return true
+	}
+	for _, codes := range mapping.StateCodes {
+		for name := range codes {
+			if strings.EqualFold(name, text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isPostcode reports whether the text has the postcode format of the
+// country, or of any country if the country has no known format.
+func isPostcode(text string, country string) bool {
+	if _, ok := mapping.PostcodePatterns[country]; ok {
+		return mapping.ValidPostcode(country, text)
+	}
+	return len(mapping.PostcodeCountries(text)) > 0
+}
+
+// findPostcode returns the token range of the last postcode of a part,
+// trying two token postcodes like "NW1 6XE" first. A number ending the
+// first part is its house number, e.g. "Unter den Linden 1234", unless it
+// has the postcode format of the country, e.g. "Wien 1010, Austria", starts
+// with a zero or has five digits or more, e.g. "Berlin 10115". The country is
+// the given one or that of a state before the number, e.g. "Melbourne VIC
+// 3000".
+func findPostcode(tokens []string, country string, first bool) (int, int, bool) {
+	for size := 2; size >= 1; size-- {
+		for start := len(tokens) - size; start >= 0; start-- {
+			end := start + size
+			text := strings.Join(tokens[start:end], " ")
+			partCountry := country
+			if partCountry == "" && start > 0 {
+				partCountry = stateCountry(tokens[start-1])
+			}
+			if !isPostcode(text, partCountry) {
+				continue
+			}
+			if first && start > 0 && end == len(tokens) && isDigits(text) && !isCountryPostcode(text, partCountry) &&
+				!strings.HasPrefix(text, "0") && len(text) < 5 {
+				continue
+			}
+			return start, end, true
+		}
+	}
+	return 0, 0, false
+}
+
+// isCountryPostcode reports whether the country has a known postcode format
+// and the text matches it.
+func isCountryPostcode(text string, country string) bool {
+	_, ok := mapping.PostcodePatterns[country]
+	return ok && mapping.ValidPostcode(country, text)
+}
+
+// stateCountry returns the country of a state abbreviation, e.g. "AU" for
+// "VIC", or "" if it isn't one.
+func stateCountry(text string) string {
+	for _, country := range stateCountries {
+		for _, code := range mapping.StateCodes[country] {
+			if code == text {
+				return country
+			}
+		}
+	}
+	return ""
+}
+
+func isDigits(text string) bool {
+	for _, r := range text {
+		if !unicode.IsDigit(r) && r != ' ' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// isStreetType reports whether the token at position i names a street type.
+// Suffix types need a word of the name before them.
+func isStreetType(tokens []string, i int) bool {
+	word := strings.ToLower(tokens[i])
+	if prefixStreetTypes[word] {
+		return true
+	}
+	if streetTypes[word] {
+		return i > 1 || (i == 1 && !isHouseNumber(tokens[0]))
+	}
+	for _, suffix := range streetSuffixes {
+		if len(word) > len(suffix) && strings.HasSuffix(word, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasStreetType(tokens []string) bool {
+	for i := range tokens {
+		if isStreetType(tokens, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func isHouseNumber(token string) bool {
+	return houseNumberPattern.MatchString(token)
+}
+
+// hasHouseNumber reports whether a part starts or ends with a house number
+// and has a name besides it, e.g. "Unter den Linden 77".
+func hasHouseNumber(tokens []string) bool {
+	_, number, _ := splitStreet(tokens)
+	return number != "" && len(tokens) > 1
+}
+
+// bareHouseNumber returns the house number of a part that consists of
+// nothing else, e.g. "5", "5 bis" or "Nr. 5".
+func bareHouseNumber(tokens []string) (string, bool) {
+	if len(tokens) > 1 && numberPrefixes[strings.ToLower(tokens[0])] {
+		tokens = tokens[1:]
+	}
+	switch {
+	case len(tokens) == 1 && isHouseNumber(tokens[0]):
+		return tokens[0], true
+	case len(tokens) == 2 && isHouseNumber(tokens[0]) && numberSuffixes[strings.ToLower(tokens[1])]:
+		return tokens[0] + " " + tokens[1], true
+	}
+	return "", false
+}
+
+// splitStreet returns the street name and house number of a street part.
+// A leading house number ends the street after a suffix street type, the
+// words after it are returned as the rest, e.g. "London" for "12 Baker
+// Street London".
+func splitStreet(tokens []string) (string, string, string) {
+	n := len(tokens)
+	if n == 0 {
+		return "", "", ""
+	}
+
+	// "12 Baker Street", "5 bis rue de Rivoli"
+	if n > 1 && isHouseNumber(tokens[0]) {
+		number, name := tokens[0], tokens[1:]
+		if len(name) > 1 && numberSuffixes[strings.ToLower(name[0])] {
+			number, name = number+" "+name[0], name[1:]
+		}
+		for i := range name {
+			word := strings.ToLower(name[i])
+			if i+1 < len(name) && !prefixStreetTypes[word] && isStreetType(name, i) {
+				return strings.Join(name[:i+1], " "), number, strings.Join(name[i+1:], " ")
+			}
+		}
+		return strings.Join(name, " "), number, ""
+	}
+
+	// "Unter den Linden 77", "Rue de Rivoli 5 bis", "Hauptstraße Nr. 5"
+	end := n
+	number := ""
+	switch {
+	case n > 2 && numberSuffixes[strings.ToLower(tokens[n-1])] && isHouseNumber(tokens[n-2]):
+		number, end = tokens[n-2]+" "+tokens[n-1], n-2
+	case n > 1 && isHouseNumber(tokens[n-1]):
+		number, end = tokens[n-1], n-1
+	}
+	if number != "" && end > 1 && numberPrefixes[strings.ToLower(tokens[end-1])] {
+		end--
+	}
+	// "Route 66" or "Calle 5" is named by the number
+	if word := strings.ToLower(tokens[0]); end == 1 && (prefixStreetTypes[word] || streetTypes[word]) {
+		return strings.Join(tokens, " "), "", ""
+	}
+	return strings.Join(tokens[:end], " "), number, ""
+}
+
+// splitUnit returns a unit at the start or end of a part and the remaining
+// tokens, e.g. "Apt 4" or "#4".
+func splitUnit(tokens []string) (string, []string, bool) {
+	n := len(tokens)
+	isUnit := func(i int) bool {
+		return i+1 < n && unitTypes[strings.ToLower(tokens[i])] && unitPattern.MatchString(tokens[i+1])
+	}
+	isHash := func(i int) bool {
+		return strings.HasPrefix(tokens[i], "#") && unitPattern.MatchString(tokens[i][1:])
+	}
+
+	switch {
+	case n == 0:
+	case isUnit(0):
+		return tokens[0] + " " + tokens[1], tokens[2:], true
+	case isHash(0):
+		return tokens[0], tokens[1:], true
+	case n > 2 && isUnit(n-2):
+		return tokens[n-2] + " " + tokens[n-1], tokens[:n-2], true
+	case n > 1 && isHash(n-1):
+		return tokens[n-1], tokens[:n-1], true
+	}
+	return "", nil, false
+}
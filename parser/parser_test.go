@@ -0,0 +1,86 @@
+package parser
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		want  Address
+	}{
+		{
+			"Apt 4, 12 Baker Street, London NW1 6XE, UK",
+			Address{Unit: "Apt 4", HouseNumber: "12", Street: "Baker Street", Postcode: "NW1 6XE", City: "London", Country: "UK", CountryCode: "GB"},
+		},
+		{
+			"1600 Pennsylvania Ave, Washington, DC 20500, USA",
+			Address{HouseNumber: "1600", Street: "Pennsylvania Ave", Postcode: "20500", City: "Washington", State: "DC", Country: "USA", CountryCode: "US"},
+		},
+		{
+			"Unter den Linden 77, 10117 Berlin",
+			Address{HouseNumber: "77", Street: "Unter den Linden", Postcode: "10117", City: "Berlin"},
+		},
+		{
+			"5 bis rue de Rivoli, 75001 Paris",
+			Address{HouseNumber: "5 bis", Street: "rue de Rivoli", Postcode: "75001", City: "Paris"},
+		},
+		{"Calle Mayor, 5, Madrid", Address{HouseNumber: "5", Street: "Calle Mayor", City: "Madrid"}},
+		{"Hauptstraße Nr. 5", Address{HouseNumber: "5", Street: "Hauptstraße"}},
+		{"#4, 221B Baker Street", Address{Unit: "#4", HouseNumber: "221B", Street: "Baker Street"}},
+		{"British Museum, Great Russell Street, London", Address{Name: "British Museum", Street: "Great Russell Street", City: "London"}},
+		{"Eiffel Tower, Paris", Address{Name: "Eiffel Tower", City: "Paris"}},
+		{"Paris, France", Address{City: "Paris", Country: "France", CountryCode: "FR"}},
+
+		// State names only count with a part after them, codes only in
+		// capitals
+		{"12 Main St, Washington", Address{HouseNumber: "12", Street: "Main St", City: "Washington"}},
+		{"Springfield, IL 62701", Address{Postcode: "62701", City: "Springfield", State: "IL"}},
+
+		// A number ending a part is a house number up to four digits and a
+		// postcode from five, with a zero first or in the format of the
+		// country
+		{"Berlin 10115", Address{Postcode: "10115", City: "Berlin"}},
+		{"Unter den Linden 1234", Address{HouseNumber: "1234", Street: "Unter den Linden"}},
+		{"Wien 1010, Austria", Address{Postcode: "1010", City: "Wien", Country: "Austria", CountryCode: "AT"}},
+		{"Bern 3000, Switzerland", Address{Postcode: "3000", City: "Bern", Country: "Switzerland", CountryCode: "CH"}},
+		{"Oslo 0150, Norway", Address{Postcode: "0150", City: "Oslo", Country: "Norway", CountryCode: "NO"}},
+		{"Oslo 0150", Address{Postcode: "0150", City: "Oslo"}},
+		{"København 1050, Denmark", Address{Postcode: "1050", City: "København", Country: "Denmark", CountryCode: "DK"}},
+		{"Bruxelles 1000, Belgium", Address{Postcode: "1000", City: "Bruxelles", Country: "Belgium", CountryCode: "BE"}},
+		{"Melbourne VIC 3000, Australia", Address{Postcode: "3000", City: "Melbourne", State: "VIC", Country: "Australia", CountryCode: "AU"}},
+		{"Melbourne VIC 3000", Address{Postcode: "3000", City: "Melbourne", State: "VIC"}},
+		{"10115", Address{Postcode: "10115"}},
+		{"Berlin", Address{City: "Berlin"}},
+
+		// A number right after a street type names the street
+		{"Route 66", Address{Street: "Route 66"}},
+		{"Calle 5", Address{Street: "Calle 5"}},
+		{"Route 66 12, Flagstaff", Address{HouseNumber: "12", Street: "Route 66", City: "Flagstaff"}},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			if got := Parse(test.query); got != test.want {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseCountry(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+		ok   bool
+	}{
+		{"Germany", "DE", true},
+		{"Deutschland", "DE", true},
+		{"UK", "GB", true},
+		{"fr", "FR", true},
+		{"USA", "US", true},
+		{"Atlantis", "", false},
+	}
+	for _, test := range tests {
+		if got, ok := ParseCountry(test.text); got != test.want || ok != test.ok {
+			t.Errorf("ParseCountry(%q) = %q, %v, want %q, %v", test.text, got, ok, test.want, test.ok)
+		}
+	}
+}
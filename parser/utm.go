@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// WGS84 ellipsoid and UTM projection constants.
+const (
+	wgs84A       = 6378137.0
+	wgs84F       = 1 / 298.257223563
+	utmScale     = 0.9996
+	utmFalseEast = 500000.0
+	utmFalseN    = 10000000.0
+)
+
+// mgrsBands are the latitude bands of 8 degrees from 80°S, X spans 12.
+const mgrsBands = "CDEFGHJKLMNPQRSTUVWX"
+
+var (
+	// utmPattern matches "33U 389434 5819806" or "33U 389434mE 5819806mN"
+	utmPattern = regexp.MustCompile(`^(\d{1,2})\s*([C-HJ-NP-X])\s+(\d{1,6}(?:\.\d+)?)\s*(?:M?E)?[\s,]+(\d{1,7}(?:\.\d+)?)\s*(?:M?N)?$`)
+	// mgrsPattern matches "33UUU8943419806" or "33U UU 89434 19806"
+	mgrsPattern = regexp.MustCompile(`^(\d{1,2})\s*([C-HJ-NP-X])\s*([A-HJ-NP-Z])([A-HJ-NP-V])\s*(\d{0,5})\s*(\d{0,5})$`)
+)
+
+// parseUTM recognizes UTM coordinates with zone and latitude band.
+func parseUTM(query string) (Coordinate, bool) {
+	m := utmPattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(query)))
+	if m == nil {
+		return Coordinate{}, false
+	}
+
+	zone, _ := strconv.Atoi(m[1])
+	easting, _ := strconv.ParseFloat(m[3], 64)
+	northing, _ := strconv.ParseFloat(m[4], 64)
+	if zone < 1 || zone > 60 || easting < 100000 || easting > 900000 {
+		return Coordinate{}, false
+	}
+
+	lat, lng := utmToLatLng(zone, m[2][0] < 'N', easting, northing)
+	if !validLatLng(lat, lng) {
+		return Coordinate{}, false
+	}
+	return Coordinate{Lat: lat, Lng: lng, Format: FormatUTM}, true
+}
+
+// parseMGRS recognizes MGRS references with up to metre precision. The
+// point is the centre of the referenced square.
+func parseMGRS(query string) (Coordinate, bool) {
+	m := mgrsPattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(query)))
+	if m == nil {
+		return Coordinate{}, false
+	}
+
+	digits := m[5] + m[6]
+	if len(digits)%2 == 1 || (m[6] != "" && len(m[5]) != len(m[6])) {
+		return Coordinate{}, false
+	}
+	zone, _ := strconv.Atoi(m[1])
+	if zone < 1 || zone > 60 {
+		return Coordinate{}, false
+	}
+	band := strings.IndexByte(mgrsBands, m[2][0])
+
+	// The column letters repeat every three zones, the row letters every
+	// two with an offset of 5 in even zones
+	columns := [3]string{"STUVWXYZ", "ABCDEFGH", "JKLMNPQR"}[zone%3]
+	column := strings.IndexByte(columns, m[3][0])
+	if column < 0 {
+		return Coordinate{}, false
+	}
+	row := strings.IndexByte("ABCDEFGHJKLMNPQRSTUV", m[4][0])
+	if zone%2 == 0 {
+		row = (row + 15) % 20
+	}
+
+	precision := len(digits) / 2
+	size := math.Pow(10, float64(5-precision))
+	easting := float64(column+1) * 100000
+	northing := float64(row) * 100000
+	if precision > 0 {
+		e, _ := strconv.ParseFloat(digits[:precision], 64)
+		n, _ := strconv.ParseFloat(digits[precision:], 64)
+		easting += e * size
+		northing += n * size
+	}
+	easting += size / 2
+	northing += size / 2
+
+	// The row letters repeat every 2000 km, the band tells which repeat
+	south := band < strings.IndexByte(mgrsBands, 'N')
+	bandMin := -80 + float64(band)*8
+	bandCentre := bandMin + 4
+	if m[2][0] == 'X' {
+		bandCentre = 78
+	}
+	bestLat, bestLng, best := 0.0, 0.0, math.Inf(1)
+	for n := northing; n < utmFalseN+2000000; n += 2000000 {
+		lat, lng := utmToLatLng(zone, south, easting, n)
+		if distance := math.Abs(lat - bandCentre); distance < best {
+			bestLat, bestLng, best = lat, lng, distance
+		}
+	}
+	if best > 6 || !validLatLng(bestLat, bestLng) {
+		return Coordinate{}, false
+	}
+
+	return Coordinate{Lat: bestLat, Lng: bestLng, Format: FormatMGRS, Accuracy: size}, true
+}
+
+// utmToLatLng converts UTM coordinates to WGS84 degrees, with the series
+// expansion of the USGS Map Projections manual.
+func utmToLatLng(zone int, south bool, easting, northing float64) (float64, float64) {
+	e2 := wgs84F * (2 - wgs84F)
+	ep2 := e2 / (1 - e2)
+	e1 := (1 - math.Sqrt(1-e2)) / (1 + math.Sqrt(1-e2))
+
+	x := easting - utmFalseEast
+	y := northing
+	if south {
+		y -= utmFalseN
+	}
+
+	mu := y / utmScale / (wgs84A * (1 - e2/4 - 3*e2*e2/64 - 5*e2*e2*e2/256))
+	phi := mu +
+		(3*e1/2-27*math.Pow(e1, 3)/32)*math.Sin(2*mu) +
+		(21*e1*e1/16-55*math.Pow(e1, 4)/32)*math.Sin(4*mu) +
+		(151*math.Pow(e1, 3)/96)*math.Sin(6*mu) +
+		(1097*math.Pow(e1, 4)/512)*math.Sin(8*mu)
+
+	sin, cos, tan := math.Sin(phi), math.Cos(phi), math.Tan(phi)
+	n := wgs84A / math.Sqrt(1-e2*sin*sin)
+	t := tan * tan
+	c := ep2 * cos * cos
+	r := wgs84A * (1 - e2) / math.Pow(1-e2*sin*sin, 1.5)
+	d := x / (n * utmScale)
+
+	lat := phi - (n*tan/r)*(d*d/2-
+		(5+3*t+10*c-4*c*c-9*ep2)*math.Pow(d, 4)/24+
+		(61+90*t+298*c+45*t*t-252*ep2-3*c*c)*math.Pow(d, 6)/720)
+	lng := (d - (1+2*t+c)*math.Pow(d, 3)/6 +
+		(5-2*c+28*t-3*c*c+8*ep2+24*t*t)*math.Pow(d, 5)/120) / cos
+
+	centralMeridian := float64(zone-1)*6 - 180 + 3
+	return lat * 180 / math.Pi, centralMeridian + lng*180/math.Pi
+}
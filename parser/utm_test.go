@@ -0,0 +1,32 @@
+package parser
+
+import "testing"
+
+func TestParseUTM(t *testing.T) {
+	testCoordinates(t, []coordinateTest{
+		{"17T 630084 4833438", 43.64256, -79.38714, FormatUTM, 0},
+		{"56H 334873 6252266", -33.85700, 151.21500, FormatUTM, 0},
+		{"33U 390000 5820000", 52.518995, 13.378812, FormatUTM, 0},
+		{"33U 390000mE 5820000mN", 52.518995, 13.378812, FormatUTM, 0},
+	})
+	testNotCoordinates(t, []string{
+		"60X 999999 9999999",
+		"61U 390000 5820000",
+		"33U 50000 5820000",
+		"31N 0 0",
+		"33U",
+	})
+}
+
+func TestParseMGRS(t *testing.T) {
+	testCoordinates(t, []coordinateTest{
+		{"33U UU 90000 20000", 52.519000, 13.378819, FormatMGRS, 1},
+		{"33UUU8943419806", 52.517142, 13.370545, FormatMGRS, 1},
+		{"18T WL 80594 09212", 40.72990, -74.04559, FormatMGRS, 1},
+		{"56H LH 34000 51000", -33.86827, 151.20533, FormatMGRS, 1},
+	})
+	testNotCoordinates(t, []string{
+		"33U UU 9000 200",
+		"33UUU894341980",
+	})
+}
@@ -0,0 +1,219 @@
+package structures
+
+import (
+	"bufio"
+	"bytes"
+	"hstin/gocoder/geo"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/rtree"
+)
+
+// Address is a single house number. Parent is the document id of the street
+// or, for addresses without addr:street, the place of their addr:place or
+// addr:city it belongs to.
+type Address struct {
+	Parent      int64
+	HouseNumber string
+	Postcode    string
+	OSMType     uint8
+	ID          int64
+	Lat         float32
+	Lng         float32
+}
+
+// AddressIndex stores the addresses sorted by parent and house number, so
+// all house numbers of a street are one contiguous range.
+type AddressIndex struct {
+	Addresses []Address
+	tree      rtree.RTree
+}
+
+func NewAddressIndex() *AddressIndex {
+	return &AddressIndex{
+		Addresses: make([]Address, 0),
+	}
+}
+
+// NormalizeHouseNumber lower cases a house number and removes whitespace,
+// so "12 A" and "12a" are the same number.
+func NormalizeHouseNumber(number string) string {
+	return strings.Join(strings.Fields(strings.ToLower(number)), "")
+}
+
+func (a *AddressIndex) Add(address Address) {
+	a.Addresses = append(a.Addresses, address)
+}
+
+// Optimize sorts the addresses by parent and house number and builds the
+// spatial index. It has to be called after the last Add.
+func (a *AddressIndex) Optimize() {
+	sort.SliceStable(a.Addresses, func(i, j int) bool {
+		if a.Addresses[i].Parent != a.Addresses[j].Parent {
+			return a.Addresses[i].Parent < a.Addresses[j].Parent
+		}
+		return NormalizeHouseNumber(a.Addresses[i].HouseNumber) < NormalizeHouseNumber(a.Addresses[j].HouseNumber)
+	})
+
+	a.buildTree()
+}
+
+func (a *AddressIndex) buildTree() {
+	a.tree = rtree.RTree{}
+	for i, address := range a.Addresses {
+		point := [2]float64{float64(address.Lng), float64(address.Lat)}
+		a.tree.Insert(point, point, i)
+	}
+}
+
+// Street returns all addresses of a parent document.
+func (a *AddressIndex) Street(parent int64) []Address {
+	start := sort.Search(len(a.Addresses), func(i int) bool {
+		return a.Addresses[i].Parent >= parent
+	})
+	end := start
+	for end < len(a.Addresses) && a.Addresses[end].Parent == parent {
+		end++
+	}
+	return a.Addresses[start:end]
+}
+
+// Lookup returns the addresses of a parent document with the house number.
+func (a *AddressIndex) Lookup(parent int64, houseNumber string) []Address {
+	number := NormalizeHouseNumber(houseNumber)
+	out := make([]Address, 0)
+	for _, address := range a.Street(parent) {
+		if NormalizeHouseNumber(address.HouseNumber) == number {
+			out = append(out, address)
+		}
+	}
+	return out
+}
+
+// Nearest returns the address closest to the coordinate within radius
+// metres and its distance, or nil if there is none.
+func (a *AddressIndex) Nearest(lat, lng, radius float64) (*Address, float64) {
+	metresPerDegree := geo.EarthRadius * math.Pi / 180
+	dLat := radius / metresPerDegree
+	dLng := dLat / math.Max(math.Cos(lat*math.Pi/180), 1e-6)
+
+	var nearest *Address
+	best := radius
+
+	a.tree.Search(
+		[2]float64{lng - dLng, lat - dLat},
+		[2]float64{lng + dLng, lat + dLat},
+		func(min, max [2]float64, data interface{}) bool {
+			address := &a.Addresses[data.(int)]
+			if d := geo.Haversine(lat, lng, float64(address.Lat), float64(address.Lng)); d <= best {
+				best = d
+				nearest = address
+			}
+			return true
+		},
+	)
+
+	return nearest, best
+}
+
+// -------------------------------------------------------------------
+// Custom Binary Serialization
+// -------------------------------------------------------------------
+/*
+Format:
+
+1) uint64 = number of addresses
+   then for each:
+    1.1) int64 = parent docID
+    1.2) string = house number
+    1.3) string = postcode
+    1.4) byte = OSM type
+    1.5) int64 = OSM ID
+    1.6) float32 lat, float32 lng
+*/
+
+func (a *AddressIndex) Save(w io.Writer) error {
+	a.Optimize()
+	writer := bufio.NewWriter(w)
+
+	if err := writeUint64(writer, uint64(len(a.Addresses))); err != nil {
+		return err
+	}
+	for _, address := range a.Addresses {
+		if err := writeInt64(writer, address.Parent); err != nil {
+			return err
+		}
+		if err := writeString(writer, address.HouseNumber); err != nil {
+			return err
+		}
+		if err := writeString(writer, address.Postcode); err != nil {
+			return err
+		}
+		if err := writeByte(writer, address.OSMType); err != nil {
+			return err
+		}
+		if err := writeInt64(writer, address.ID); err != nil {
+			return err
+		}
+		if err := writeFloat32(writer, address.Lat); err != nil {
+			return err
+		}
+		if err := writeFloat32(writer, address.Lng); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+func (a *AddressIndex) Load(data []byte) error {
+	return a.LoadFromReader(bytes.NewReader(data))
+}
+
+func (a *AddressIndex) LoadFromReader(r io.Reader) error {
+	reader := bufio.NewReaderSize(r, 256*1024) // 256KB buffer
+
+	count, err := readUint64(reader)
+	if err != nil {
+		return err
+	}
+
+	a.Addresses = make([]Address, count)
+	for i := range a.Addresses {
+		address := &a.Addresses[i]
+		if address.Parent, err = readInt64(reader); err != nil {
+			return err
+		}
+		if address.HouseNumber, err = readString(reader); err != nil {
+			return err
+		}
+		if address.Postcode, err = readString(reader); err != nil {
+			return err
+		}
+		if address.OSMType, err = readByte(reader); err != nil {
+			return err
+		}
+		if address.ID, err = readInt64(reader); err != nil {
+			return err
+		}
+		if address.Lat, err = readFloat32(reader); err != nil {
+			return err
+		}
+		if address.Lng, err = readFloat32(reader); err != nil {
+			return err
+		}
+	}
+
+	// The addresses are stored sorted, only the spatial index is rebuilt
+	a.buildTree()
+
+	return nil
+}
+
+func (a *AddressIndex) LoadFromFile(file io.ReaderAt, offset int64, size uint64) error {
+	reader := io.NewSectionReader(file, offset, int64(size))
+	return a.LoadFromReader(reader)
+}
@@ -0,0 +1,60 @@
+package structures
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestAddressIndex(t *testing.T) {
+	index := NewAddressIndex()
+	index.Add(Address{Parent: 2, HouseNumber: "12 A", ID: 3, Lat: 52.5170, Lng: 13.3889})
+	index.Add(Address{Parent: 1, HouseNumber: "77", Postcode: "10117", ID: 1, Lat: 52.5166, Lng: 13.3800})
+	index.Add(Address{Parent: 2, HouseNumber: "12", ID: 2, Lat: 52.5171, Lng: 13.3890})
+	index.Add(Address{Parent: 2, HouseNumber: "12a", OSMType: 2, ID: 4, Lat: 52.5172, Lng: 13.3891})
+	index.Optimize()
+
+	var buf bytes.Buffer
+	if err := index.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewAddressIndex()
+	if err := loaded.Load(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Addresses, index.Addresses) {
+		t.Fatalf("loaded %+v, want %+v", loaded.Addresses, index.Addresses)
+	}
+
+	lookups := []struct {
+		parent int64
+		number string
+		want   []int64
+	}{
+		{1, "77", []int64{1}},
+		// "12 A" and "12a" are the same number
+		{2, "12A", []int64{3, 4}},
+		{2, "12", []int64{2}},
+		{1, "12", []int64{}},
+		{3, "77", []int64{}},
+	}
+	for _, test := range lookups {
+		ids := make([]int64, 0)
+		for _, address := range loaded.Lookup(test.parent, test.number) {
+			ids = append(ids, address.ID)
+		}
+		if !reflect.DeepEqual(ids, test.want) {
+			t.Errorf("Lookup(%d, %q) = %v, want %v", test.parent, test.number, ids, test.want)
+		}
+	}
+	if street := loaded.Street(2); len(street) != 3 {
+		t.Errorf("street 2 has %d addresses, want 3", len(street))
+	}
+
+	if address, distance := loaded.Nearest(52.5166, 13.3801, 50); address == nil || address.ID != 1 || distance > 10 {
+		t.Errorf("nearest: got %+v at %.0f m, want address 1", address, distance)
+	}
+	if address, _ := loaded.Nearest(52.5200, 13.3800, 50); address != nil {
+		t.Errorf("nearest: got %+v, want none within 50 m", address)
+	}
+}
@@ -0,0 +1,240 @@
+package structures
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+	"github.com/tidwall/rtree"
+)
+
+// AdminPolygon is a simplified administrative boundary that is persisted in
+// the database so reverse geocoding can use containment instead of the admin
+// areas of the nearest place.
+type AdminPolygon struct {
+	ID        int64
+	Placetype string
+	Country   string
+	// Source is "wof" for Who's On First areas and "osm" for OSM relations,
+	// the IDs of both may collide.
+	Source string
+	// Names holds the default name followed by one name per database
+	// language, in the same order as the node name arrays.
+	Names    []string
+	Geometry orb.MultiPolygon
+}
+
+type AdminIndex struct {
+	Polygons []AdminPolygon
+	tree     rtree.RTree
+}
+
+func NewAdminIndex() *AdminIndex {
+	return &AdminIndex{
+		Polygons: make([]AdminPolygon, 0),
+	}
+}
+
+func (a *AdminIndex) Add(polygon AdminPolygon) {
+	a.Polygons = append(a.Polygons, polygon)
+	a.insert(len(a.Polygons) - 1)
+}
+
+func (a *AdminIndex) insert(i int) {
+	bound := a.Polygons[i].Geometry.Bound()
+	a.tree.Insert(
+		[2]float64{bound.Min[0], bound.Min[1]},
+		[2]float64{bound.Max[0], bound.Max[1]},
+		i,
+	)
+}
+
+// Contains returns every admin polygon containing the coordinate.
+func (a *AdminIndex) Contains(lat, lng float64) []*AdminPolygon {
+	point := orb.Point{lng, lat}
+	out := make([]*AdminPolygon, 0)
+
+	a.tree.Search([2]float64{lng, lat}, [2]float64{lng, lat}, func(min, max [2]float64, data interface{}) bool {
+		polygon := &a.Polygons[data.(int)]
+		if planar.MultiPolygonContains(polygon.Geometry, point) {
+			out = append(out, polygon)
+		}
+		return true
+	})
+
+	return out
+}
+
+// -------------------------------------------------------------------
+// Custom Binary Serialization
+// -------------------------------------------------------------------
+/*
+Format:
+
+1) uint64 = number of polygons
+   then for each:
+    1.1) int64 = ID
+    1.2) string = placetype
+    1.3) string = country
+    1.4) string = source
+    1.5) uint64 = number of names, then each name as string
+    1.6) uint64 = number of polygons (P)
+         for each polygon: uint64 = number of rings (R)
+         for each ring: uint64 = number of points, then float32 lng, float32 lat
+
+Strings are stored as uint64 length followed by the UTF-8 bytes.
+*/
+
+func (a *AdminIndex) Save(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+
+	if err := writeUint64(writer, uint64(len(a.Polygons))); err != nil {
+		return err
+	}
+	for _, polygon := range a.Polygons {
+		if err := writeInt64(writer, polygon.ID); err != nil {
+			return err
+		}
+		if err := writeString(writer, polygon.Placetype); err != nil {
+			return err
+		}
+		if err := writeString(writer, polygon.Country); err != nil {
+			return err
+		}
+		if err := writeString(writer, polygon.Source); err != nil {
+			return err
+		}
+		if err := writeUint64(writer, uint64(len(polygon.Names))); err != nil {
+			return err
+		}
+		for _, name := range polygon.Names {
+			if err := writeString(writer, name); err != nil {
+				return err
+			}
+		}
+		if err := writeMultiPolygon(writer, polygon.Geometry); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+func (a *AdminIndex) Load(data []byte) error {
+	return a.LoadFromReader(bytes.NewReader(data))
+}
+
+func (a *AdminIndex) LoadFromReader(r io.Reader) error {
+	reader := bufio.NewReaderSize(r, 256*1024) // 256KB buffer
+
+	count, err := readUint64(reader)
+	if err != nil {
+		return err
+	}
+
+	a.Polygons = make([]AdminPolygon, 0, count)
+	a.tree = rtree.RTree{}
+
+	for i := uint64(0); i < count; i++ {
+		var polygon AdminPolygon
+
+		if polygon.ID, err = readInt64(reader); err != nil {
+			return err
+		}
+		if polygon.Placetype, err = readString(reader); err != nil {
+			return err
+		}
+		if polygon.Country, err = readString(reader); err != nil {
+			return err
+		}
+		if polygon.Source, err = readString(reader); err != nil {
+			return err
+		}
+
+		nameCount, err := readUint64(reader)
+		if err != nil {
+			return err
+		}
+		polygon.Names = make([]string, nameCount)
+		for j := range polygon.Names {
+			if polygon.Names[j], err = readString(reader); err != nil {
+				return err
+			}
+		}
+
+		if polygon.Geometry, err = readMultiPolygon(reader); err != nil {
+			return err
+		}
+
+		a.Add(polygon)
+	}
+
+	return nil
+}
+
+func (a *AdminIndex) LoadFromFile(file io.ReaderAt, offset int64, size uint64) error {
+	reader := io.NewSectionReader(file, offset, int64(size))
+	return a.LoadFromReader(reader)
+}
+
+func writeMultiPolygon(w io.Writer, mp orb.MultiPolygon) error {
+	if err := writeUint64(w, uint64(len(mp))); err != nil {
+		return err
+	}
+	for _, polygon := range mp {
+		if err := writeUint64(w, uint64(len(polygon))); err != nil {
+			return err
+		}
+		for _, ring := range polygon {
+			if err := writeUint64(w, uint64(len(ring))); err != nil {
+				return err
+			}
+			for _, point := range ring {
+				if err := writeFloat32(w, float32(point[0])); err != nil {
+					return err
+				}
+				if err := writeFloat32(w, float32(point[1])); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func readMultiPolygon(r io.Reader) (orb.MultiPolygon, error) {
+	polygonCount, err := readUint64(r)
+	if err != nil {
+		return nil, err
+	}
+	mp := make(orb.MultiPolygon, polygonCount)
+	for i := range mp {
+		ringCount, err := readUint64(r)
+		if err != nil {
+			return nil, err
+		}
+		mp[i] = make(orb.Polygon, ringCount)
+		for j := range mp[i] {
+			pointCount, err := readUint64(r)
+			if err != nil {
+				return nil, err
+			}
+			ring := make(orb.Ring, pointCount)
+			for k := range ring {
+				lng, err := readFloat32(r)
+				if err != nil {
+					return nil, err
+				}
+				lat, err := readFloat32(r)
+				if err != nil {
+					return nil, err
+				}
+				ring[k] = orb.Point{float64(lng), float64(lat)}
+			}
+			mp[i][j] = ring
+		}
+	}
+	return mp, nil
+}
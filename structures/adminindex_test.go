@@ -0,0 +1,58 @@
+package structures
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/orb"
+)
+
+// rectangle returns a rectangle with the corners in lng/lat order. The
+// coordinates are stored as float32, the tests use exact ones.
+func rectangle(minLng, minLat, maxLng, maxLat float64) orb.MultiPolygon {
+	return orb.MultiPolygon{{{
+		{minLng, minLat}, {maxLng, minLat}, {maxLng, maxLat}, {minLng, maxLat}, {minLng, minLat},
+	}}}
+}
+
+func TestAdminIndexContains(t *testing.T) {
+	index := NewAdminIndex()
+	index.Add(AdminPolygon{ID: 1, Placetype: "country", Country: "DE", Source: "wof", Names: []string{"Germany"}, Geometry: rectangle(7, 47.5625, 8, 48)})
+	index.Add(AdminPolygon{ID: 2, Placetype: "country", Country: "CH", Source: "wof", Names: []string{"Switzerland"}, Geometry: rectangle(7, 47, 8, 47.5625)})
+	index.Add(AdminPolygon{ID: 3, Placetype: "region", Country: "CH", Source: "osm", Names: []string{"Basel-Stadt"}, Geometry: rectangle(7.5, 47.5, 7.75, 47.5625)})
+
+	var buf bytes.Buffer
+	if err := index.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewAdminIndex()
+	if err := loaded.Load(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Polygons, index.Polygons) {
+		t.Fatalf("loaded %+v, want %+v", loaded.Polygons, index.Polygons)
+	}
+
+	tests := []struct {
+		name     string
+		lat, lng float64
+		want     []int64
+	}{
+		// Basel is next to the German border
+		{"basel", 47.55, 7.59, []int64{2, 3}},
+		{"lörrach", 47.61, 7.66, []int64{1}},
+		{"outside", 46, 7.5, []int64{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ids := make([]int64, 0)
+			for _, polygon := range loaded.Contains(test.lat, test.lng) {
+				ids = append(ids, polygon.ID)
+			}
+			if !reflect.DeepEqual(ids, test.want) {
+				t.Errorf("got %v, want %v", ids, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package structures
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sort"
+)
+
+// Code is a transport identifier of a document, e.g. the IATA code "FRA" of
+// an airport or the UIC code of a station. Type is the code type of
+// mapping.CodeTags.
+type Code struct {
+	Type  string
+	Code  string
+	DocID int64
+}
+
+// CodeIndex maps normalized codes to documents. It is kept apart from the
+// name trie, codes only match exactly.
+type CodeIndex struct {
+	Codes     []Code
+	documents map[int64][]int
+}
+
+func NewCodeIndex() *CodeIndex {
+	return &CodeIndex{
+		Codes:     make([]Code, 0),
+		documents: make(map[int64][]int),
+	}
+}
+
+func (c *CodeIndex) Add(code Code) {
+	c.Codes = append(c.Codes, code)
+}
+
+// Optimize sorts the codes so lookups are a binary search. It has to be
+// called after the last Add.
+func (c *CodeIndex) Optimize() {
+	sort.Slice(c.Codes, func(i, j int) bool {
+		if c.Codes[i].Code != c.Codes[j].Code {
+			return c.Codes[i].Code < c.Codes[j].Code
+		}
+		if c.Codes[i].Type != c.Codes[j].Type {
+			return c.Codes[i].Type < c.Codes[j].Type
+		}
+		return c.Codes[i].DocID < c.Codes[j].DocID
+	})
+
+	c.buildDocuments()
+}
+
+func (c *CodeIndex) buildDocuments() {
+	c.documents = make(map[int64][]int)
+	for i, code := range c.Codes {
+		c.documents[code.DocID] = append(c.documents[code.DocID], i)
+	}
+}
+
+// Lookup returns all entries with the normalized code.
+func (c *CodeIndex) Lookup(code string) []Code {
+	i := sort.Search(len(c.Codes), func(i int) bool {
+		return c.Codes[i].Code >= code
+	})
+
+	out := make([]Code, 0)
+	for ; i < len(c.Codes) && c.Codes[i].Code == code; i++ {
+		out = append(out, c.Codes[i])
+	}
+	return out
+}
+
+// Document returns the codes of a document.
+func (c *CodeIndex) Document(docID int64) []Code {
+	positions := c.documents[docID]
+	out := make([]Code, 0, len(positions))
+	for _, i := range positions {
+		out = append(out, c.Codes[i])
+	}
+	return out
+}
+
+// -------------------------------------------------------------------
+// Custom Binary Serialization
+// -------------------------------------------------------------------
+/*
+Format:
+
+1) uint64 = number of codes
+   then for each:
+    1.1) string = code type
+    1.2) string = code
+    1.3) int64 = docID
+*/
+
+func (c *CodeIndex) Save(w io.Writer) error {
+	c.Optimize()
+	writer := bufio.NewWriter(w)
+
+	if err := writeUint64(writer, uint64(len(c.Codes))); err != nil {
+		return err
+	}
+	for _, code := range c.Codes {
+		if err := writeString(writer, code.Type); err != nil {
+			return err
+		}
+		if err := writeString(writer, code.Code); err != nil {
+			return err
+		}
+		if err := writeInt64(writer, code.DocID); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+func (c *CodeIndex) Load(data []byte) error {
+	return c.LoadFromReader(bytes.NewReader(data))
+}
+
+func (c *CodeIndex) LoadFromReader(r io.Reader) error {
+	reader := bufio.NewReaderSize(r, 256*1024) // 256KB buffer
+
+	count, err := readUint64(reader)
+	if err != nil {
+		return err
+	}
+
+	c.Codes = make([]Code, count)
+	for i := range c.Codes {
+		code := &c.Codes[i]
+		if code.Type, err = readString(reader); err != nil {
+			return err
+		}
+		if code.Code, err = readString(reader); err != nil {
+			return err
+		}
+		if code.DocID, err = readInt64(reader); err != nil {
+			return err
+		}
+	}
+
+	// The codes are stored sorted, only the document lookup is rebuilt
+	c.buildDocuments()
+
+	return nil
+}
+
+func (c *CodeIndex) LoadFromFile(file io.ReaderAt, offset int64, size uint64) error {
+	reader := io.NewSectionReader(file, offset, int64(size))
+	return c.LoadFromReader(reader)
+}
@@ -0,0 +1,62 @@
+package structures
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCodeIndex(t *testing.T) {
+	index := NewCodeIndex()
+	index.Add(Code{Type: "icao", Code: "EDDF", DocID: 3})
+	index.Add(Code{Type: "iata", Code: "FRA", DocID: 3})
+	index.Add(Code{Type: "uic", Code: "8000105", DocID: 7})
+	index.Add(Code{Type: "iata", Code: "QYG", DocID: 7})
+	index.Add(Code{Type: "iata", Code: "QYG", DocID: 5})
+	index.Add(Code{Type: "crs", Code: "FRA", DocID: 9})
+	index.Optimize()
+
+	var buf bytes.Buffer
+	if err := index.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewCodeIndex()
+	if err := loaded.Load(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Codes, index.Codes) {
+		t.Fatalf("loaded %+v, want %+v", loaded.Codes, index.Codes)
+	}
+
+	lookups := []struct {
+		code string
+		want []Code
+	}{
+		// The same code of several types, sorted by type
+		{"FRA", []Code{{"crs", "FRA", 9}, {"iata", "FRA", 3}}},
+		{"QYG", []Code{{"iata", "QYG", 5}, {"iata", "QYG", 7}}},
+		{"EDDF", []Code{{"icao", "EDDF", 3}}},
+		// Lookups are exact, codes are normalized before
+		{"fra", []Code{}},
+		{"FR", []Code{}},
+	}
+	for _, test := range lookups {
+		if got := loaded.Lookup(test.code); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Lookup(%q) = %v, want %v", test.code, got, test.want)
+		}
+	}
+
+	documents := []struct {
+		docID int64
+		want  []Code
+	}{
+		{3, []Code{{"icao", "EDDF", 3}, {"iata", "FRA", 3}}},
+		{7, []Code{{"uic", "8000105", 7}, {"iata", "QYG", 7}}},
+		{4, []Code{}},
+	}
+	for _, test := range documents {
+		if got := loaded.Document(test.docID); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Document(%d) = %v, want %v", test.docID, got, test.want)
+		}
+	}
+}
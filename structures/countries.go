@@ -0,0 +1,140 @@
+package structures
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// Country holds the localized names and metadata of a country.
+type Country struct {
+	Code        string
+	ISO3        string
+	Continent   string
+	CallingCode string
+	Currency    string
+	// BoundingBox uses the node order: min lat, min lng, max lat, max lng.
+	BoundingBox [4]float32
+	// Names holds the default name followed by one name per database
+	// language, in the same order as the node name arrays.
+	Names []string
+}
+
+type CountryTable struct {
+	Countries []Country
+	byCode    map[string]int
+}
+
+func NewCountryTable() *CountryTable {
+	return &CountryTable{
+		Countries: make([]Country, 0),
+		byCode:    make(map[string]int),
+	}
+}
+
+func (c *CountryTable) Add(country Country) {
+	c.byCode[country.Code] = len(c.Countries)
+	c.Countries = append(c.Countries, country)
+}
+
+// Get returns the country for an ISO-3166 alpha-2 code.
+func (c *CountryTable) Get(code string) (*Country, bool) {
+	i, ok := c.byCode[code]
+	if !ok {
+		return nil, false
+	}
+	return &c.Countries[i], true
+}
+
+// -------------------------------------------------------------------
+// Custom Binary Serialization
+// -------------------------------------------------------------------
+/*
+Format:
+
+1) uint64 = number of countries
+   then for each:
+    1.1) string = code, ISO3, continent, calling code, currency
+    1.2) 4 x float32 = bounding box
+    1.3) uint64 = number of names, then each name as string
+
+Strings are stored as uint64 length followed by the UTF-8 bytes.
+*/
+
+func (c *CountryTable) Save(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+
+	if err := writeUint64(writer, uint64(len(c.Countries))); err != nil {
+		return err
+	}
+	for _, country := range c.Countries {
+		for _, s := range []string{country.Code, country.ISO3, country.Continent, country.CallingCode, country.Currency} {
+			if err := writeString(writer, s); err != nil {
+				return err
+			}
+		}
+		for _, v := range country.BoundingBox {
+			if err := writeFloat32(writer, v); err != nil {
+				return err
+			}
+		}
+		if err := writeUint64(writer, uint64(len(country.Names))); err != nil {
+			return err
+		}
+		for _, name := range country.Names {
+			if err := writeString(writer, name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return writer.Flush()
+}
+
+func (c *CountryTable) Load(data []byte) error {
+	return c.LoadFromReader(bytes.NewReader(data))
+}
+
+func (c *CountryTable) LoadFromReader(r io.Reader) error {
+	reader := bufio.NewReader(r)
+
+	count, err := readUint64(reader)
+	if err != nil {
+		return err
+	}
+
+	c.Countries = make([]Country, 0, count)
+	c.byCode = make(map[string]int, count)
+
+	for i := uint64(0); i < count; i++ {
+		var country Country
+		for _, s := range []*string{&country.Code, &country.ISO3, &country.Continent, &country.CallingCode, &country.Currency} {
+			if *s, err = readString(reader); err != nil {
+				return err
+			}
+		}
+		for j := range country.BoundingBox {
+			if country.BoundingBox[j], err = readFloat32(reader); err != nil {
+				return err
+			}
+		}
+		nameCount, err := readUint64(reader)
+		if err != nil {
+			return err
+		}
+		country.Names = make([]string, nameCount)
+		for j := range country.Names {
+			if country.Names[j], err = readString(reader); err != nil {
+				return err
+			}
+		}
+		c.Add(country)
+	}
+
+	return nil
+}
+
+func (c *CountryTable) LoadFromFile(file io.ReaderAt, offset int64, size uint64) error {
+	reader := io.NewSectionReader(file, offset, int64(size))
+	return c.LoadFromReader(reader)
+}
@@ -0,0 +1,42 @@
+package structures
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCountryTable(t *testing.T) {
+	table := NewCountryTable()
+	table.Add(Country{Code: "DE", ISO3: "DEU", Continent: "EU", CallingCode: "+49", Currency: "EUR", BoundingBox: [4]float32{47.25, 5.875, 55.0625, 15.0625}, Names: []string{"Deutschland", "Germany", "Allemagne"}})
+	table.Add(Country{Code: "ML", ISO3: "MLI", Continent: "AF", CallingCode: "+223", Currency: "XOF", Names: []string{"Mali", "Mali", "Mali"}})
+
+	var buf bytes.Buffer
+	if err := table.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewCountryTable()
+	if err := loaded.Load(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Countries, table.Countries) {
+		t.Fatalf("loaded %+v, want %+v", loaded.Countries, table.Countries)
+	}
+
+	tests := []struct {
+		code string
+		iso3 string
+		ok   bool
+	}{
+		{"DE", "DEU", true},
+		{"ML", "MLI", true},
+		{"FR", "", false},
+		{"", "", false},
+	}
+	for _, test := range tests {
+		country, ok := loaded.Get(test.code)
+		if ok != test.ok || (ok && country.ISO3 != test.iso3) {
+			t.Errorf("Get(%q) = %+v, %v, want %q, %v", test.code, country, ok, test.iso3, test.ok)
+		}
+	}
+}
@@ -6,8 +6,29 @@ import (
 	"io"
 )
 
+// DocumentMap maps document keys to the position of the node in the nodes
+// array, see DocumentKey.
 type DocumentMap map[int64]int32
 
+// OSM ids of nodes, ways and relations overlap, so ways and relations get a
+// type bit above the id range. Node keys are the plain node id.
+const (
+	wayKeyBit      int64 = 1 << 60
+	relationKeyBit int64 = 1 << 61
+)
+
+// DocumentKey returns the document map key of an OSM object.
+func DocumentKey(osmType string, id int64) int64 {
+	switch osmType {
+	case "way":
+		return id | wayKeyBit
+	case "relation":
+		return id | relationKeyBit
+	default:
+		return id
+	}
+}
+
 func (dm *DocumentMap) Save(w io.Writer) error {
 	count := int64(len(*dm))
 	if err := binary.Write(w, binary.LittleEndian, count); err != nil {
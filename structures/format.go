@@ -0,0 +1,52 @@
+package structures
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// FormatVersion is the version of the database layout. It has to be
+// increased whenever the header, the nodes, the KD tree, a section or the
+// records in a section are written differently, so the geocoder refuses
+// databases it would misread, or that hold records the geocoder reads
+// differently. Sections are looked up by name, a new section only needs a
+// new version if older databases can't do without it.
+//
+//	2: format header in front of the offsets
+//	3: polygons of OSM admin relations in the admin section
+//	4: admin polygons only down to county, the parent of an address is its
+//	   street, or its addr:place or addr:city without addr:street
+const FormatVersion = 4
+
+// FormatHeaderSize is the size of the magic and version that start every
+// database file, in front of the offsets of the data structures.
+const FormatHeaderSize = 16
+
+var formatMagic = []byte("GOCODER\x00")
+
+// WriteFormatHeader writes the magic and the current format version.
+func WriteFormatHeader(w io.Writer) error {
+	if _, err := w.Write(formatMagic); err != nil {
+		return err
+	}
+	return writeUint64(w, FormatVersion)
+}
+
+// ReadFormatHeader reads the magic and version at the start of a database
+// and fails if the file was written in another format.
+func ReadFormatHeader(r io.Reader) error {
+	var buf [FormatHeaderSize]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return fmt.Errorf("failed to read database header: %w", err)
+	}
+	if !bytes.Equal(buf[:8], formatMagic) {
+		return errors.New("database has no format version, it was written by an older generator and has to be regenerated")
+	}
+	if version := binary.LittleEndian.Uint64(buf[8:]); version != FormatVersion {
+		return fmt.Errorf("database format version %d is not supported, expected %d, the database has to be regenerated", version, FormatVersion)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package structures
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestFormatHeader(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteFormatHeader(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != FormatHeaderSize {
+		t.Fatalf("header is %d bytes, want %d", buf.Len(), FormatHeaderSize)
+	}
+	if err := ReadFormatHeader(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("current format refused: %v", err)
+	}
+
+	// Databases without version start with the offset of the nodes
+	old := make([]byte, 80)
+	binary.LittleEndian.PutUint64(old, 120)
+	if err := ReadFormatHeader(bytes.NewReader(old)); err == nil || !strings.Contains(err.Error(), "regenerated") {
+		t.Errorf("database without version: got %v", err)
+	}
+
+	other := append([]byte(nil), buf.Bytes()...)
+	binary.LittleEndian.PutUint64(other[8:], FormatVersion+1)
+	if err := ReadFormatHeader(bytes.NewReader(other)); err == nil || !strings.Contains(err.Error(), "version") {
+		t.Errorf("other version: got %v", err)
+	}
+
+	if err := ReadFormatHeader(bytes.NewReader(nil)); err == nil {
+		t.Error("empty file accepted")
+	}
+}
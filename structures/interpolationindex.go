@@ -0,0 +1,349 @@
+package structures
+
+import (
+	"bufio"
+	"bytes"
+	"hstin/gocoder/geo"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/paulmach/orb"
+	"github.com/tidwall/rtree"
+)
+
+// Interpolation is an addr:interpolation way between two house numbers. The
+// line runs from Start to End, Step is 2 for odd and even numbers and 1 for
+// all numbers.
+type Interpolation struct {
+	Parent   int64
+	Start    int32
+	End      int32
+	Step     int32
+	Postcode string
+	ID       int64
+	Line     orb.LineString
+}
+
+// InterpolatedAddress is a house number positioned on an interpolation line.
+// Accuracy is the distance in metres between two neighbouring numbers on the
+// line, the real house can be about that far from the position.
+type InterpolatedAddress struct {
+	Interpolation *Interpolation
+	Number        int
+	Lat           float64
+	Lng           float64
+	Accuracy      float64
+}
+
+type InterpolationIndex struct {
+	Interpolations []Interpolation
+	tree           rtree.RTree
+}
+
+func NewInterpolationIndex() *InterpolationIndex {
+	return &InterpolationIndex{
+		Interpolations: make([]Interpolation, 0),
+	}
+}
+
+func (x *InterpolationIndex) Add(interpolation Interpolation) {
+	x.Interpolations = append(x.Interpolations, interpolation)
+}
+
+// Optimize sorts the interpolations by parent and builds the spatial index.
+// It has to be called after the last Add.
+func (x *InterpolationIndex) Optimize() {
+	sort.SliceStable(x.Interpolations, func(i, j int) bool {
+		if x.Interpolations[i].Parent != x.Interpolations[j].Parent {
+			return x.Interpolations[i].Parent < x.Interpolations[j].Parent
+		}
+		return x.Interpolations[i].Start < x.Interpolations[j].Start
+	})
+
+	x.buildTree()
+}
+
+func (x *InterpolationIndex) buildTree() {
+	x.tree = rtree.RTree{}
+	for i, interpolation := range x.Interpolations {
+		bound := interpolation.Line.Bound()
+		x.tree.Insert(
+			[2]float64{bound.Min[0], bound.Min[1]},
+			[2]float64{bound.Max[0], bound.Max[1]},
+			i,
+		)
+	}
+}
+
+// Lookup returns the positions of a house number on the interpolation lines
+// of a parent document. Only plain numbers can be interpolated.
+func (x *InterpolationIndex) Lookup(parent int64, houseNumber string) []InterpolatedAddress {
+	number, err := strconv.Atoi(NormalizeHouseNumber(houseNumber))
+	if err != nil {
+		return nil
+	}
+
+	start := sort.Search(len(x.Interpolations), func(i int) bool {
+		return x.Interpolations[i].Parent >= parent
+	})
+
+	out := make([]InterpolatedAddress, 0)
+	for i := start; i < len(x.Interpolations) && x.Interpolations[i].Parent == parent; i++ {
+		interpolation := &x.Interpolations[i]
+		if number < int(interpolation.Start) || number > int(interpolation.End) {
+			continue
+		}
+		if (number-int(interpolation.Start))%int(interpolation.Step) != 0 {
+			continue
+		}
+		out = append(out, interpolation.at(number))
+	}
+	return out
+}
+
+// Nearest returns the interpolated house number closest to the coordinate
+// whose line lies within radius metres, and the distance to its position.
+func (x *InterpolationIndex) Nearest(lat, lng, radius float64) (*InterpolatedAddress, float64) {
+	metresPerDegree := geo.EarthRadius * math.Pi / 180
+	dLat := radius / metresPerDegree
+	dLng := dLat / math.Max(math.Cos(lat*math.Pi/180), 1e-6)
+
+	var nearest *Interpolation
+	var nearestFraction float64
+	best := radius
+
+	x.tree.Search(
+		[2]float64{lng - dLng, lat - dLat},
+		[2]float64{lng + dLng, lat + dLat},
+		func(min, max [2]float64, data interface{}) bool {
+			interpolation := &x.Interpolations[data.(int)]
+			if d, fraction := interpolation.project(lat, lng); d <= best {
+				best = d
+				nearest = interpolation
+				nearestFraction = fraction
+			}
+			return true
+		},
+	)
+
+	if nearest == nil {
+		return nil, 0
+	}
+
+	// Round to the closest number that exists on the line
+	steps := math.Round(nearestFraction * float64(nearest.End-nearest.Start) / float64(nearest.Step))
+	address := nearest.at(int(nearest.Start) + int(steps)*int(nearest.Step))
+
+	return &address, geo.Haversine(lat, lng, address.Lat, address.Lng)
+}
+
+// plane projects the line into a local plane in metres around its first
+// point and returns the projected points and the cumulative lengths.
+func (i *Interpolation) plane() ([][2]float64, []float64) {
+	metresPerDegree := geo.EarthRadius * math.Pi / 180
+	cosLat := math.Max(math.Cos(i.Line[0][1]*math.Pi/180), 1e-6)
+
+	points := make([][2]float64, len(i.Line))
+	lengths := make([]float64, len(i.Line))
+	for k, p := range i.Line {
+		points[k] = [2]float64{
+			(p[0] - i.Line[0][0]) * cosLat * metresPerDegree,
+			(p[1] - i.Line[0][1]) * metresPerDegree,
+		}
+		if k > 0 {
+			lengths[k] = lengths[k-1] + math.Hypot(points[k][0]-points[k-1][0], points[k][1]-points[k-1][1])
+		}
+	}
+	return points, lengths
+}
+
+// at returns the position of a house number on the line.
+func (i *Interpolation) at(number int) InterpolatedAddress {
+	points, lengths := i.plane()
+	total := lengths[len(lengths)-1]
+
+	fraction := 0.0
+	if i.End > i.Start {
+		fraction = float64(number-int(i.Start)) / float64(i.End-i.Start)
+	}
+	target := fraction * total
+
+	position := i.Line[len(i.Line)-1]
+	for k := 1; k < len(points); k++ {
+		if lengths[k] < target {
+			continue
+		}
+		t := 0.0
+		if segment := lengths[k] - lengths[k-1]; segment > 0 {
+			t = (target - lengths[k-1]) / segment
+		}
+		position = orb.Point{
+			i.Line[k-1][0] + t*(i.Line[k][0]-i.Line[k-1][0]),
+			i.Line[k-1][1] + t*(i.Line[k][1]-i.Line[k-1][1]),
+		}
+		break
+	}
+
+	accuracy := total
+	if i.End > i.Start {
+		accuracy = total * float64(i.Step) / float64(i.End-i.Start)
+	}
+
+	return InterpolatedAddress{
+		Interpolation: i,
+		Number:        number,
+		Lat:           position[1],
+		Lng:           position[0],
+		Accuracy:      accuracy,
+	}
+}
+
+// project returns the distance in metres of the coordinate to the line and
+// the fraction of the line length at the closest point.
+func (i *Interpolation) project(lat, lng float64) (float64, float64) {
+	points, lengths := i.plane()
+	total := lengths[len(lengths)-1]
+
+	metresPerDegree := geo.EarthRadius * math.Pi / 180
+	cosLat := math.Max(math.Cos(i.Line[0][1]*math.Pi/180), 1e-6)
+	px := (lng - i.Line[0][0]) * cosLat * metresPerDegree
+	py := (lat - i.Line[0][1]) * metresPerDegree
+
+	best, along := math.Inf(1), 0.0
+	for k := 0; k+1 < len(points); k++ {
+		ax, ay := points[k][0]-px, points[k][1]-py
+		bx, by := points[k+1][0]-px, points[k+1][1]-py
+		dx, dy := bx-ax, by-ay
+		t := 0.0
+		if length := dx*dx + dy*dy; length > 0 {
+			t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/length))
+		}
+		if d := math.Hypot(ax+t*dx, ay+t*dy); d < best {
+			best = d
+			along = lengths[k] + t*(lengths[k+1]-lengths[k])
+		}
+	}
+
+	if total == 0 {
+		return best, 0
+	}
+	return best, along / total
+}
+
+// -------------------------------------------------------------------
+// Custom Binary Serialization
+// -------------------------------------------------------------------
+/*
+Format:
+
+1) uint64 = number of interpolations
+   then for each:
+    1.1) int64 = parent docID
+    1.2) int32 start, int32 end, int32 step
+    1.3) string = postcode
+    1.4) int64 = OSM way ID
+    1.5) uint64 = number of points, then float32 lng, float32 lat
+*/
+
+func (x *InterpolationIndex) Save(w io.Writer) error {
+	x.Optimize()
+	writer := bufio.NewWriter(w)
+
+	if err := writeUint64(writer, uint64(len(x.Interpolations))); err != nil {
+		return err
+	}
+	for _, interpolation := range x.Interpolations {
+		if err := writeInt64(writer, interpolation.Parent); err != nil {
+			return err
+		}
+		for _, val := range []int32{interpolation.Start, interpolation.End, interpolation.Step} {
+			if err := writeInt32(writer, val); err != nil {
+				return err
+			}
+		}
+		if err := writeString(writer, interpolation.Postcode); err != nil {
+			return err
+		}
+		if err := writeInt64(writer, interpolation.ID); err != nil {
+			return err
+		}
+		if err := writeUint64(writer, uint64(len(interpolation.Line))); err != nil {
+			return err
+		}
+		for _, point := range interpolation.Line {
+			if err := writeFloat32(writer, float32(point[0])); err != nil {
+				return err
+			}
+			if err := writeFloat32(writer, float32(point[1])); err != nil {
+				return err
+			}
+		}
+	}
+
+	return writer.Flush()
+}
+
+func (x *InterpolationIndex) Load(data []byte) error {
+	return x.LoadFromReader(bytes.NewReader(data))
+}
+
+func (x *InterpolationIndex) LoadFromReader(r io.Reader) error {
+	reader := bufio.NewReaderSize(r, 256*1024) // 256KB buffer
+
+	count, err := readUint64(reader)
+	if err != nil {
+		return err
+	}
+
+	x.Interpolations = make([]Interpolation, count)
+	for i := range x.Interpolations {
+		interpolation := &x.Interpolations[i]
+		if interpolation.Parent, err = readInt64(reader); err != nil {
+			return err
+		}
+		if interpolation.Start, err = readInt32(reader); err != nil {
+			return err
+		}
+		if interpolation.End, err = readInt32(reader); err != nil {
+			return err
+		}
+		if interpolation.Step, err = readInt32(reader); err != nil {
+			return err
+		}
+		if interpolation.Postcode, err = readString(reader); err != nil {
+			return err
+		}
+		if interpolation.ID, err = readInt64(reader); err != nil {
+			return err
+		}
+
+		points, err := readUint64(reader)
+		if err != nil {
+			return err
+		}
+		interpolation.Line = make(orb.LineString, points)
+		for k := range interpolation.Line {
+			lng, err := readFloat32(reader)
+			if err != nil {
+				return err
+			}
+			lat, err := readFloat32(reader)
+			if err != nil {
+				return err
+			}
+			interpolation.Line[k] = orb.Point{float64(lng), float64(lat)}
+		}
+	}
+
+	// The interpolations are stored sorted, only the spatial index is rebuilt
+	x.buildTree()
+
+	return nil
+}
+
+func (x *InterpolationIndex) LoadFromFile(file io.ReaderAt, offset int64, size uint64) error {
+	reader := io.NewSectionReader(file, offset, int64(size))
+	return x.LoadFromReader(reader)
+}
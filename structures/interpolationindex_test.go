@@ -0,0 +1,71 @@
+package structures
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/paulmach/orb"
+)
+
+func TestInterpolationIndex(t *testing.T) {
+	index := NewInterpolationIndex()
+	// Odd numbers 1 to 21 along a line of about 680 m running east
+	index.Add(Interpolation{Parent: 1, Start: 1, End: 21, Step: 2, Postcode: "10117", ID: 9, Line: orb.LineString{{13.380, 52.516}, {13.390, 52.516}}})
+	index.Add(Interpolation{Parent: 1, Start: 2, End: 4, Step: 2, ID: 10, Line: orb.LineString{{13.380, 52.517}, {13.381, 52.517}}})
+	index.Optimize()
+
+	var buf bytes.Buffer
+	if err := index.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewInterpolationIndex()
+	if err := loaded.Load(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	// Coordinates are stored as float32
+	if len(loaded.Interpolations) != 2 || loaded.Interpolations[0].Postcode != "10117" {
+		t.Fatalf("loaded %+v", loaded.Interpolations)
+	}
+
+	lookups := []struct {
+		number string
+		lng    float64
+	}{
+		{"1", 13.380},
+		{"11", 13.385},
+		{"21", 13.390},
+		{"3", 13.381},
+		// Even numbers are on the other line
+		{"12", 0},
+		{"23", 0},
+		{"11a", 0},
+	}
+	for _, test := range lookups {
+		addresses := loaded.Lookup(1, test.number)
+		if test.lng == 0 {
+			if len(addresses) != 0 {
+				t.Errorf("%s: got %+v, want none", test.number, addresses)
+			}
+			continue
+		}
+		if len(addresses) != 1 || math.Abs(addresses[0].Lng-test.lng) > 1e-4 || math.Abs(addresses[0].Lat-52.516) > 1e-5 {
+			t.Errorf("%s: got %+v, want at %v", test.number, addresses, test.lng)
+		}
+	}
+	if addresses := loaded.Lookup(1, "11"); len(addresses) == 1 && math.Abs(addresses[0].Accuracy-68) > 1 {
+		t.Errorf("accuracy %.0f m, want 68", addresses[0].Accuracy)
+	}
+	if addresses := loaded.Lookup(2, "11"); len(addresses) != 0 {
+		t.Errorf("other parent: got %+v", addresses)
+	}
+
+	// The nearest number that exists on the line
+	address, distance := loaded.Nearest(52.5159, 13.3830, 50)
+	if address == nil || address.Number != 7 || address.Interpolation.ID != 9 || distance > 25 {
+		t.Errorf("nearest: got %+v at %.0f m, want 7", address, distance)
+	}
+	if address, _ := loaded.Nearest(52.5200, 13.3830, 50); address != nil {
+		t.Errorf("nearest: got %+v, want none within 50 m", address)
+	}
+}
@@ -5,26 +5,43 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"hstin/gocoder/geo"
 	"io"
 	"math"
 	"sort"
 )
 
+// Point is a place stored in the KDTree. Coordinates hold latitude and
+// longitude in degrees, while the tree itself splits on the point's position
+// on the unit sphere so that distances stay correct at high latitudes and
+// across the antimeridian.
 type Point struct {
 	ID          int64
 	Coordinates [2]float32
+	xyz         [3]float64
 }
 
 func NewPoint(id int64, coords [2]float32) *Point {
-	return &Point{ID: id, Coordinates: coords}
+	return &Point{
+		ID:          id,
+		Coordinates: coords,
+		xyz:         geo.ToUnitVector(float64(coords[0]), float64(coords[1])),
+	}
 }
 
 func (p *Point) Dimensions() int {
-	return len(p.Coordinates)
+	return len(p.xyz)
+}
+
+func (p *Point) Dimension(i int) float64 {
+	return p.xyz[i]
 }
 
-func (p *Point) Dimension(i int) float32 {
-	return p.Coordinates[i]
+// Neighbor is a point found by a nearest neighbour search together with its
+// great-circle distance to the query point in metres.
+type Neighbor struct {
+	*Point
+	Distance float64
 }
 
 type KDTree struct {
@@ -294,75 +311,254 @@ func nodeString(n *KDNode) string {
 }
 
 // -------------------------------------------------------------------
-// KNN
+// Nearest Neighbour / Radius Search
 // -------------------------------------------------------------------
 
 // KNN returns the k-nearest neighbors of point p, sorted by distance ascending.
-func (t *KDTree) KNN(p *Point, k int) []*Point {
-	if t.Root == nil || p == nil || k <= 0 {
+func (t *KDTree) KNN(p *Point, k int) []Neighbor {
+	if k <= 0 {
+		return nil
+	}
+	return t.Nearest(p, k, 0, nil)
+}
+
+// Radius returns all points within radius metres of p, sorted by distance
+// ascending.
+func (t *KDTree) Radius(p *Point, radius float64) []Neighbor {
+	if radius <= 0 {
+		return nil
+	}
+	return t.Nearest(p, 0, radius, nil)
+}
+
+// maxPreallocated is the most results Nearest reserves memory for up front.
+const maxPreallocated = 1024
+
+// Nearest returns up to k points within radius metres of p that pass the
+// accept filter, sorted by distance ascending. A k <= 0 returns every match
+// within the radius, a radius <= 0 means the search is unbounded and a nil
+// accept function accepts every point. Filtering happens during the search,
+// so the k results are the k nearest accepted points.
+func (t *KDTree) Nearest(p *Point, k int, radius float64, accept func(*Point) bool) []Neighbor {
+	if t.Root == nil || p == nil || (k <= 0 && radius <= 0) {
 		return nil
 	}
-	neighbors := make([]*KDNode, 0, k)
-	knnSearch(p, k, t.Root, &neighbors)
-	out := make([]*Point, len(neighbors))
-	for i, nn := range neighbors {
-		out[i] = nn.Point
+
+	s := nearestSearch{
+		p:       p,
+		k:       k,
+		maxDist: math.Inf(1),
+		accept:  accept,
+	}
+	if radius > 0 {
+		chord := geo.DistanceToChord(radius)
+		s.maxDist = chord * chord
+	}
+	if k > 0 {
+		// A huge k must not reserve memory the tree can't fill
+		s.best = make([]candidate, 0, min(k, maxPreallocated))
+	}
+
+	s.search(t.Root)
+
+	out := make([]Neighbor, len(s.best))
+	for i, c := range s.best {
+		out[i] = Neighbor{
+			Point:    c.node.Point,
+			Distance: geo.ChordToDistance(math.Sqrt(c.dist)),
+		}
 	}
 	return out
 }
 
-// knnSearch does a DFS search in the KDTree, tracking up to k best nodes.
-func knnSearch(p *Point, k int, node *KDNode, best *[]*KDNode) {
+type candidate struct {
+	node *KDNode
+	dist float64
+}
+
+type nearestSearch struct {
+	p       *Point
+	k       int
+	maxDist float64
+	accept  func(*Point) bool
+	best    []candidate
+}
+
+func (s *nearestSearch) full() bool {
+	return s.k > 0 && len(s.best) == s.k
+}
+
+// bound returns the squared distance a node has to beat to be considered.
+func (s *nearestSearch) bound() float64 {
+	if s.full() {
+		return s.best[len(s.best)-1].dist
+	}
+	return s.maxDist
+}
+
+// search does a DFS search in the KDTree, tracking the best nodes.
+func (s *nearestSearch) search(node *KDNode) {
 	if node == nil {
 		return
 	}
 
 	// 1) "Visit" node
-	insertNeighbor(p, node, k, best)
+	s.insert(node)
 
 	// 2) Determine search path
-	leftFirst := (p.Dimension(node.axis) < node.Point.Dimension(node.axis))
+	leftFirst := (s.p.Dimension(node.axis) < node.Point.Dimension(node.axis))
 	first, second := node.Left, node.Right
 	if !leftFirst {
 		first, second = node.Right, node.Left
 	}
 
-	knnSearch(p, k, first, best)
+	s.search(first)
 
 	// 3) Check if we need to search the other side
 	// planeDistance = difference in the splitting dimension
-	axisDist := float32(math.Abs(float64(node.Point.Dimension(node.axis) - p.Dimension(node.axis))))
-	if len(*best) < k || axisDist*axisDist < distSquared(p, (*best)[len(*best)-1].Point) {
-		knnSearch(p, k, second, best)
+	axisDist := math.Abs(node.Point.Dimension(node.axis) - s.p.Dimension(node.axis))
+	if axisDist*axisDist <= s.bound() {
+		s.search(second)
 	}
 }
 
-// insertNeighbor tries to insert 'node' into 'best' (up to k neighbors),
-// keeping them sorted by ascending distance from p.
-func insertNeighbor(p *Point, node *KDNode, k int, best *[]*KDNode) {
-	d := distSquared(p, node.Point)
-	if len(*best) < k {
-		*best = append(*best, node)
-		sort.Slice(*best, func(i, j int) bool {
-			return distSquared(p, (*best)[i].Point) < distSquared(p, (*best)[j].Point)
-		})
-	} else {
-		// compare with worst so far
-		worstDist := distSquared(p, (*best)[k-1].Point)
-		if d < worstDist {
-			(*best)[k-1] = node
-			// re-sort
-			sort.Slice(*best, func(i, j int) bool {
-				return distSquared(p, (*best)[i].Point) < distSquared(p, (*best)[j].Point)
-			})
+// insert tries to insert 'node' into the result set, keeping it sorted by
+// ascending distance from p.
+func (s *nearestSearch) insert(node *KDNode) {
+	d := distSquared(s.p, node.Point)
+	if d > s.maxDist || (s.full() && d >= s.bound()) {
+		return
+	}
+	if s.accept != nil && !s.accept(node.Point) {
+		return
+	}
+
+	pos := sort.Search(len(s.best), func(i int) bool {
+		return s.best[i].dist > d
+	})
+	if s.full() {
+		// drop the worst so far
+		s.best = s.best[:len(s.best)-1]
+	}
+	s.best = append(s.best, candidate{})
+	copy(s.best[pos+1:], s.best[pos:])
+	s.best[pos] = candidate{node: node, dist: d}
+}
+
+// -------------------------------------------------------------------
+// Range Search
+// -------------------------------------------------------------------
+
+// Range returns all points whose latitude/longitude lie inside the given
+// rectangle. A minLng greater than maxLng denotes a rectangle crossing the
+// antimeridian.
+func (t *KDTree) Range(minLat, minLng, maxLat, maxLng float64) []*Point {
+	minLat = math.Max(minLat, -90)
+	maxLat = math.Min(maxLat, 90)
+	if t.Root == nil || minLat > maxLat {
+		return nil
+	}
+	if minLng > maxLng {
+		return append(
+			t.Range(minLat, minLng, maxLat, 180),
+			t.Range(minLat, -180, maxLat, maxLng)...,
+		)
+	}
+
+	lo, hi := sphereBounds(minLat, minLng, maxLat, maxLng)
+	out := make([]*Point, 0)
+	rangeSearch(t.Root, lo, hi, func(p *Point) bool {
+		lat := float64(p.Coordinates[0])
+		lng := float64(p.Coordinates[1])
+		return lat >= minLat && lat <= maxLat && lng >= minLng && lng <= maxLng
+	}, &out)
+	return out
+}
+
+func rangeSearch(node *KDNode, lo, hi [3]float64, inside func(*Point) bool, out *[]*Point) {
+	if node == nil {
+		return
+	}
+
+	within := true
+	for i := 0; i < node.Point.Dimensions(); i++ {
+		if node.Point.Dimension(i) < lo[i] || node.Point.Dimension(i) > hi[i] {
+			within = false
+			break
 		}
 	}
+	if within && inside(node.Point) {
+		*out = append(*out, node.Point)
+	}
+
+	split := node.Point.Dimension(node.axis)
+	if lo[node.axis] <= split {
+		rangeSearch(node.Left, lo, hi, inside, out)
+	}
+	if hi[node.axis] >= split {
+		rangeSearch(node.Right, lo, hi, inside, out)
+	}
+}
+
+// sphereBounds returns an axis-aligned box in unit sphere coordinates that
+// encloses the given latitude/longitude rectangle. The box is conservative,
+// callers still have to check candidates against the rectangle itself.
+func sphereBounds(minLat, minLng, maxLat, maxLng float64) ([3]float64, [3]float64) {
+	const eps = 1e-9
+	rad := math.Pi / 180
+
+	cosLatMin := math.Min(math.Cos(minLat*rad), math.Cos(maxLat*rad))
+	cosLatMax := math.Max(math.Cos(minLat*rad), math.Cos(maxLat*rad))
+	if minLat <= 0 && maxLat >= 0 {
+		cosLatMax = 1
+	}
+
+	cosLngMin := math.Min(math.Cos(minLng*rad), math.Cos(maxLng*rad))
+	cosLngMax := math.Max(math.Cos(minLng*rad), math.Cos(maxLng*rad))
+	if minLng <= 0 && maxLng >= 0 {
+		cosLngMax = 1
+	}
+	if minLng <= -180 || maxLng >= 180 {
+		cosLngMin = -1
+	}
+
+	sinLngMin := math.Min(math.Sin(minLng*rad), math.Sin(maxLng*rad))
+	sinLngMax := math.Max(math.Sin(minLng*rad), math.Sin(maxLng*rad))
+	if minLng <= 90 && maxLng >= 90 {
+		sinLngMax = 1
+	}
+	if minLng <= -90 && maxLng >= -90 {
+		sinLngMin = -1
+	}
+
+	xMin, xMax := productBounds(cosLatMin, cosLatMax, cosLngMin, cosLngMax)
+	yMin, yMax := productBounds(cosLatMin, cosLatMax, sinLngMin, sinLngMax)
+
+	lo := [3]float64{xMin - eps, yMin - eps, math.Sin(minLat*rad) - eps}
+	hi := [3]float64{xMax + eps, yMax + eps, math.Sin(maxLat*rad) + eps}
+	return lo, hi
+}
+
+// productBounds returns the range of a*b for a in [aMin, aMax] and b in
+// [bMin, bMax].
+func productBounds(aMin, aMax, bMin, bMax float64) (float64, float64) {
+	products := [4]float64{aMin * bMin, aMin * bMax, aMax * bMin, aMax * bMax}
+	lo, hi := products[0], products[0]
+	for _, v := range products[1:] {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	return lo, hi
 }
 
-func distSquared(a, b *Point) float32 {
+// distSquared returns the squared chord length between two points on the unit
+// sphere. It is monotonic in the great-circle distance, so it can be used for
+// ordering and pruning without the cost of the trigonometry.
+func distSquared(a, b *Point) float64 {
 	dx := a.Dimension(0) - b.Dimension(0)
 	dy := a.Dimension(1) - b.Dimension(1)
-	return dx*dx + dy*dy
+	dz := a.Dimension(2) - b.Dimension(2)
+	return dx*dx + dy*dy + dz*dz
 }
 
 // -------------------------------------------------------------------
@@ -375,7 +571,8 @@ For each node:
 1. Write a 1 byte flag: 0 = nil node, 1 = non-nil
 2. If non-nil:
    2.1. Write n.Point.ID (int64)
-   2.2. Write n.Point.Coordinates[2] (two float32s)
+   2.2. Write n.Point.Coordinates[2] (two float32s, lat/lng; the unit
+        sphere position is recomputed on load)
    2.3. Write n.axis (int32)
    2.4. Recursively write n.Left
    2.5. Recursively write n.Right
@@ -492,10 +689,7 @@ func readNode(r io.Reader) (*KDNode, error) {
 	}
 
 	return &KDNode{
-		Point: &Point{
-			ID:          id,
-			Coordinates: [2]float32{x, y},
-		},
+		Point: NewPoint(id, [2]float32{x, y}),
 		axis:  int(axis),
 		Left:  leftNode,
 		Right: rightNode,
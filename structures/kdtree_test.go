@@ -0,0 +1,267 @@
+package structures
+
+import (
+	"bytes"
+	"hstin/gocoder/geo"
+	"math"
+	"sort"
+	"testing"
+)
+
+// testPoints returns points every 2° of latitude and 5° of longitude, so
+// there are points on both sides of the antimeridian and close to the poles.
+func testPoints() []*Point {
+	var points []*Point
+	id := int64(1)
+	for lat := -88; lat <= 88; lat += 2 {
+		for lng := -180; lng < 180; lng += 5 {
+			points = append(points, NewPoint(id, [2]float32{float32(lat), float32(lng)}))
+			id++
+		}
+	}
+	// Single points right at the poles and the antimeridian
+	points = append(points,
+		NewPoint(id, [2]float32{90, 0}),
+		NewPoint(id+1, [2]float32{-90, 0}),
+		NewPoint(id+2, [2]float32{0.5, 179.9}),
+		NewPoint(id+3, [2]float32{0.5, -179.9}),
+	)
+	return points
+}
+
+func distance(a, b *Point) float64 {
+	return geo.Haversine(
+		float64(a.Coordinates[0]), float64(a.Coordinates[1]),
+		float64(b.Coordinates[0]), float64(b.Coordinates[1]),
+	)
+}
+
+// bruteNearest returns the IDs of the k nearest points within radius, like
+// Nearest but by comparing every point.
+func bruteNearest(points []*Point, p *Point, k int, radius float64) []int64 {
+	sorted := append([]*Point(nil), points...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return distSquared(p, sorted[i]) < distSquared(p, sorted[j])
+	})
+	var ids []int64
+	for _, q := range sorted {
+		if radius > 0 && distance(p, q) > radius {
+			break
+		}
+		if k > 0 && len(ids) == k {
+			break
+		}
+		ids = append(ids, q.ID)
+	}
+	return ids
+}
+
+func neighborIDs(neighbors []Neighbor) []int64 {
+	ids := make([]int64, len(neighbors))
+	for i, n := range neighbors {
+		ids[i] = n.ID
+	}
+	return ids
+}
+
+func TestNearest(t *testing.T) {
+	points := testPoints()
+	byID := make(map[int64]*Point, len(points))
+	for _, p := range points {
+		byID[p.ID] = p
+	}
+	tree := New(points)
+
+	tests := []struct {
+		name   string
+		lat    float64
+		lng    float64
+		k      int
+		radius float64
+	}{
+		{"antimeridian east", 0.4, 179.95, 4, 0},
+		{"antimeridian west", 0.4, -179.95, 4, 0},
+		{"antimeridian radius", -10, 179, 0, 800000},
+		{"north pole", 89.9, 45, 8, 0},
+		{"south pole", -89.9, -135, 8, 0},
+		{"north pole radius", 87.5, 170, 0, 500000},
+		{"equator", 0, 0, 5, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := NewPoint(0, [2]float32{float32(test.lat), float32(test.lng)})
+			got := tree.Nearest(p, test.k, test.radius, nil)
+			want := bruteNearest(points, p, test.k, test.radius)
+			if len(want) == 0 {
+				t.Fatal("test has no expected points")
+			}
+			if len(got) != len(want) {
+				t.Fatalf("got %d points %v, want %d %v", len(got), neighborIDs(got), len(want), want)
+			}
+			for i, n := range got {
+				if d := distance(p, n.Point); math.Abs(d-n.Distance) > 1 {
+					t.Errorf("point %d: distance %.1f, want %.1f", n.ID, n.Distance, d)
+				}
+				if i > 0 && n.Distance < got[i-1].Distance {
+					t.Errorf("points not sorted by distance: %v", neighborIDs(got))
+				}
+				// Equal distances may come in any order, compare distances
+				if d := distance(p, byID[want[i]]); math.Abs(d-n.Distance) > 1 {
+					t.Errorf("result %d: point %d at %.1f, want point %d at %.1f", i, n.ID, n.Distance, want[i], d)
+				}
+			}
+		})
+	}
+}
+
+func TestNearestAntimeridian(t *testing.T) {
+	tree := New([]*Point{
+		NewPoint(1, [2]float32{0, 179.9}),
+		NewPoint(2, [2]float32{0, -179.9}),
+		NewPoint(3, [2]float32{0, 170}),
+	})
+
+	// The point across the antimeridian is closer than the one on the same
+	// side
+	got := tree.KNN(NewPoint(0, [2]float32{0, -179.8}), 2)
+	if ids := neighborIDs(got); len(ids) != 2 || ids[0] != 2 || ids[1] != 1 {
+		t.Fatalf("got %v, want [2 1]", ids)
+	}
+	if got[1].Distance > 35000 {
+		t.Errorf("distance across the antimeridian %.0f m, want about 33 km", got[1].Distance)
+	}
+}
+
+func TestNearestAccept(t *testing.T) {
+	tree := New(testPoints())
+	even := func(p *Point) bool { return p.ID%2 == 0 }
+
+	got := tree.Nearest(NewPoint(0, [2]float32{89, 0}), 5, 0, even)
+	if len(got) != 5 {
+		t.Fatalf("got %d points, want 5", len(got))
+	}
+	for _, n := range got {
+		if n.ID%2 != 0 {
+			t.Errorf("point %d does not pass the filter", n.ID)
+		}
+	}
+}
+
+func TestRadius(t *testing.T) {
+	points := testPoints()
+	tree := New(points)
+
+	// The pole itself and the points of the 88° and 86° rings are within
+	// 500 km of the north pole
+	pole := NewPoint(0, [2]float32{90, 0})
+	got := tree.Radius(pole, 500000)
+	want := bruteNearest(points, pole, 0, 500000)
+	if len(got) != len(want) || len(got) != 1+2*72 {
+		t.Fatalf("got %d points, want %d", len(got), len(want))
+	}
+	for _, n := range got {
+		if n.Distance > 500000 {
+			t.Errorf("point %d at %.0f m is outside the radius", n.ID, n.Distance)
+		}
+	}
+
+	if got := tree.Radius(pole, 0); got != nil {
+		t.Errorf("zero radius returned %d points", len(got))
+	}
+}
+
+// bruteRange returns the IDs of the points inside a rectangle, which crosses
+// the antimeridian if minLng > maxLng.
+func bruteRange(points []*Point, minLat, minLng, maxLat, maxLng float64) map[int64]bool {
+	ids := make(map[int64]bool)
+	for _, p := range points {
+		lat, lng := float64(p.Coordinates[0]), float64(p.Coordinates[1])
+		if lat < minLat || lat > maxLat {
+			continue
+		}
+		if minLng <= maxLng && (lng < minLng || lng > maxLng) {
+			continue
+		}
+		if minLng > maxLng && lng < minLng && lng > maxLng {
+			continue
+		}
+		ids[p.ID] = true
+	}
+	return ids
+}
+
+func TestRange(t *testing.T) {
+	points := testPoints()
+	tree := New(points)
+
+	tests := []struct {
+		name                           string
+		minLat, minLng, maxLat, maxLng float64
+	}{
+		{"antimeridian", -5, 170, 5, -170},
+		{"antimeridian narrow", 0, 179.5, 1, -179.5},
+		{"north pole", 85, -180, 90, 180},
+		{"north pole wedge", 80, 100, 90, 120},
+		{"south pole across antimeridian", -90, 160, -84, -160},
+		{"equator", -3, -7, 3, 7},
+		{"whole world", -90, -180, 90, 180},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := tree.Range(test.minLat, test.minLng, test.maxLat, test.maxLng)
+			want := bruteRange(points, test.minLat, test.minLng, test.maxLat, test.maxLng)
+			if len(want) == 0 {
+				t.Fatal("test has no expected points")
+			}
+			if len(got) != len(want) {
+				t.Errorf("got %d points, want %d", len(got), len(want))
+			}
+			seen := make(map[int64]bool)
+			for _, p := range got {
+				if !want[p.ID] {
+					t.Errorf("point %d %v is outside the rectangle", p.ID, p.Coordinates)
+				}
+				if seen[p.ID] {
+					t.Errorf("point %d returned twice", p.ID)
+				}
+				seen[p.ID] = true
+			}
+		})
+	}
+}
+
+func TestKDTreeSaveLoad(t *testing.T) {
+	points := testPoints()
+	tree := New(points)
+
+	var buf bytes.Buffer
+	if err := tree.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var loaded KDTree
+	if err := loaded.Load(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPoint(0, [2]float32{0.4, 179.95})
+	want := neighborIDs(tree.KNN(p, 10))
+	got := neighborIDs(loaded.KNN(p, 10))
+	if len(got) != len(want) {
+		t.Fatalf("loaded tree returned %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("loaded tree returned %v, want %v", got, want)
+		}
+	}
+}
+
+func TestNearestHugeK(t *testing.T) {
+	points := testPoints()
+	tree := New(points)
+
+	// A k far beyond the number of points must not reserve memory for it
+	if got := tree.Nearest(NewPoint(0, [2]float32{0, 0}), 2000000000, 0, nil); len(got) != len(points) {
+		t.Fatalf("got %d points, want %d", len(got), len(points))
+	}
+}
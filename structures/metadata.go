@@ -0,0 +1,44 @@
+package structures
+
+import (
+	"bytes"
+	"encoding/json"
+	"hstin/gocoder/mapping"
+	"io"
+)
+
+// Metadata describes how a database was generated. It is stored as JSON so
+// it stays readable without knowing the generator version.
+type Metadata struct {
+	Languages   []string    `json:"languages"`
+	RankFormula string      `json:"rank_formula"`
+	Ranking     interface{} `json:"ranking"`
+	Filters     interface{} `json:"filters"`
+	// Layers and PlaceTypes are the lists the layer and place type numbers
+	// of the nodes refer to, including the ones added by tag filters.
+	Layers     []string `json:"layers"`
+	PlaceTypes []string `json:"place_types"`
+	// Categories is the list the category numbers of the nodes refer to,
+	// Taxonomy the POI taxonomy with the keywords used in queries.
+	Categories []string           `json:"categories,omitempty"`
+	Taxonomy   []mapping.Category `json:"taxonomy,omitempty"`
+}
+
+func (m *Metadata) Save(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(m)
+}
+
+func (m *Metadata) Load(data []byte) error {
+	return m.LoadFromReader(bytes.NewReader(data))
+}
+
+func (m *Metadata) LoadFromReader(r io.Reader) error {
+	return json.NewDecoder(r).Decode(m)
+}
+
+func (m *Metadata) LoadFromFile(file io.ReaderAt, offset int64, size uint64) error {
+	reader := io.NewSectionReader(file, offset, int64(size))
+	return m.LoadFromReader(reader)
+}
@@ -0,0 +1,131 @@
+package structures
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sort"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+	"github.com/tidwall/rtree"
+)
+
+// NaturalArea is the simplified polygon of a natural feature document, e.g.
+// a sea, lake or island, used to find the feature containing a coordinate.
+type NaturalArea struct {
+	DocID    int64
+	Geometry orb.MultiPolygon
+	area     float64
+}
+
+type NaturalIndex struct {
+	Areas []NaturalArea
+	tree  rtree.RTree
+}
+
+func NewNaturalIndex() *NaturalIndex {
+	return &NaturalIndex{
+		Areas: make([]NaturalArea, 0),
+	}
+}
+
+func (n *NaturalIndex) Add(area NaturalArea) {
+	n.Areas = append(n.Areas, area)
+	n.insert(len(n.Areas) - 1)
+}
+
+func (n *NaturalIndex) insert(i int) {
+	area := &n.Areas[i]
+	area.area = planar.Area(area.Geometry)
+	bound := area.Geometry.Bound()
+	n.tree.Insert(
+		[2]float64{bound.Min[0], bound.Min[1]},
+		[2]float64{bound.Max[0], bound.Max[1]},
+		i,
+	)
+}
+
+// Contains returns the natural areas containing the coordinate, the
+// smallest first, so a lake on an island comes before the island and the
+// island before the sea around it.
+func (n *NaturalIndex) Contains(lat, lng float64) []*NaturalArea {
+	point := orb.Point{lng, lat}
+	out := make([]*NaturalArea, 0)
+
+	n.tree.Search([2]float64{lng, lat}, [2]float64{lng, lat}, func(min, max [2]float64, data interface{}) bool {
+		area := &n.Areas[data.(int)]
+		if planar.MultiPolygonContains(area.Geometry, point) {
+			out = append(out, area)
+		}
+		return true
+	})
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].area < out[j].area
+	})
+
+	return out
+}
+
+// -------------------------------------------------------------------
+// Custom Binary Serialization
+// -------------------------------------------------------------------
+/*
+Format:
+
+1) uint64 = number of areas
+   then for each:
+    1.1) int64 = docID
+    1.2) multipolygon, see writeMultiPolygon
+*/
+
+func (n *NaturalIndex) Save(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+
+	if err := writeUint64(writer, uint64(len(n.Areas))); err != nil {
+		return err
+	}
+	for _, area := range n.Areas {
+		if err := writeInt64(writer, area.DocID); err != nil {
+			return err
+		}
+		if err := writeMultiPolygon(writer, area.Geometry); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+func (n *NaturalIndex) Load(data []byte) error {
+	return n.LoadFromReader(bytes.NewReader(data))
+}
+
+func (n *NaturalIndex) LoadFromReader(r io.Reader) error {
+	reader := bufio.NewReaderSize(r, 256*1024) // 256KB buffer
+
+	count, err := readUint64(reader)
+	if err != nil {
+		return err
+	}
+
+	n.Areas = make([]NaturalArea, count)
+	n.tree = rtree.RTree{}
+	for i := range n.Areas {
+		if n.Areas[i].DocID, err = readInt64(reader); err != nil {
+			return err
+		}
+		if n.Areas[i].Geometry, err = readMultiPolygon(reader); err != nil {
+			return err
+		}
+		n.insert(i)
+	}
+
+	return nil
+}
+
+func (n *NaturalIndex) LoadFromFile(file io.ReaderAt, offset int64, size uint64) error {
+	reader := io.NewSectionReader(file, offset, int64(size))
+	return n.LoadFromReader(reader)
+}
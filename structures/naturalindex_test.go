@@ -0,0 +1,46 @@
+package structures
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestNaturalIndex(t *testing.T) {
+	index := NewNaturalIndex()
+	index.Add(NaturalArea{DocID: 1, Geometry: rectangle(-4, 51, 9, 61)})
+	index.Add(NaturalArea{DocID: 2, Geometry: rectangle(8.5, 53.5, 9, 54)})
+	index.Add(NaturalArea{DocID: 3, Geometry: rectangle(9, 47.5, 9.75, 47.75)})
+
+	var buf bytes.Buffer
+	if err := index.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewNaturalIndex()
+	if err := loaded.Load(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		lat, lng float64
+		want     []int64
+	}{
+		// The smallest area comes first
+		{"both seas", 53.75, 8.75, []int64{2, 1}},
+		{"open sea", 55, 3, []int64{1}},
+		{"lake", 47.6, 9.5, []int64{3}},
+		{"land", 52.5, 13.4, []int64{}},
+	}
+	for _, test := range tests {
+		for _, x := range []*NaturalIndex{index, loaded} {
+			got := make([]int64, 0)
+			for _, area := range x.Contains(test.lat, test.lng) {
+				got = append(got, area.DocID)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+			}
+		}
+	}
+}
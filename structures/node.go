@@ -5,24 +5,38 @@ import (
 	"math"
 )
 
-// Node is a fixed-size (64-byte) structure that can be directly mapped into memory.
-// Field layout chosen to minimize padding and ensure 64-byte total size.
+// Node is a fixed-size (96-byte) structure that can be directly mapped into memory.
+// Field layout chosen to minimize padding and ensure 96-byte total size.
 //
 // Layout (with offsets and sizes):
 
 type Node struct {
-	ID           int64   // 0-7
-	NameOffset   uint64  // 8-15
-	RegionOffset uint64  // 16-23
-	Population   uint32  // 24-27
-	Rank         uint16  // 28-29
-	Timezone     uint16  // 30-31
-	Country      uint8   // 32
-	_            [3]byte // 33-35 (padding)
+	ID           int64  // 0-7
+	NameOffset   uint64 // 8-15
+	RegionOffset uint64 // 16-23
+	Population   uint32 // 24-27
+	Rank         uint16 // 28-29
+	Timezone     uint16 // 30-31
+	Country      uint8  // 32
+	Layer        uint8  // 33
+	PlaceType    uint8  // 34
+	OSMType      uint8  // 35
 
 	Center      [2]float32 // 36-43 (8 bytes)
 	BoundingBox [4]float32 // 44-59 (16 bytes)
-	_           [4]byte    // 60-63 (padding to reach 64 bytes)
+	Wikidata    uint32     // 60-63 (item number without the Q prefix)
+
+	HierarchyOffset uint64  // 64-71
+	Importance      float32 // 72-75 (normalized wikimedia importance, 0-1)
+	Parent          uint32  // 76-79 (document id + 1 of the parent settlement, 0 if none)
+
+	// Centroid of the area for ways, relations and nodes with a merged
+	// area, zero otherwise. Center is a point on the surface of the area.
+	Centroid [2]float32 // 80-87
+
+	Postcode  uint32 // 88-91 (index + 1 into the postcode index, 0 if none)
+	Category  uint16 // 92-93 (POI category, see mapping.Categories)
+	Elevation int16  // 94-95 (metres from the ele tag, 0 if unknown)
 }
 
 type Region struct {
@@ -30,19 +44,49 @@ type Region struct {
 	SubRegion string
 }
 
+// AdminLevel is one entry of the admin hierarchy of a node. Names holds the
+// default name under "name" and one entry per configured language.
+type AdminLevel struct {
+	Placetype string
+	ID        int64
+	Names     map[string]string
+}
+
 type TmpNode struct {
 	ID          int64
 	Names       map[string]string
 	Regions     map[string]Region
+	Hierarchy   []AdminLevel
 	Country     string
 	Center      [2]float32
+	Centroid    [2]float32
 	BoundingBox [4]float32
 	Rank        int
 	Population  int64
 	Timezone    string
+	Layer       string
+	PlaceType   string
+	// Source is "osm" for OSM objects and "wof" for Who's On First records.
+	Source     string
+	OSMType    string
+	Wikidata   string
+	Importance float64
+	// Parent is the document id + 1 of the settlement the node belongs to,
+	// 0 if it has none.
+	Parent int64
+	// Postcode is the index + 1 of the postcode of the node, 0 if unknown.
+	Postcode int64
+	// Category is the POI category, empty for places.
+	Category string
+	// Codes are the transport identifiers like IATA or UIC codes.
+	Codes []Code
+	// Elevation in metres, 0 if unknown.
+	Elevation int64
+	// IsIn is the is_in:city, is_in:town or is_in tag of sub-settlements.
+	IsIn string
 }
 
-const NodeSize = 64
+const NodeSize = 96
 
 func (n *Node) Serialize() []byte {
 	var buf [NodeSize]byte
@@ -54,7 +98,9 @@ func (n *Node) Serialize() []byte {
 	binary.LittleEndian.PutUint16(buf[28:30], n.Rank)
 	binary.LittleEndian.PutUint16(buf[30:32], n.Timezone)
 	buf[32] = n.Country
-	// buf[33:36] is padding (already zero)
+	buf[33] = n.Layer
+	buf[34] = n.PlaceType
+	buf[35] = n.OSMType
 
 	binary.LittleEndian.PutUint32(buf[36:40], math.Float32bits(n.Center[0]))
 	binary.LittleEndian.PutUint32(buf[40:44], math.Float32bits(n.Center[1]))
@@ -64,6 +110,16 @@ func (n *Node) Serialize() []byte {
 		binary.LittleEndian.PutUint32(buf[off:off+4], math.Float32bits(n.BoundingBox[i]))
 		off += 4
 	}
+	binary.LittleEndian.PutUint32(buf[60:64], n.Wikidata)
+
+	binary.LittleEndian.PutUint64(buf[64:72], n.HierarchyOffset)
+	binary.LittleEndian.PutUint32(buf[72:76], math.Float32bits(n.Importance))
+	binary.LittleEndian.PutUint32(buf[76:80], n.Parent)
+	binary.LittleEndian.PutUint32(buf[80:84], math.Float32bits(n.Centroid[0]))
+	binary.LittleEndian.PutUint32(buf[84:88], math.Float32bits(n.Centroid[1]))
+	binary.LittleEndian.PutUint32(buf[88:92], n.Postcode)
+	binary.LittleEndian.PutUint16(buf[92:94], n.Category)
+	binary.LittleEndian.PutUint16(buf[94:96], uint16(n.Elevation))
 
 	return buf[:]
 }
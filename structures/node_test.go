@@ -0,0 +1,41 @@
+package structures
+
+import (
+	"bytes"
+	"testing"
+	"unsafe"
+)
+
+func TestNodeLayout(t *testing.T) {
+	// The geocoder maps the serialized nodes straight into memory
+	if size := unsafe.Sizeof(Node{}); size != NodeSize {
+		t.Fatalf("Node is %d bytes, want %d", size, NodeSize)
+	}
+
+	node := Node{
+		ID:              240109189,
+		NameOffset:      1,
+		RegionOffset:    2,
+		Population:      3700000,
+		Rank:            900,
+		Timezone:        3,
+		Country:         4,
+		Layer:           5,
+		PlaceType:       6,
+		OSMType:         7,
+		Center:          [2]float32{52.517, 13.3889},
+		BoundingBox:     [4]float32{52.3, 13.0, 52.7, 13.8},
+		Wikidata:        64,
+		HierarchyOffset: 8,
+		Importance:      0.9,
+		Parent:          9,
+		Centroid:        [2]float32{52.5, 13.4},
+		Postcode:        10,
+		Category:        11,
+		Elevation:       -12,
+	}
+	memory := unsafe.Slice((*byte)(unsafe.Pointer(&node)), NodeSize)
+	if serialized := node.Serialize(); !bytes.Equal(serialized, memory) {
+		t.Errorf("serialized %x, in memory %x", serialized, memory)
+	}
+}
@@ -0,0 +1,254 @@
+package structures
+
+import (
+	"bufio"
+	"bytes"
+	"hstin/gocoder/geo"
+	"hstin/gocoder/mapping"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+	"github.com/tidwall/rtree"
+)
+
+// Postcode is a postal code area. Postcodes from boundary=postal_code
+// relations and Who's On First polygons have a geometry, postcodes derived
+// from the addr:postcode of addresses only a centroid and bounding box.
+type Postcode struct {
+	Code    string
+	Country string
+	// Source is "osm", "wof" or "addresses"
+	Source      string
+	OSMType     uint8
+	ID          int64
+	Center      [2]float32 // lat, lng
+	BoundingBox [4]float32 // minLat, minLng, maxLat, maxLng
+	Geometry    orb.MultiPolygon
+}
+
+// PostcodeIndex stores the postcodes sorted by their lookup key, so the
+// position of a postcode can be referenced from the nodes.
+type PostcodeIndex struct {
+	Postcodes []Postcode
+	areas     rtree.RTree
+	centers   rtree.RTree
+}
+
+func NewPostcodeIndex() *PostcodeIndex {
+	return &PostcodeIndex{
+		Postcodes: make([]Postcode, 0),
+	}
+}
+
+func (p *PostcodeIndex) Add(postcode Postcode) {
+	p.Postcodes = append(p.Postcodes, postcode)
+}
+
+// Optimize sorts the postcodes by key and country and builds the spatial
+// indexes. Positions returned by Lookup and Find are only stable after it.
+func (p *PostcodeIndex) Optimize() {
+	sort.SliceStable(p.Postcodes, func(i, j int) bool {
+		a, b := mapping.PostcodeKey(p.Postcodes[i].Code), mapping.PostcodeKey(p.Postcodes[j].Code)
+		if a != b {
+			return a < b
+		}
+		return p.Postcodes[i].Country < p.Postcodes[j].Country
+	})
+
+	p.buildTree()
+}
+
+func (p *PostcodeIndex) buildTree() {
+	p.areas = rtree.RTree{}
+	p.centers = rtree.RTree{}
+	for i, postcode := range p.Postcodes {
+		if len(postcode.Geometry) > 0 {
+			bound := postcode.Geometry.Bound()
+			p.areas.Insert(
+				[2]float64{bound.Min[0], bound.Min[1]},
+				[2]float64{bound.Max[0], bound.Max[1]},
+				i,
+			)
+		}
+		center := [2]float64{float64(postcode.Center[1]), float64(postcode.Center[0])}
+		p.centers.Insert(center, center, i)
+	}
+}
+
+// Get returns the postcode at a position.
+func (p *PostcodeIndex) Get(i int) *Postcode {
+	if i < 0 || i >= len(p.Postcodes) {
+		return nil
+	}
+	return &p.Postcodes[i]
+}
+
+// Lookup returns the positions of all postcodes with the code, e.g. the
+// same postcode in several countries.
+func (p *PostcodeIndex) Lookup(code string) []int {
+	key := mapping.PostcodeKey(code)
+	start := sort.Search(len(p.Postcodes), func(i int) bool {
+		return mapping.PostcodeKey(p.Postcodes[i].Code) >= key
+	})
+
+	out := make([]int, 0)
+	for i := start; i < len(p.Postcodes) && mapping.PostcodeKey(p.Postcodes[i].Code) == key; i++ {
+		out = append(out, i)
+	}
+	return out
+}
+
+// Find returns the position of the smallest postcode area containing the
+// coordinate with a distance of 0, or the postcode with the nearest centre
+// within radius metres and its distance. It returns -1 if there is none.
+func (p *PostcodeIndex) Find(lat, lng, radius float64) (int, float64) {
+	point := orb.Point{lng, lat}
+
+	found, smallest := -1, math.Inf(1)
+	p.areas.Search([2]float64{lng, lat}, [2]float64{lng, lat}, func(min, max [2]float64, data interface{}) bool {
+		i := data.(int)
+		if !planar.MultiPolygonContains(p.Postcodes[i].Geometry, point) {
+			return true
+		}
+		if area := (max[0] - min[0]) * (max[1] - min[1]); area < smallest {
+			found, smallest = i, area
+		}
+		return true
+	})
+	if found >= 0 {
+		return found, 0
+	}
+
+	metresPerDegree := geo.EarthRadius * math.Pi / 180
+	dLat := radius / metresPerDegree
+	dLng := dLat / math.Max(math.Cos(lat*math.Pi/180), 1e-6)
+
+	best := radius
+	p.centers.Search(
+		[2]float64{lng - dLng, lat - dLat},
+		[2]float64{lng + dLng, lat + dLat},
+		func(min, max [2]float64, data interface{}) bool {
+			i := data.(int)
+			center := p.Postcodes[i].Center
+			if d := geo.Haversine(lat, lng, float64(center[0]), float64(center[1])); d <= best {
+				found, best = i, d
+			}
+			return true
+		},
+	)
+
+	return found, best
+}
+
+// -------------------------------------------------------------------
+// Custom Binary Serialization
+// -------------------------------------------------------------------
+/*
+Format:
+
+1) uint64 = number of postcodes
+   then for each:
+    1.1) string = code
+    1.2) string = country
+    1.3) string = source
+    1.4) byte = OSM type
+    1.5) int64 = ID
+    1.6) float32 lat, float32 lng
+    1.7) 4x float32 = bounding box
+    1.8) multipolygon (see AdminIndex), 0 polygons if there is no geometry
+*/
+
+func (p *PostcodeIndex) Save(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+
+	if err := writeUint64(writer, uint64(len(p.Postcodes))); err != nil {
+		return err
+	}
+	for _, postcode := range p.Postcodes {
+		for _, val := range []string{postcode.Code, postcode.Country, postcode.Source} {
+			if err := writeString(writer, val); err != nil {
+				return err
+			}
+		}
+		if err := writeByte(writer, postcode.OSMType); err != nil {
+			return err
+		}
+		if err := writeInt64(writer, postcode.ID); err != nil {
+			return err
+		}
+		for _, val := range postcode.Center {
+			if err := writeFloat32(writer, val); err != nil {
+				return err
+			}
+		}
+		for _, val := range postcode.BoundingBox {
+			if err := writeFloat32(writer, val); err != nil {
+				return err
+			}
+		}
+		if err := writeMultiPolygon(writer, postcode.Geometry); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+func (p *PostcodeIndex) Load(data []byte) error {
+	return p.LoadFromReader(bytes.NewReader(data))
+}
+
+func (p *PostcodeIndex) LoadFromReader(r io.Reader) error {
+	reader := bufio.NewReaderSize(r, 256*1024) // 256KB buffer
+
+	count, err := readUint64(reader)
+	if err != nil {
+		return err
+	}
+
+	p.Postcodes = make([]Postcode, count)
+	for i := range p.Postcodes {
+		postcode := &p.Postcodes[i]
+		if postcode.Code, err = readString(reader); err != nil {
+			return err
+		}
+		if postcode.Country, err = readString(reader); err != nil {
+			return err
+		}
+		if postcode.Source, err = readString(reader); err != nil {
+			return err
+		}
+		if postcode.OSMType, err = readByte(reader); err != nil {
+			return err
+		}
+		if postcode.ID, err = readInt64(reader); err != nil {
+			return err
+		}
+		for k := range postcode.Center {
+			if postcode.Center[k], err = readFloat32(reader); err != nil {
+				return err
+			}
+		}
+		for k := range postcode.BoundingBox {
+			if postcode.BoundingBox[k], err = readFloat32(reader); err != nil {
+				return err
+			}
+		}
+		if postcode.Geometry, err = readMultiPolygon(reader); err != nil {
+			return err
+		}
+	}
+
+	// The postcodes are stored sorted, only the spatial indexes are rebuilt
+	p.buildTree()
+
+	return nil
+}
+
+func (p *PostcodeIndex) LoadFromFile(file io.ReaderAt, offset int64, size uint64) error {
+	reader := io.NewSectionReader(file, offset, int64(size))
+	return p.LoadFromReader(reader)
+}
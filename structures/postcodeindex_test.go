@@ -0,0 +1,83 @@
+package structures
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/orb"
+)
+
+func TestPostcodeIndex(t *testing.T) {
+	index := NewPostcodeIndex()
+	index.Add(Postcode{Code: "SW1A 1AA", Country: "GB", Source: "addresses", Center: [2]float32{51.5, -0.125}, BoundingBox: [4]float32{51.5, -0.125, 51.5, -0.125}, Geometry: orb.MultiPolygon{}})
+	index.Add(Postcode{Code: "10117", Country: "DE", Source: "osm", OSMType: 2, ID: 2, Center: [2]float32{52.5625, 13.375}, Geometry: rectangle(13.25, 52.5, 13.5, 52.625)})
+	index.Add(Postcode{Code: "10115", Country: "US", Source: "addresses", Center: [2]float32{40.75, -73.5}, Geometry: orb.MultiPolygon{}})
+	index.Add(Postcode{Code: "10115", Country: "DE", Source: "osm", OSMType: 2, ID: 1, Center: [2]float32{52.53125, 13.40625}, Geometry: rectangle(13.375, 52.5, 13.4375, 52.5625)})
+	index.Optimize()
+
+	var buf bytes.Buffer
+	if err := index.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewPostcodeIndex()
+	if err := loaded.Load(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Postcodes, index.Postcodes) {
+		t.Fatalf("loaded %+v, want %+v", loaded.Postcodes, index.Postcodes)
+	}
+
+	lookups := []struct {
+		code string
+		want []string
+	}{
+		// The same postcode in several countries, sorted by country
+		{"10115", []string{"DE", "US"}},
+		{"sw1a1aa", []string{"GB"}},
+		{"SW1A  1AA", []string{"GB"}},
+		{"10999", []string{}},
+	}
+	for _, test := range lookups {
+		countries := make([]string, 0)
+		for _, i := range loaded.Lookup(test.code) {
+			countries = append(countries, loaded.Get(i).Country)
+		}
+		if !reflect.DeepEqual(countries, test.want) {
+			t.Errorf("Lookup(%q) = %v, want %v", test.code, countries, test.want)
+		}
+	}
+
+	finds := []struct {
+		name     string
+		lat, lng float64
+		radius   float64
+		want     string
+		inside   bool
+	}{
+		// The smallest containing area wins
+		{"inside both", 52.53, 13.4, 2000, "10115", true},
+		{"inside one", 52.6, 13.3, 2000, "10117", true},
+		{"near a centre", 51.501, -0.125, 500, "SW1A 1AA", false},
+		{"too far", 51.51, -0.125, 500, "", false},
+	}
+	for _, test := range finds {
+		i, distance := loaded.Find(test.lat, test.lng, test.radius)
+		if test.want == "" {
+			if i >= 0 {
+				t.Errorf("%s: got %q, want none", test.name, loaded.Get(i).Code)
+			}
+			continue
+		}
+		if i < 0 || loaded.Get(i).Code != test.want {
+			t.Errorf("%s: got position %d, want %q", test.name, i, test.want)
+			continue
+		}
+		if (distance == 0) != test.inside {
+			t.Errorf("%s: distance %.0f m", test.name, distance)
+		}
+	}
+	if loaded.Get(-1) != nil || loaded.Get(len(loaded.Postcodes)) != nil {
+		t.Error("Get out of range returned a postcode")
+	}
+}
@@ -0,0 +1,132 @@
+package structures
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Names of the sections written by the generator.
+const (
+	SectionMetadata       = "metadata"
+	SectionAdmin          = "admin"
+	SectionCountries      = "countries"
+	SectionWikidata       = "wikidata"
+	SectionStreets        = "streets"
+	SectionAddresses      = "addresses"
+	SectionInterpolations = "interpolations"
+	SectionPostcodes      = "postcodes"
+	SectionCodes          = "codes"
+	SectionNatural        = "natural"
+)
+
+// Section is a named blob stored in the section directory at the end of the
+// database file. The fixed header only has room for the original data
+// structures, everything added later is stored as a section.
+//
+// Layout of the directory (directly after the KD tree):
+//
+//	[8 bytes: number_of_sections (uint64)]
+//	For each section:
+//	  [8 bytes: length_of_name (uint64)]
+//	  [N bytes: name (UTF-8)]
+//	  [8 bytes: length_of_data (uint64)]
+//	  [M bytes: data]
+type Section struct {
+	Name   string
+	Offset int64
+	Size   uint64
+}
+
+func WriteSections(w io.Writer, names []string, data [][]byte) error {
+	if err := writeUint64(w, uint64(len(names))); err != nil {
+		return err
+	}
+	for i, name := range names {
+		if err := writeString(w, name); err != nil {
+			return err
+		}
+		if err := writeUint64(w, uint64(len(data[i]))); err != nil {
+			return err
+		}
+		if _, err := w.Write(data[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadSections reads the section directory starting at offset and returns the
+// location of every section by name. A file without a directory yields an
+// empty map.
+func ReadSections(file io.ReaderAt, offset int64) (map[string]Section, error) {
+	sections := make(map[string]Section)
+
+	var buf [8]byte
+	if _, err := file.ReadAt(buf[:], offset); err != nil {
+		if err == io.EOF {
+			return sections, nil
+		}
+		return nil, err
+	}
+	count := binary.LittleEndian.Uint64(buf[:])
+	offset += 8
+
+	for i := uint64(0); i < count; i++ {
+		if _, err := file.ReadAt(buf[:], offset); err != nil {
+			return nil, err
+		}
+		nameLength := binary.LittleEndian.Uint64(buf[:])
+		offset += 8
+
+		name := make([]byte, nameLength)
+		if _, err := file.ReadAt(name, offset); err != nil {
+			return nil, err
+		}
+		offset += int64(nameLength)
+
+		if _, err := file.ReadAt(buf[:], offset); err != nil {
+			return nil, err
+		}
+		size := binary.LittleEndian.Uint64(buf[:])
+		offset += 8
+
+		sections[string(name)] = Section{
+			Name:   string(name),
+			Offset: offset,
+			Size:   size,
+		}
+		offset += int64(size)
+	}
+
+	return sections, nil
+}
+
+func writeString(w io.Writer, s string) error {
+	if err := writeUint64(w, uint64(len(s))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, s)
+	return err
+}
+
+func readString(r io.Reader) (string, error) {
+	length, err := readUint64(r)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func writeFloat32(w io.Writer, val float32) error {
+	return binary.Write(w, binary.LittleEndian, val)
+}
+
+func readFloat32(r io.Reader) (float32, error) {
+	var val float32
+	err := binary.Read(r, binary.LittleEndian, &val)
+	return val, err
+}
@@ -0,0 +1,200 @@
+package structures
+
+import (
+	"bufio"
+	"bytes"
+	"hstin/gocoder/geo"
+	"io"
+	"math"
+
+	"github.com/paulmach/orb"
+	"github.com/tidwall/rtree"
+)
+
+// StreetGeometry is the merged geometry of a street document, used to find
+// the nearest street when reverse geocoding.
+type StreetGeometry struct {
+	DocID    int64
+	Geometry orb.MultiLineString
+}
+
+type StreetIndex struct {
+	Streets []StreetGeometry
+	tree    rtree.RTree
+}
+
+func NewStreetIndex() *StreetIndex {
+	return &StreetIndex{
+		Streets: make([]StreetGeometry, 0),
+	}
+}
+
+func (s *StreetIndex) Add(street StreetGeometry) {
+	s.Streets = append(s.Streets, street)
+
+	bound := street.Geometry.Bound()
+	s.tree.Insert(
+		[2]float64{bound.Min[0], bound.Min[1]},
+		[2]float64{bound.Max[0], bound.Max[1]},
+		len(s.Streets)-1,
+	)
+}
+
+// Nearest returns the street closest to the coordinate within radius metres
+// and its distance, or nil if there is none.
+func (s *StreetIndex) Nearest(lat, lng, radius float64) (*StreetGeometry, float64) {
+	return s.NearestMatching(lat, lng, radius, nil)
+}
+
+// NearestMatching is Nearest restricted to the streets accepted by accept.
+func (s *StreetIndex) NearestMatching(lat, lng, radius float64, accept func(docID int64) bool) (*StreetGeometry, float64) {
+	metresPerDegree := geo.EarthRadius * math.Pi / 180
+	cosLat := math.Max(math.Cos(lat*math.Pi/180), 1e-6)
+
+	dLat := radius / metresPerDegree
+	dLng := dLat / cosLat
+
+	// Project into a local plane in metres around the query point
+	project := func(p orb.Point) (float64, float64) {
+		return (p[0] - lng) * cosLat * metresPerDegree, (p[1] - lat) * metresPerDegree
+	}
+
+	var nearest *StreetGeometry
+	best := radius
+
+	s.tree.Search(
+		[2]float64{lng - dLng, lat - dLat},
+		[2]float64{lng + dLng, lat + dLat},
+		func(min, max [2]float64, data interface{}) bool {
+			street := &s.Streets[data.(int)]
+			if accept != nil && !accept(street.DocID) {
+				return true
+			}
+			for _, line := range street.Geometry {
+				for i := 0; i+1 < len(line); i++ {
+					ax, ay := project(line[i])
+					bx, by := project(line[i+1])
+					if d := segmentDistance(ax, ay, bx, by); d <= best {
+						best = d
+						nearest = street
+					}
+				}
+			}
+			return true
+		},
+	)
+
+	return nearest, best
+}
+
+// segmentDistance returns the distance of the origin to the segment a-b.
+func segmentDistance(ax, ay, bx, by float64) float64 {
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/length))
+	}
+	return math.Hypot(ax+t*dx, ay+t*dy)
+}
+
+// -------------------------------------------------------------------
+// Custom Binary Serialization
+// -------------------------------------------------------------------
+/*
+Format:
+
+1) uint64 = number of streets
+   then for each:
+    1.1) int64 = docID
+    1.2) uint64 = number of lines
+         for each line: uint64 = number of points, then float32 lng, float32 lat
+*/
+
+func (s *StreetIndex) Save(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+
+	if err := writeUint64(writer, uint64(len(s.Streets))); err != nil {
+		return err
+	}
+	for _, street := range s.Streets {
+		if err := writeInt64(writer, street.DocID); err != nil {
+			return err
+		}
+		if err := writeUint64(writer, uint64(len(street.Geometry))); err != nil {
+			return err
+		}
+		for _, line := range street.Geometry {
+			if err := writeUint64(writer, uint64(len(line))); err != nil {
+				return err
+			}
+			for _, point := range line {
+				if err := writeFloat32(writer, float32(point[0])); err != nil {
+					return err
+				}
+				if err := writeFloat32(writer, float32(point[1])); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return writer.Flush()
+}
+
+func (s *StreetIndex) Load(data []byte) error {
+	return s.LoadFromReader(bytes.NewReader(data))
+}
+
+func (s *StreetIndex) LoadFromReader(r io.Reader) error {
+	reader := bufio.NewReaderSize(r, 256*1024) // 256KB buffer
+
+	count, err := readUint64(reader)
+	if err != nil {
+		return err
+	}
+
+	s.Streets = make([]StreetGeometry, 0, count)
+	s.tree = rtree.RTree{}
+
+	for i := uint64(0); i < count; i++ {
+		var street StreetGeometry
+
+		if street.DocID, err = readInt64(reader); err != nil {
+			return err
+		}
+
+		lineCount, err := readUint64(reader)
+		if err != nil {
+			return err
+		}
+		street.Geometry = make(orb.MultiLineString, lineCount)
+		for j := range street.Geometry {
+			pointCount, err := readUint64(reader)
+			if err != nil {
+				return err
+			}
+			line := make(orb.LineString, pointCount)
+			for k := range line {
+				lng, err := readFloat32(reader)
+				if err != nil {
+					return err
+				}
+				lat, err := readFloat32(reader)
+				if err != nil {
+					return err
+				}
+				line[k] = orb.Point{float64(lng), float64(lat)}
+			}
+			street.Geometry[j] = line
+		}
+
+		s.Add(street)
+	}
+
+	return nil
+}
+
+func (s *StreetIndex) LoadFromFile(file io.ReaderAt, offset int64, size uint64) error {
+	reader := io.NewSectionReader(file, offset, int64(size))
+	return s.LoadFromReader(reader)
+}
@@ -0,0 +1,58 @@
+package structures
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/paulmach/orb"
+)
+
+func TestStreetIndexNearest(t *testing.T) {
+	index := NewStreetIndex()
+	// Unter den Linden runs east-west, Friedrichstraße north-south
+	index.Add(StreetGeometry{DocID: 1, Geometry: orb.MultiLineString{{{13.3777, 52.5163}, {13.3950, 52.5175}}}})
+	index.Add(StreetGeometry{DocID: 2, Geometry: orb.MultiLineString{{{13.3889, 52.5050}, {13.3880, 52.5250}}}})
+
+	var buf bytes.Buffer
+	if err := index.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewStreetIndex()
+	if err := loaded.Load(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		lat, lng float64
+		radius   float64
+		accept   func(docID int64) bool
+		want     int64
+		distance float64
+	}{
+		// South of the middle of Unter den Linden, far from both ends of the
+		// segment
+		{"segment", 52.5159, 13.3860, 500, nil, 1, 109},
+		{"nearer street", 52.5100, 13.3886, 500, nil, 2, 0},
+		{"accept", 52.5100, 13.3886, 1000, func(docID int64) bool { return docID == 1 }, 1, 785},
+		{"out of radius", 52.5000, 13.3500, 500, nil, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			street, distance := loaded.NearestMatching(test.lat, test.lng, test.radius, test.accept)
+			if test.want == 0 {
+				if street != nil {
+					t.Fatalf("got street %d, want none", street.DocID)
+				}
+				return
+			}
+			if street == nil || street.DocID != test.want {
+				t.Fatalf("got %v, want street %d", street, test.want)
+			}
+			if math.Abs(distance-test.distance) > 25 {
+				t.Errorf("distance %.0f m, want about %.0f", distance, test.distance)
+			}
+		})
+	}
+}
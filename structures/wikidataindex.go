@@ -0,0 +1,141 @@
+package structures
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type wikidataEntry struct {
+	qid   uint32
+	docID int64
+}
+
+// WikidataIndex maps Wikidata item numbers to document IDs. Entries are kept
+// sorted by item number so lookups are a binary search.
+type WikidataIndex struct {
+	entries []wikidataEntry
+}
+
+func NewWikidataIndex() *WikidataIndex {
+	return &WikidataIndex{
+		entries: make([]wikidataEntry, 0),
+	}
+}
+
+// ParseWikidataID parses an item ID like "Q64" into its number. It returns 0
+// for empty or invalid IDs.
+func ParseWikidataID(id string) uint32 {
+	id = strings.TrimSpace(id)
+	if len(id) < 2 || (id[0] != 'Q' && id[0] != 'q') {
+		return 0
+	}
+	qid, err := strconv.ParseUint(id[1:], 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(qid)
+}
+
+// FormatWikidataID is the inverse of ParseWikidataID.
+func FormatWikidataID(qid uint32) string {
+	if qid == 0 {
+		return ""
+	}
+	return "Q" + strconv.FormatUint(uint64(qid), 10)
+}
+
+func (w *WikidataIndex) Add(qid uint32, docID int64) {
+	if qid == 0 {
+		return
+	}
+	w.entries = append(w.entries, wikidataEntry{qid: qid, docID: docID})
+}
+
+// Optimize sorts the entries by item number.
+func (w *WikidataIndex) Optimize() {
+	sort.Slice(w.entries, func(i, j int) bool {
+		if w.entries[i].qid != w.entries[j].qid {
+			return w.entries[i].qid < w.entries[j].qid
+		}
+		return w.entries[i].docID < w.entries[j].docID
+	})
+}
+
+// Lookup returns all documents tagged with the item number.
+func (w *WikidataIndex) Lookup(qid uint32) []int64 {
+	i := sort.Search(len(w.entries), func(i int) bool {
+		return w.entries[i].qid >= qid
+	})
+
+	out := make([]int64, 0)
+	for ; i < len(w.entries) && w.entries[i].qid == qid; i++ {
+		out = append(out, w.entries[i].docID)
+	}
+	return out
+}
+
+// -------------------------------------------------------------------
+// Custom Binary Serialization
+// -------------------------------------------------------------------
+/*
+Format:
+
+1) uint64 = number of entries
+   then for each:
+    1.1) uint32 = item number
+    1.2) int64 = docID
+*/
+
+func (w *WikidataIndex) Save(wr io.Writer) error {
+	w.Optimize()
+	writer := bufio.NewWriter(wr)
+
+	if err := writeUint64(writer, uint64(len(w.entries))); err != nil {
+		return err
+	}
+	for _, entry := range w.entries {
+		if err := binary.Write(writer, binary.LittleEndian, entry.qid); err != nil {
+			return err
+		}
+		if err := writeInt64(writer, entry.docID); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+func (w *WikidataIndex) Load(data []byte) error {
+	return w.LoadFromReader(bytes.NewReader(data))
+}
+
+func (w *WikidataIndex) LoadFromReader(r io.Reader) error {
+	reader := bufio.NewReaderSize(r, 256*1024) // 256KB buffer
+
+	count, err := readUint64(reader)
+	if err != nil {
+		return err
+	}
+
+	w.entries = make([]wikidataEntry, count)
+	for i := range w.entries {
+		if err := binary.Read(reader, binary.LittleEndian, &w.entries[i].qid); err != nil {
+			return err
+		}
+		if w.entries[i].docID, err = readInt64(reader); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *WikidataIndex) LoadFromFile(file io.ReaderAt, offset int64, size uint64) error {
+	reader := io.NewSectionReader(file, offset, int64(size))
+	return w.LoadFromReader(reader)
+}
@@ -0,0 +1,64 @@
+package structures
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParseWikidataID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want uint32
+	}{
+		{"Q64", 64},
+		{"q64", 64},
+		{" Q1490 ", 1490},
+		{"Q", 0},
+		{"64", 0},
+		{"P31", 0},
+		{"Q64a", 0},
+		{"", 0},
+	}
+	for _, test := range tests {
+		if got := ParseWikidataID(test.id); got != test.want {
+			t.Errorf("ParseWikidataID(%q) = %d, want %d", test.id, got, test.want)
+		}
+		if test.want != 0 && ParseWikidataID(FormatWikidataID(test.want)) != test.want {
+			t.Errorf("FormatWikidataID(%d) = %q", test.want, FormatWikidataID(test.want))
+		}
+	}
+}
+
+func TestWikidataIndex(t *testing.T) {
+	index := NewWikidataIndex()
+	index.Add(64, 7)
+	index.Add(1490, 3)
+	index.Add(64, 2)
+	index.Add(0, 5)
+
+	var buf bytes.Buffer
+	if err := index.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewWikidataIndex()
+	if err := loaded.Load(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		qid  uint32
+		want []int64
+	}{
+		// A place node and its area may share the item
+		{64, []int64{2, 7}},
+		{1490, []int64{3}},
+		{0, []int64{}},
+		{65, []int64{}},
+	}
+	for _, test := range tests {
+		if got := loaded.Lookup(test.qid); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Lookup(%d) = %v, want %v", test.qid, got, test.want)
+		}
+	}
+}
@@ -8,9 +8,11 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 )
 
-var ImportanceMap map[string]int
+// ImportanceMap holds the normalized (0-1) Wikimedia importance per Wikidata ID.
+var ImportanceMap map[string]float64
 
 func LoadImportanceMap() {
 
@@ -31,7 +33,15 @@ func LoadImportanceMap() {
 	reader.FieldsPerRecord = -1
 	reader.LazyQuotes = true
 
-	ImportanceMap := make(map[string]int)
+	// Default column layout of wikimedia-importance.tsv:
+	// language, type, title, importance, wikidata_id
+	importanceColumn, wikidataColumn := 3, 4
+
+	ImportanceMap = make(map[string]float64)
+
+	var maxImportance float64
+	var rows, skipped int
+	first := true
 
 	for {
 		record, err := reader.Read()
@@ -39,12 +49,68 @@ func LoadImportanceMap() {
 			break
 		}
 		if err != nil {
+			skipped++
+			continue
+		}
+
+		if first {
+			first = false
+			if header := columnIndex(record, "importance"); header >= 0 {
+				importanceColumn = header
+				if column := columnIndex(record, "wikidata_id"); column >= 0 {
+					wikidataColumn = column
+				}
+				continue
+			}
+		}
+
+		rows++
+		if len(record) <= importanceColumn || len(record) <= wikidataColumn {
+			skipped++
+			continue
+		}
+
+		wikidata := strings.ToUpper(strings.TrimSpace(record[wikidataColumn]))
+		importance, err := strconv.ParseFloat(record[importanceColumn], 64)
+		if err != nil || wikidata == "" || importance <= 0 {
+			skipped++
 			continue
 		}
 
-		if len(record) > 3 {
-			importanceFloat, _ := strconv.ParseFloat(record[3], 64)
-			ImportanceMap[record[4]] = int(importanceFloat * config.WikimediaMaxImportance)
+		// The file has one row per language, keep the highest score
+		if importance > ImportanceMap[wikidata] {
+			ImportanceMap[wikidata] = importance
+		}
+		if importance > maxImportance {
+			maxImportance = importance
+		}
+	}
+
+	if len(ImportanceMap) == 0 {
+		log.Fatalf("No importance entries found in %s (%d rows, %d skipped)", config.WikimediaImportance, rows, skipped)
+	}
+
+	for wikidata, importance := range ImportanceMap {
+		ImportanceMap[wikidata] = importance / maxImportance
+	}
+
+	log.Printf("[IMPORTANCE] Loaded %d entries (%d rows, %d skipped)", len(ImportanceMap), rows, skipped)
+}
+
+// GetImportance returns the normalized importance of a Wikidata ID, or 0 if
+// the ID is unknown.
+func GetImportance(wikidata string) float64 {
+	if wikidata == "" {
+		return 0
+	}
+	return ImportanceMap[strings.ToUpper(strings.TrimSpace(wikidata))]
+}
+
+func columnIndex(header []string, name string) int {
+	for i, column := range header {
+		if strings.TrimSpace(column) == name {
+			return i
 		}
 	}
+	return -1
 }
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"compress/gzip"
+	"hstin/gocoder/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeImportance writes a gzipped TSV file and points the config at it.
+func writeImportance(t *testing.T, content string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "wikimedia-importance.tsv.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	if _, err := gz.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := config.WikimediaImportance
+	config.WikimediaImportance = path
+	t.Cleanup(func() { config.WikimediaImportance = previous })
+}
+
+func TestLoadImportanceMap(t *testing.T) {
+	defer func(m map[string]float64) { ImportanceMap = m }(ImportanceMap)
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name: "default columns",
+			content: "en\tcity\tBerlin\t0.8\tQ64\n" +
+				"de\tcity\tBerlin\t0.6\tQ64\n" +
+				"en\tcity\tBonn\t0.4\tQ586\n" +
+				"en\tcity\tBroken\tnone\tQ1\n" +
+				"en\tcity\tShort\n",
+		},
+		{
+			// The header decides the columns
+			name: "header",
+			content: "wikidata_id\timportance\tlanguage\n" +
+				"Q64\t0.6\tde\n" +
+				"q64\t0.8\ten\n" +
+				"Q586\t0.4\ten\n" +
+				"Q2\t0\ten\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			writeImportance(t, test.content)
+			LoadImportanceMap()
+
+			if len(ImportanceMap) != 2 {
+				t.Errorf("loaded %d entries, want 2: %v", len(ImportanceMap), ImportanceMap)
+			}
+			// The highest row counts, normalized to the most important item
+			for wikidata, want := range map[string]float64{"Q64": 1, " q64 ": 1, "Q586": 0.5, "Q1": 0, "": 0} {
+				if got := GetImportance(wikidata); got != want {
+					t.Errorf("GetImportance(%q) = %v, want %v", wikidata, got, want)
+				}
+			}
+
+			// The rank adds the weighted importance
+			with := Ranking.Rank(map[string]string{"place": "city", "wikidata": "Q64"}, 0, "")
+			without := Ranking.Rank(map[string]string{"place": "city"}, 0, "")
+			if Ranking.Importance > 0 && with <= without {
+				t.Errorf("rank with importance %d, without %d", with, without)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"hstin/gocoder/mapping"
+	"math"
 	"strconv"
 	"strings"
 )
@@ -33,3 +34,14 @@ func ParseStringAsNumber(s string) int64 {
 
 	return int64(number)
 }
+
+// ParseElevation parses an OSM ele tag like "2962", "2962.1" or "2962 m"
+// into whole metres. It returns false for empty or invalid values.
+func ParseElevation(s string) (int64, bool) {
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "m"))
+	elevation, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(elevation) || math.IsInf(elevation, 0) {
+		return 0, false
+	}
+	return int64(math.Round(elevation)), true
+}
@@ -0,0 +1,26 @@
+package utils
+
+import "testing"
+
+func TestParseElevation(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+		ok    bool
+	}{
+		{"2962", 2962, true},
+		{"2962.1", 2962, true},
+		{"2962 m", 2962, true},
+		{" 2962m ", 2962, true},
+		{"-430", -430, true},
+		{"", 0, false},
+		{"high", 0, false},
+		{"NaN", 0, false},
+	}
+	for _, test := range tests {
+		got, ok := ParseElevation(test.value)
+		if got != test.want || ok != test.ok {
+			t.Errorf("ParseElevation(%q) = %d, %v, want %d, %v", test.value, got, ok, test.want, test.ok)
+		}
+	}
+}
@@ -1,45 +1,120 @@
 package utils
 
 import (
+	"encoding/json"
+	"hstin/gocoder/config"
 	"hstin/gocoder/mapping"
+	"log"
+	"math"
+	"os"
+	"strings"
 )
 
-func CreateRank(tags map[string]string, pop int64) int {
+// RankFormula documents how RankingModel.Rank combines the weights. It is
+// stored in the database metadata next to the model.
+const RankFormula = "round(filter rank or place_types[type] or default_place_type" +
+	" + population * log10(population + 1)" +
+	" + capital[capital]" +
+	" + importance * wikimedia_importance" +
+	" + countries[country]), clamped to 0-65535"
 
-	// Calculate the rank based on the place type
-	calculatedRank := mapping.PlaceRank[tags["place"]]
+// RankingModel holds the weights used to compute the static rank of a place,
+// see RankFormula.
+type RankingModel struct {
+	PlaceTypes       map[string]float64 `json:"place_types"`
+	DefaultPlaceType float64            `json:"default_place_type"`
+	Population       float64            `json:"population"`
+	Capital          map[string]float64 `json:"capital"`
+	Importance       float64            `json:"importance"`
+	Countries        map[string]float64 `json:"countries"`
+}
+
+// Ranking is the model used by CreateRank. It is replaced by LoadRankingModel.
+var Ranking = DefaultRankingModel()
+
+func DefaultRankingModel() RankingModel {
+	placeTypes := make(map[string]float64, len(mapping.PlaceRank))
+	for place, rank := range mapping.PlaceRank {
+		placeTypes[place] = float64(rank)
+	}
+
+	return RankingModel{
+		PlaceTypes: placeTypes,
+		Population: 100,
+		Capital: map[string]float64{
+			"yes": 500,
+			"2":   500,
+			"4":   250,
+			"6":   100,
+		},
+		Importance: config.WikimediaMaxImportance,
+		Countries:  make(map[string]float64),
+	}
+}
+
+// LoadRankingModel reads the ranking configuration file if one is set. Values
+// in the file override the defaults, map entries are merged.
+func LoadRankingModel() {
+	Ranking = DefaultRankingModel()
+
+	if config.RankingConfig == "" {
+		return
+	}
+
+	data, err := os.ReadFile(config.RankingConfig)
+	if err != nil {
+		log.Fatalf("Failed to read ranking config: %v", err)
+	}
+
+	if err := json.Unmarshal(data, &Ranking); err != nil {
+		log.Fatalf("Failed to parse ranking config: %v", err)
+	}
+
+	// Country codes are stored upper case
+	countries := make(map[string]float64, len(Ranking.Countries))
+	for code, boost := range Ranking.Countries {
+		countries[strings.ToUpper(code)] = boost
+	}
+	Ranking.Countries = countries
+}
+
+func CreateRank(tags map[string]string, pop int64, country string) int {
+	return Ranking.Rank(tags, pop, country)
+}
+
+// CreateRankWithBase replaces the place type weight with a base rank, e.g.
+// the rank of a tag filter.
+func CreateRankWithBase(base float64, tags map[string]string, pop int64, country string) int {
+	return Ranking.RankWithBase(base, tags, pop, country)
+}
+
+// BaseRank returns the weight of a place type.
+func (m RankingModel) BaseRank(placeType string) float64 {
+	rank, ok := m.PlaceTypes[placeType]
+	if !ok {
+		return m.DefaultPlaceType
+	}
+	return rank
+}
+
+func (m RankingModel) Rank(tags map[string]string, pop int64, country string) int {
+	return m.RankWithBase(m.BaseRank(tags["place"]), tags, pop, country)
+}
+
+func (m RankingModel) RankWithBase(base float64, tags map[string]string, pop int64, country string) int {
+	rank := base
 
 	// Factor in population
-	if pop < 50000000 {
-		calculatedRank += 750
-	} else if pop < 10000000 {
-		calculatedRank += 500
-	} else if pop < 1000000 {
-		calculatedRank += 200
-	} else if pop < 500000 {
-		calculatedRank += 150
-	} else if pop < 100000 {
-		calculatedRank += 100
-	} else if pop < 50000 {
-		calculatedRank += 75
-	} else if pop < 10000 {
-		calculatedRank += 50
-	} else if pop < 5000 {
-		calculatedRank += 25
-	} else if pop < 1000 {
-		calculatedRank += 10
-	} else if pop < 500 {
-		calculatedRank += 5
-	} else if pop < 100 {
-		calculatedRank += 1
-	}
-
-	// Capital cities get a bonus
-	if tags["capital"] == "yes" {
-		calculatedRank += 500
-	}
-
-	calculatedRank += ImportanceMap[tags["wikidata"]]
-
-	return calculatedRank
+	if pop > 0 {
+		rank += m.Population * math.Log10(float64(pop)+1)
+	}
+
+	// Capital cities get a bonus depending on their level
+	rank += m.Capital[tags["capital"]]
+
+	rank += m.Importance * GetImportance(tags["wikidata"])
+
+	rank += m.Countries[strings.ToUpper(country)]
+
+	return int(math.Max(0, math.Min(math.MaxUint16, math.Round(rank))))
 }
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"hstin/gocoder/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRank(t *testing.T) {
+	model := RankingModel{
+		PlaceTypes:       map[string]float64{"city": 900, "village": 800},
+		DefaultPlaceType: 100,
+		Population:       100,
+		Capital:          map[string]float64{"2": 500, "4": 250},
+		Countries:        map[string]float64{"DE": 50},
+	}
+
+	tests := []struct {
+		name       string
+		tags       map[string]string
+		population int64
+		country    string
+		want       int
+	}{
+		{"place type", map[string]string{"place": "city"}, 0, "", 900},
+		{"unknown place type", map[string]string{"place": "plaza"}, 0, "", 100},
+		// log10(999999 + 1) * 100
+		{"population", map[string]string{"place": "village"}, 999999, "", 1400},
+		{"capital", map[string]string{"place": "city", "capital": "2"}, 0, "", 1400},
+		{"state capital", map[string]string{"place": "city", "capital": "4"}, 0, "", 1150},
+		{"country", map[string]string{"place": "city"}, 0, "de", 950},
+	}
+	for _, test := range tests {
+		if got := model.Rank(test.tags, test.population, test.country); got != test.want {
+			t.Errorf("%s: rank %d, want %d", test.name, got, test.want)
+		}
+	}
+
+	// Every population band counts, larger places rank higher
+	previous := -1
+	for _, population := range []int64{0, 100, 10000, 100000, 1000000, 10000000, 60000000} {
+		rank := model.Rank(map[string]string{"place": "city"}, population, "")
+		if rank <= previous {
+			t.Errorf("population %d: rank %d, want more than %d", population, rank, previous)
+		}
+		previous = rank
+	}
+
+	if got := model.RankWithBase(-5000, nil, 0, ""); got != 0 {
+		t.Errorf("negative rank %d, want 0", got)
+	}
+	if got := model.RankWithBase(1e6, nil, 0, ""); got != 65535 {
+		t.Errorf("rank %d, want 65535", got)
+	}
+}
+
+func TestLoadRankingModel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ranking.json")
+	content := `{"place_types": {"city": 1000, "plaza": 300}, "capital": {"2": 800}, "countries": {"de": 75}}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(file string, model RankingModel) {
+		config.RankingConfig = file
+		Ranking = model
+	}(config.RankingConfig, Ranking)
+	config.RankingConfig = path
+	LoadRankingModel()
+
+	defaults := DefaultRankingModel()
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"overridden place type", Ranking.PlaceTypes["city"], 1000},
+		{"added place type", Ranking.PlaceTypes["plaza"], 300},
+		// Entries missing in the file keep their default
+		{"default place type", Ranking.PlaceTypes["village"], defaults.PlaceTypes["village"]},
+		{"overridden capital", Ranking.Capital["2"], 800},
+		{"default capital", Ranking.Capital["4"], defaults.Capital["4"]},
+		{"population", Ranking.Population, defaults.Population},
+		{"country", Ranking.Countries["DE"], 75},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s: %v, want %v", test.name, test.got, test.want)
+		}
+	}
+}